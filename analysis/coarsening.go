@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/davidkleiven/gopf/pf"
+	"gonum.org/v1/gonum/stat"
+)
+
+// CoarseningLogger is a solver callback that calculates the radially averaged structure
+// factor of Field after each epoch, together with its first moment and the characteristic
+// length. The values are stored and written to the log
+//
+//	logger := analysis.NewCoarseningLogger("conc", domainSize)
+//	solver.AddCallback(logger.Log)
+//	solver.Solve(nepochs, nsteps)
+//	n, _, err := logger.Exponent(100.0, 1000.0)
+type CoarseningLogger struct {
+	Field            string
+	Calc             *StructureFactorCalculator
+	Time             []float64
+	FirstMoment      []float64
+	Length           []float64
+	StructureFactors []StructureFactor
+}
+
+// NewCoarseningLogger returns a new CoarseningLogger for the passed field
+func NewCoarseningLogger(field string, domainSize []int) *CoarseningLogger {
+	return &CoarseningLogger{
+		Field: field,
+		Calc:  NewStructureFactorCalculator(domainSize),
+	}
+}
+
+// Log calculates the structure factor of the field. It satisfies the SolverCB type,
+// and can thus be attached as a callback to a solver
+func (cl *CoarseningLogger) Log(s *pf.Solver, epoch int) {
+	brick, ok := s.Model.Bricks[cl.Field]
+	if !ok {
+		panic("analysis: unknown field " + cl.Field)
+	}
+	data := make([]complex128, s.Model.NumNodes())
+	for i := range data {
+		data[i] = brick.Get(i)
+	}
+	sf := cl.Calc.Calculate(data)
+	t := s.Stepper.GetTime()
+	k1 := sf.FirstMoment()
+	length := sf.CharacteristicLength()
+
+	cl.Time = append(cl.Time, t)
+	cl.FirstMoment = append(cl.FirstMoment, k1)
+	cl.Length = append(cl.Length, length)
+	cl.StructureFactors = append(cl.StructureFactors, sf)
+	log.Printf("Epoch %d: time %e, first moment %e, characteristic length %e\n", epoch, t, k1, length)
+}
+
+// Exponent fits the coarsening law L = A*t^n to the recorded characteristic lengths in
+// the time window [tmin, tmax] and returns the exponent n and the prefactor A
+func (cl *CoarseningLogger) Exponent(tmin, tmax float64) (n, A float64, err error) {
+	return FitCoarseningExponent(cl.Time, cl.Length, tmin, tmax)
+}
+
+// FitCoarseningExponent fits L = A*t^n by a linear least squares fit of log(L) versus
+// log(t). Only the points with tmin <= t <= tmax are used. Points with non-positive
+// time or non-positive or infinite length (uniform fields) are ignored. The function
+// returns the exponent n and the prefactor A. An error is returned if time and length
+// differ in length, or if less than two points are inside the window
+func FitCoarseningExponent(time, length []float64, tmin, tmax float64) (n, A float64, err error) {
+	if len(time) != len(length) {
+		return 0.0, 0.0, fmt.Errorf("analysis: got %d times and %d lengths", len(time), len(length))
+	}
+	logT := []float64{}
+	logL := []float64{}
+	for i := range time {
+		if time[i] < tmin || time[i] > tmax || time[i] <= 0.0 {
+			continue
+		}
+		if length[i] <= 0.0 || math.IsInf(length[i], 1) {
+			continue
+		}
+		logT = append(logT, math.Log(time[i]))
+		logL = append(logL, math.Log(length[i]))
+	}
+	if len(logT) < 2 {
+		return 0.0, 0.0, fmt.Errorf("analysis: need at least two points in [%v, %v], got %d", tmin, tmax, len(logT))
+	}
+	alpha, beta := stat.LinearRegression(logT, logL, nil, false)
+	return beta, math.Exp(alpha), nil
+}
@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/davidkleiven/gopf/pf"
+)
+
+func TestFitCoarseningExponent(t *testing.T) {
+	time := []float64{0.0, 1.0, 2.0, 4.0, 8.0, 16.0, 32.0}
+	length := make([]float64, len(time))
+	for i := range time {
+		length[i] = 2.0 * math.Pow(time[i], 1.0/3.0)
+	}
+
+	// Outlier outside the window should not affect the fit
+	length[len(length)-1] = 100.0
+
+	n, prefactor, err := FitCoarseningExponent(time, length, 0.0, 20.0)
+	if err != nil {
+		t.Errorf("Unexpected error %v\n", err)
+		return
+	}
+	if math.Abs(n-1.0/3.0) > 1e-10 {
+		t.Errorf("Expected exponent 1/3 got %f\n", n)
+	}
+	if math.Abs(prefactor-2.0) > 1e-10 {
+		t.Errorf("Expected prefactor 2 got %f\n", prefactor)
+	}
+}
+
+func TestFitCoarseningExponentErrors(t *testing.T) {
+	for i, test := range []struct {
+		time   []float64
+		length []float64
+		tmin   float64
+		tmax   float64
+	}{
+		// Length mismatch
+		{
+			time:   []float64{1.0, 2.0, 4.0},
+			length: []float64{1.0, 2.0},
+			tmin:   0.0,
+			tmax:   10.0,
+		},
+		// Window narrower than the output interval
+		{
+			time:   []float64{1.0, 2.0, 4.0},
+			length: []float64{1.0, 2.0, 3.0},
+			tmin:   2.5,
+			tmax:   3.5,
+		},
+		// Only one finite length inside the window
+		{
+			time:   []float64{1.0, 2.0, 4.0},
+			length: []float64{math.Inf(1), math.Inf(1), 3.0},
+			tmin:   0.0,
+			tmax:   10.0,
+		},
+	} {
+		if _, _, err := FitCoarseningExponent(test.time, test.length, test.tmin, test.tmax); err == nil {
+			t.Errorf("Test #%d: Expected an error\n", i)
+		}
+	}
+}
+
+func TestCoarseningLogger(t *testing.T) {
+	N := 16
+	m := pf.NewModel()
+	conc := pf.NewField("conc", N*N, nil)
+	for i := range conc.Data {
+		conc.Data[i] = complex(math.Cos(2.0*math.Pi*float64(i%N)/4.0), 0.0)
+	}
+	m.AddField(conc)
+	m.AddEquation("dconc/dt = LAP conc")
+
+	solver := pf.NewSolver(&m, []int{N, N}, 0.01)
+	logger := NewCoarseningLogger("conc", []int{N, N})
+	solver.AddCallback(logger.Log)
+	solver.Solve(3, 2)
+
+	if len(logger.Time) != 3 || len(logger.Length) != 3 || len(logger.StructureFactors) != 3 {
+		t.Errorf("Expected 3 entries got %d\n", len(logger.Time))
+		return
+	}
+	for i, l := range logger.Length {
+		if math.Abs(l-4.0) > 0.2 {
+			t.Errorf("Epoch %d: Expected length 4 got %f\n", i, l)
+		}
+	}
+}
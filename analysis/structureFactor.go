@@ -0,0 +1,138 @@
+// Package analysis provides post-processing tools that are typically used in spinodal
+// decomposition and coarsening studies, such as the radially averaged structure factor
+// and the characteristic domain length
+package analysis
+
+import (
+	"math"
+
+	"github.com/davidkleiven/gopf/pf"
+	"github.com/davidkleiven/gopf/pfutil"
+)
+
+// StructureFactor holds the radially averaged structure factor S(|k|). K is the wave
+// number at the center of each bin (in units of inverse node spacing) and S is the
+// average of |c_k|^2/N over the nodes in the bin. The k = 0 component is excluded such
+// that the structure factor describes the fluctuations around the mean
+type StructureFactor struct {
+	K []float64
+	S []float64
+}
+
+// FirstMoment returns the first moment of the structure factor
+//
+// k_1 = sum_k k S(k) / sum_k S(k)
+//
+// If the structure factor is zero everywhere (e.g. a uniform field) there are no
+// fluctuations, and zero is returned
+func (sf *StructureFactor) FirstMoment() float64 {
+	num := 0.0
+	denum := 0.0
+	for i := range sf.K {
+		num += sf.K[i] * sf.S[i]
+		denum += sf.S[i]
+	}
+	if denum == 0.0 {
+		return 0.0
+	}
+	return num / denum
+}
+
+// CharacteristicLength returns the characteristic domain length L = 2*pi/k_1, where
+// k_1 is the first moment of the structure factor. For a uniform field the first
+// moment is zero, and the length is +Inf
+func (sf *StructureFactor) CharacteristicLength() float64 {
+	return 2.0 * math.Pi / sf.FirstMoment()
+}
+
+// StructureFactorCalculator calculates the radially averaged structure factor. The bin
+// width is given by the smallest non-zero wave number, 2*pi/max(domainSize), and bin i
+// contains all wave vectors with (i - 0.5)*dk <= |k| < (i + 0.5)*dk
+type StructureFactorCalculator struct {
+	FT         pf.FourierTransform
+	DomainSize []int
+
+	// binIdx holds the bin of each node. Nodes that are not part of any bin are -1
+	binIdx []int
+
+	// binCount holds the number of nodes in each bin
+	binCount []int
+}
+
+// NewStructureFactorCalculator returns a new calculator for a domain of the passed size
+func NewStructureFactorCalculator(domainSize []int) *StructureFactorCalculator {
+	calc := StructureFactorCalculator{
+		FT:         pfutil.NewFFTW(domainSize),
+		DomainSize: domainSize,
+	}
+	calc.initBins()
+	return &calc
+}
+
+// BinWidth returns the width of the wave number bins
+func (sfc *StructureFactorCalculator) BinWidth() float64 {
+	maxSize := 0
+	for _, n := range sfc.DomainSize {
+		if n > maxSize {
+			maxSize = n
+		}
+	}
+	return 2.0 * math.Pi / float64(maxSize)
+}
+
+// initBins assigns each node to a bin
+func (sfc *StructureFactorCalculator) initBins() {
+	numNodes := pfutil.ProdInt(sfc.DomainSize)
+	dk := sfc.BinWidth()
+	sfc.binIdx = make([]int, numNodes)
+	for i := range sfc.binIdx {
+		f := sfc.FT.Freq(i)
+		k := 2.0 * math.Pi * math.Sqrt(pfutil.Dot(f, f))
+		bin := int(k/dk + 0.5)
+		if bin == 0 {
+			sfc.binIdx[i] = -1
+			continue
+		}
+		for len(sfc.binCount) <= bin {
+			sfc.binCount = append(sfc.binCount, 0)
+		}
+		sfc.binIdx[i] = bin
+		sfc.binCount[bin]++
+	}
+}
+
+// Calculate returns the radially averaged structure factor of data. Data is assumed to be
+// the real space representation and is not altered. Empty bins are not included in the result
+func (sfc *StructureFactorCalculator) Calculate(data []complex128) StructureFactor {
+	ft := make([]complex128, len(data))
+	copy(ft, data)
+	sfc.FT.FFT(ft)
+
+	sum := make([]float64, len(sfc.binCount))
+	for i, v := range ft {
+		if bin := sfc.binIdx[i]; bin >= 0 {
+			sum[bin] += real(v)*real(v) + imag(v)*imag(v)
+		}
+	}
+
+	numNodes := float64(len(data))
+	dk := sfc.BinWidth()
+	sf := StructureFactor{}
+	for i := range sum {
+		if sfc.binCount[i] == 0 {
+			continue
+		}
+		sf.K = append(sf.K, float64(i)*dk)
+		sf.S = append(sf.S, sum[i]/(numNodes*float64(sfc.binCount[i])))
+	}
+	return sf
+}
+
+// RadialStructureFactor is a convenience function that calculates the radially averaged
+// structure factor of a field. When the structure factor is calculated repeatedly, a
+// StructureFactorCalculator should be used instead, such that the fourier transform plan
+// and the bins are only constructed once
+func RadialStructureFactor(field pf.Field, domainSize []int) StructureFactor {
+	calc := NewStructureFactorCalculator(domainSize)
+	return calc.Calculate(field.Data)
+}
@@ -0,0 +1,84 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/davidkleiven/gopf/pf"
+	"github.com/davidkleiven/gopf/pfutil"
+)
+
+func TestStructureFactorSingleMode(t *testing.T) {
+	for i, test := range []struct {
+		domainSize []int
+		axis       int
+		periods    int
+		length     float64
+	}{
+		{domainSize: []int{32, 32}, axis: 0, periods: 4, length: 8.0},
+		{domainSize: []int{32, 16}, axis: 1, periods: 2, length: 8.0},
+		{domainSize: []int{8, 8, 16}, axis: 2, periods: 2, length: 8.0},
+	} {
+		numNodes := pfutil.ProdInt(test.domainSize)
+		field := pf.NewField("conc", numNodes, nil)
+		sumSq := 0.0
+		for j := range field.Data {
+			pos := pfutil.Pos(test.domainSize, j)
+			x := float64(pos[test.axis]) / float64(test.domainSize[test.axis])
+			v := 0.5 + math.Cos(2.0*math.Pi*float64(test.periods)*x)
+			field.Data[j] = complex(v, 0.0)
+			sumSq += (v - 0.5) * (v - 0.5)
+		}
+
+		calc := NewStructureFactorCalculator(test.domainSize)
+		sf := calc.Calculate(field.Data)
+
+		// Parseval: all non-zero wave vectors should be part of a bin
+		total := 0.0
+		for j := range sf.S {
+			bin := int(sf.K[j]/calc.BinWidth() + 0.5)
+			total += sf.S[j] * float64(calc.binCount[bin])
+		}
+		if math.Abs(total-sumSq) > 1e-8 {
+			t.Errorf("Test #%d: Expected sum of S to be %f got %f\n", i, sumSq, total)
+		}
+
+		if l := sf.CharacteristicLength(); math.Abs(l-test.length) > 0.05*test.length {
+			t.Errorf("Test #%d: Expected length %f got %f\n", i, test.length, l)
+		}
+	}
+}
+
+func TestStructureFactorBinsSorted(t *testing.T) {
+	sf := RadialStructureFactor(pf.NewField("conc", 64, nil), []int{8, 8})
+	for i := 1; i < len(sf.K); i++ {
+		if sf.K[i] <= sf.K[i-1] {
+			t.Errorf("Wave numbers are not increasing: %v\n", sf.K)
+			return
+		}
+	}
+	if sf.K[0] < 0.5*2.0*math.Pi/8.0 {
+		t.Errorf("The k = 0 component should not be included. Got %v\n", sf.K)
+	}
+}
+
+func TestFirstMomentUniformField(t *testing.T) {
+	field := pf.NewField("conc", 64, nil)
+	for i := range field.Data {
+		field.Data[i] = complex(0.3, 0.0)
+	}
+	sf := RadialStructureFactor(field, []int{8, 8})
+	if k1 := sf.FirstMoment(); k1 != 0.0 {
+		t.Errorf("Expected first moment 0 got %f\n", k1)
+	}
+	if l := sf.CharacteristicLength(); !math.IsInf(l, 1) {
+		t.Errorf("Expected infinite length got %f\n", l)
+	}
+
+	// Uniform fields are ignored when fitting the coarsening exponent
+	time := []float64{1.0, 2.0, 4.0, 8.0}
+	length := []float64{sf.CharacteristicLength(), 2.0, 2.0 * math.Pow(2.0, 1.0/3.0), 2.0 * math.Pow(4.0, 1.0/3.0)}
+	if n, _, err := FitCoarseningExponent(time, length, 0.0, 10.0); err != nil || math.Abs(n-1.0/3.0) > 1e-10 {
+		t.Errorf("Expected exponent 1/3 got %f (error %v)\n", n, err)
+	}
+}
@@ -0,0 +1,239 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidkleiven/gopf/pf"
+	"github.com/davidkleiven/gopf/pfutil"
+	"github.com/spf13/cobra"
+)
+
+// animateCmd represents the animate command
+var animateCmd = &cobra.Command{
+	Use:   "animate",
+	Short: "Create an animated GIF from a folder of CSV or binary output files",
+	Long: `Animate renders one field from a series of output files and assembles the
+frames into an animated GIF. The frames are rendered in the same way as by the contour
+command. The files are ordered by the epoch in the filename.
+
+CSV files written by CsvIO are named <prefix>_<epoch>.csv
+
+gopf animate --folder data --prefix run --field conc --out conc.gif
+
+Binary files written by Float64IO are named <prefix>_<field>_<epoch>.bin. In that case
+the domain size must be given
+
+gopf animate --folder data --prefix run --field conc --format bin --domain 128,128
+
+For three dimensional data, a slice normal to the axis given by --normal (0, 1 or 2) at
+the position given by --slice is rendered. By default, the middle of the domain normal
+to the z-axis is shown. The color range is set from the data in each frame unless both
+--min and --max are given (max > min).
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		folder, err := cmd.Flags().GetString("folder")
+		if err != nil {
+			log.Fatalf("Could not read folder: %s\n", err)
+			return
+		}
+
+		prefix, err := cmd.Flags().GetString("prefix")
+		if err != nil || prefix == "" {
+			log.Fatalf("No prefix given\n")
+			return
+		}
+
+		field, err := cmd.Flags().GetString("field")
+		if err != nil {
+			log.Fatalf("Could not read field: %s\n", err)
+			return
+		}
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			log.Fatalf("Could not read format: %s\n", err)
+			return
+		}
+
+		domain, err := cmd.Flags().GetString("domain")
+		if err != nil {
+			log.Fatalf("Could not read domain size: %s\n", err)
+			return
+		}
+
+		normal, err := cmd.Flags().GetInt("normal")
+		if err != nil {
+			log.Fatalf("Could not read normal: %s\n", err)
+			return
+		}
+
+		slice, err := cmd.Flags().GetInt("slice")
+		if err != nil {
+			log.Fatalf("Could not read slice: %s\n", err)
+			return
+		}
+
+		colormap, err := cmd.Flags().GetString("colormap")
+		if err != nil {
+			log.Fatalf("Could not read colormap: %s\n", err)
+			return
+		}
+
+		vmin, err := cmd.Flags().GetFloat64("min")
+		if err != nil {
+			log.Fatalf("Could not read min: %s\n", err)
+			return
+		}
+
+		vmax, err := cmd.Flags().GetFloat64("max")
+		if err != nil {
+			log.Fatalf("Could not read max: %s\n", err)
+			return
+		}
+
+		delay, err := cmd.Flags().GetInt("delay")
+		if err != nil {
+			log.Fatalf("Could not read delay: %s\n", err)
+			return
+		}
+
+		out, err := cmd.Flags().GetString("out")
+		if err != nil {
+			log.Fatalf("Could not read outfile: %s\n", err)
+			return
+		}
+
+		var pattern string
+		switch format {
+		case "csv":
+			pattern = fmt.Sprintf("%s_*.csv", prefix)
+		case "bin":
+			if field == "" {
+				log.Fatalf("The field must be given when reading binary files\n")
+			}
+			pattern = fmt.Sprintf("%s_%s_*.bin", prefix, field)
+		default:
+			log.Fatalf("Unknown format %s. Must be csv or bin\n", format)
+		}
+
+		files, epochs := epochFiles(filepath.Join(folder, pattern))
+		if len(files) == 0 {
+			log.Fatalf("No files matching %s in %s\n", pattern, folder)
+		}
+
+		if field == "" {
+			header := readHeader(files[0])
+			field = header[getColIndex(header, field)]
+		}
+
+		var domainSize []int
+		if format == "bin" {
+			domainSize = parseDomainSize(domain)
+		}
+
+		var anim pf.ImageIO
+		for i, fname := range files {
+			var data []float64
+			if format == "csv" {
+				data, domainSize = readCsvColumn(fname, field)
+			} else {
+				data = pf.LoadFloat64(fname)
+			}
+
+			if i == 0 {
+				anim = pf.NewImageIO(prefix, domainSize)
+				anim.ColorMap = colormap
+				anim.Min = vmin
+				anim.Max = vmax
+				anim.Delay = delay
+				if len(domainSize) == 3 {
+					anim.SliceNormal = normal
+					anim.SlicePos = slice
+					if slice < 0 {
+						anim.SlicePos = domainSize[normal] / 2
+					}
+				}
+			}
+
+			title := fmt.Sprintf("%s (epoch %d)", field, epochs[i])
+			if err := anim.AddFrame(field, data, title, ""); err != nil {
+				log.Fatalf("Could not render %s: %s\n", fname, err)
+			}
+		}
+
+		if err := anim.SaveGIF(field, out); err != nil {
+			log.Fatalf("Could not write animation: %s\n", err)
+		}
+		log.Printf("Animation with %d frames written to %s\n", anim.NumFrames(field), out)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(animateCmd)
+
+	animateCmd.Flags().StringP("folder", "d", ".", "Folder with the output files")
+	animateCmd.Flags().StringP("prefix", "p", "", "Prefix of the output files")
+	animateCmd.Flags().StringP("field", "c", "", "Name of the field to animate. For CSV files the first field is used if not given")
+	animateCmd.Flags().String("format", "csv", "Format of the output files (csv or bin)")
+	animateCmd.Flags().String("domain", "", "Comma separated domain size (required for binary files)")
+	animateCmd.Flags().Int("normal", 2, "Axis normal to the rendered slice for 3D data")
+	animateCmd.Flags().Int("slice", -1, "Position of the rendered slice along the normal for 3D data. Default is the middle of the domain")
+	animateCmd.Flags().String("colormap", "kindlmann", "Colormap. One of "+strings.Join(pfutil.ColorMapNames(), ", "))
+	animateCmd.Flags().Float64("min", 0.0, "Lower end of the color range. The range is set automatically if max <= min")
+	animateCmd.Flags().Float64("max", 0.0, "Upper end of the color range. The range is set automatically if max <= min")
+	animateCmd.Flags().Int("delay", 20, "Delay between frames in 100ths of a second")
+	animateCmd.Flags().StringP("out", "o", "animation.gif", "File where the animation is stored")
+}
+
+// epochFiles returns all files matching the pattern sorted by the epoch, which is the
+// number between the last underscore and the file extension. Files without an epoch are
+// ignored
+func epochFiles(pattern string) ([]string, []int) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		log.Fatalf("Invalid pattern %s: %s\n", pattern, err)
+	}
+
+	type epochFile struct {
+		fname string
+		epoch int
+	}
+	files := []epochFile{}
+	for _, fname := range matches {
+		base := strings.TrimSuffix(filepath.Base(fname), filepath.Ext(fname))
+		epoch, err := strconv.Atoi(base[strings.LastIndex(base, "_")+1:])
+		if err != nil {
+			continue
+		}
+		files = append(files, epochFile{fname: fname, epoch: epoch})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].epoch < files[j].epoch })
+
+	fnames := make([]string, len(files))
+	epochs := make([]int, len(files))
+	for i, f := range files {
+		fnames[i] = f.fname
+		epochs[i] = f.epoch
+	}
+	return fnames, epochs
+}
@@ -0,0 +1,286 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidkleiven/gopf/pf"
+	"github.com/davidkleiven/gopf/pfutil"
+	"github.com/spf13/cobra"
+)
+
+// clustersCmd represents the clusters command
+var clustersCmd = &cobra.Command{
+	Use:   "clusters",
+	Short: "Label connected clusters (e.g. precipitates or grains) in a field",
+	Long: `Clusters labels connected clusters in CSV or binary output and reports the
+number of clusters, their volumes (number of nodes), centroids and the size distribution.
+
+Example:
+
+gopf clusters -f data.csv -c conc -t 0.5
+
+labels all clusters where the field conc is larger than 0.5. If several columns are
+given, each node is assigned to the order parameter with the largest value and nodes
+belonging to different order parameters form different clusters (e.g. grains)
+
+gopf clusters -f data.csv -c eta1,eta2,eta3 -t 0.5
+
+The CSV file must be formatted as
+
+X, Y, Z, field1, field2, field3
+0, 1, 0, 0.0, 1.0, 2.0
+...
+
+Binary files written by Float64IO are supported as well. In that case the domain size
+must be given, and one file per order parameter is passed as a comma separated list
+
+gopf clusters -f run_eta1_10.bin,run_eta2_10.bin -d 128,128
+
+By default periodic boundary conditions are used. The statistics of each cluster can
+be written to a CSV file with the --out flag.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fname, err := cmd.Flags().GetString("fname")
+		if err != nil || fname == "" {
+			log.Fatalf("No filename given\n")
+			return
+		}
+
+		columns, err := cmd.Flags().GetString("columns")
+		if err != nil {
+			log.Fatalf("Could not read columns: %s\n", err)
+			return
+		}
+
+		domain, err := cmd.Flags().GetString("domain")
+		if err != nil {
+			log.Fatalf("Could not read domain size: %s\n", err)
+			return
+		}
+
+		threshold, err := cmd.Flags().GetFloat64("threshold")
+		if err != nil {
+			log.Fatalf("Could not read threshold: %s\n", err)
+			return
+		}
+
+		connName, err := cmd.Flags().GetString("connectivity")
+		if err != nil {
+			log.Fatalf("Could not read connectivity: %s\n", err)
+			return
+		}
+		conn := parseConnectivity(connName)
+
+		periodic, err := cmd.Flags().GetBool("periodic")
+		if err != nil {
+			log.Fatalf("Could not read periodic flag: %s\n", err)
+			return
+		}
+
+		out, err := cmd.Flags().GetString("out")
+		if err != nil {
+			log.Fatalf("Could not read outfile: %s\n", err)
+			return
+		}
+
+		var data []pfutil.ImmutableSlice
+		var domainSize []int
+		if strings.ToLower(filepath.Ext(fname)) == ".csv" {
+			data, domainSize = loadClusterCsv(fname, columns)
+		} else {
+			data, domainSize = loadClusterBinary(strings.Split(fname, ","), parseDomainSize(domain))
+		}
+
+		var phase []int
+		if len(data) == 1 {
+			phase = pfutil.Threshold(data[0], threshold)
+		} else {
+			phase = pfutil.ArgmaxPhase(data, threshold)
+		}
+		_, clusters := pfutil.LabelClusters(phase, domainSize, conn, periodic)
+		printClusterSummary(clusters)
+
+		if out != "" {
+			writeClusters(out, clusters)
+			log.Printf("Cluster statistics written to %s\n", out)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(clustersCmd)
+
+	clustersCmd.Flags().StringP("fname", "f", "", "CSV file or comma separated list of binary files")
+	clustersCmd.Flags().StringP("columns", "c", "", "Comma separated list of columns used when reading CSV files. If not given, the first field is used.")
+	clustersCmd.Flags().StringP("domain", "d", "", "Comma separated domain size (required for binary files)")
+	clustersCmd.Flags().Float64P("threshold", "t", 0.5, "Nodes with a value above the threshold are part of a cluster")
+	clustersCmd.Flags().String("connectivity", "face", "Connectivity of neighbouring nodes. One of face, edge and corner")
+	clustersCmd.Flags().Bool("periodic", true, "Use periodic boundary conditions")
+	clustersCmd.Flags().StringP("out", "o", "", "CSV file where the statistics of each cluster is written")
+}
+
+// parseConnectivity converts the name of the connectivity to the corresponding constant
+func parseConnectivity(name string) pfutil.Connectivity {
+	switch strings.ToLower(name) {
+	case "face":
+		return pfutil.FaceConnected
+	case "edge":
+		return pfutil.EdgeConnected
+	case "corner":
+		return pfutil.CornerConnected
+	}
+	log.Fatalf("Unknown connectivity %s. Must be one of face, edge and corner\n", name)
+	return pfutil.FaceConnected
+}
+
+// parseDomainSize parses a comma separated list of integers
+func parseDomainSize(domain string) []int {
+	if domain == "" {
+		log.Fatalf("The domain size must be given when reading binary files\n")
+		return nil
+	}
+	domainSize := []int{}
+	for _, v := range strings.Split(domain, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			log.Fatalf("Could not parse domain size: %s\n", err)
+		}
+		domainSize = append(domainSize, n)
+	}
+	return domainSize
+}
+
+// loadClusterCsv reads the passed columns from a CSV file and infers the domain size from
+// the positions
+func loadClusterCsv(fname string, columns string) ([]pfutil.ImmutableSlice, []int) {
+	names := []string{""}
+	if columns != "" {
+		names = strings.Split(columns, ",")
+	}
+
+	data := []pfutil.ImmutableSlice{}
+	var domainSize []int
+	for _, name := range names {
+		var values []float64
+		values, domainSize = readCsvColumn(fname, name)
+		data = append(data, &pfutil.RealSlice{Data: values})
+	}
+	return data, domainSize
+}
+
+// readCsvColumn reads one column from a CSV file. The values are ordered according to
+// the node index and the domain size is inferred from the positions
+func readCsvColumn(fname string, column string) ([]float64, []int) {
+	rows := readData(fname, column)
+	domainSize := csvDomainSize(rows)
+	values := make([]float64, len(rows))
+	for _, row := range rows {
+		pos := []int{row.X, row.Y, row.Z}[:len(domainSize)]
+		values[pfutil.NodeIdx(domainSize, pos)] = row.Value
+	}
+	return values, domainSize
+}
+
+// csvDomainSize returns the domain size of the data. Trailing dimensions where all
+// positions are zero are removed
+func csvDomainSize(rows []DataRow) []int {
+	domainSize := []int{1, 1, 1}
+	for _, row := range rows {
+		for i, v := range []int{row.X, row.Y, row.Z} {
+			if v+1 > domainSize[i] {
+				domainSize[i] = v + 1
+			}
+		}
+	}
+	if domainSize[2] > 1 {
+		return domainSize
+	} else if domainSize[1] > 1 {
+		return domainSize[:2]
+	}
+	return domainSize[:1]
+}
+
+// loadClusterBinary loads binary files written by Float64IO
+func loadClusterBinary(fnames []string, domainSize []int) ([]pfutil.ImmutableSlice, []int) {
+	data := []pfutil.ImmutableSlice{}
+	for _, fname := range fnames {
+		values := pf.LoadFloat64(fname)
+		if len(values) != pfutil.ProdInt(domainSize) {
+			log.Fatalf("%s contains %d values, but the domain has %d nodes\n", fname, len(values), pfutil.ProdInt(domainSize))
+		}
+		data = append(data, &pfutil.RealSlice{Data: values})
+	}
+	return data, domainSize
+}
+
+// printClusterSummary prints the number of clusters and the size distribution
+func printClusterSummary(clusters []pfutil.Cluster) {
+	numPercolating := 0
+	for _, c := range clusters {
+		if c.Percolating {
+			numPercolating++
+		}
+	}
+	fmt.Printf("Number of clusters: %d (%d percolating)\n", len(clusters), numPercolating)
+
+	dist := pfutil.SizeDistribution(clusters)
+	volumes := []int{}
+	for v := range dist {
+		volumes = append(volumes, v)
+	}
+	sort.Ints(volumes)
+	fmt.Printf("%*s %*s\n", ColWidth, "Volume", ColWidth, "Count")
+	fmt.Printf("%s\n", singleLine(2*ColWidth+1))
+	for _, v := range volumes {
+		fmt.Printf("%*d %*d\n", ColWidth, v, ColWidth, dist[v])
+	}
+}
+
+// writeClusters writes the statistics of each cluster to a CSV file
+func writeClusters(fname string, clusters []pfutil.Cluster) {
+	out, err := os.Create(fname)
+	if err != nil {
+		log.Fatalf("Could not open file: %s\n", err)
+		return
+	}
+	defer out.Close()
+
+	writer := csv.NewWriter(out)
+	defer writer.Flush()
+	writer.Write([]string{"label", "phase", "volume", "X", "Y", "Z", "percolating"})
+	for _, c := range clusters {
+		centroid := make([]float64, 3)
+		copy(centroid, c.Centroid)
+		writer.Write([]string{
+			fmt.Sprintf("%d", c.Label),
+			fmt.Sprintf("%d", c.Phase),
+			fmt.Sprintf("%d", c.Volume),
+			fmt.Sprintf("%f", centroid[0]),
+			fmt.Sprintf("%f", centroid[1]),
+			fmt.Sprintf("%f", centroid[2]),
+			fmt.Sprintf("%t", c.Percolating),
+		})
+	}
+}
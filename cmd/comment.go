@@ -26,6 +26,11 @@ updates the comment associated with the simulation with ID 346.
 
 If simid is not given (or is a negative number), the newest simulation
 is used.
+
+gopf db comment mydatabase.db --where "temperature>300"
+
+shows the comments of all simulations with a temperature above 300 (see gopf db query).
+If --new is given, the comments of all matching simulations are updated.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 1 {
@@ -51,33 +56,44 @@ is used.
 			return
 		}
 
+		if simIDs, ok := whereSimIDs(cmd, sqlDB); ok {
+			for _, id := range simIDs {
+				updateOrShowComment(sqlDB, id, newMsg)
+			}
+			return
+		}
+
 		if simid < 0 {
 			printAllComments(sqlDB)
 			return
 		}
+		updateOrShowComment(sqlDB, simid, newMsg)
+	},
+}
 
-		if newMsg != "" {
-			statement, err := sqlDB.Prepare("UPDATE comments SET value=? WHERE simId=?")
-			if err != nil {
-				fmt.Printf("%s\n", err)
-				return
-			}
-			statement.Exec(newMsg, simid)
-			fmt.Printf("Comment of id %d was successfully updated\n", simid)
-		} else {
-			rows, err := sqlDB.Query("SELECT value FROM comments WHERE simId=?", simid)
-			if err != nil {
-				fmt.Printf("%s\n", err)
-				return
-			}
-			var comment string
-			for rows.Next() {
-				rows.Scan(&comment)
-			}
-			fmt.Printf("Sim id %d:\n%s\n", simid, comment)
+// updateOrShowComment updates the comment of the simulation if newMsg is not empty.
+// Otherwise, the comment is printed
+func updateOrShowComment(sqlDB *sql.DB, simid int, newMsg string) {
+	if newMsg != "" {
+		statement, err := sqlDB.Prepare("UPDATE comments SET value=? WHERE simId=?")
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return
 		}
-
-	},
+		statement.Exec(newMsg, simid)
+		fmt.Printf("Comment of id %d was successfully updated\n", simid)
+	} else {
+		rows, err := sqlDB.Query("SELECT value FROM comments WHERE simId=?", simid)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return
+		}
+		var comment string
+		for rows.Next() {
+			rows.Scan(&comment)
+		}
+		fmt.Printf("Sim id %d:\n%s\n", simid, comment)
+	}
 }
 
 func printAllComments(db *sql.DB) {
@@ -124,4 +140,5 @@ func init() {
 	dbCmd.AddCommand(commentCmd)
 	commentCmd.Flags().IntP("simid", "i", -1, "Simulation ID. If negative, the last simulation will be shown.")
 	commentCmd.Flags().StringP("new", "n", "", "New comment. If empty, the existing comment will be shown.")
+	addWhereFlag(commentCmd)
 }
@@ -17,7 +17,6 @@ package cmd
 
 import (
 	"encoding/csv"
-	"image"
 	"io"
 	"log"
 	"os"
@@ -25,14 +24,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/davidkleiven/gopf/pfutil"
 	"github.com/spf13/cobra"
-	"gonum.org/v1/plot"
-	"gonum.org/v1/plot/palette"
-	"gonum.org/v1/plot/palette/moreland"
-	"gonum.org/v1/plot/plotter"
-	"gonum.org/v1/plot/vg"
-	"gonum.org/v1/plot/vg/draw"
-	"gonum.org/v1/plot/vg/vgimg"
 )
 
 // contourCmd represents the contour command
@@ -69,69 +62,48 @@ will be selected.
 			return
 		}
 
+		cmapName, err := cmd.Flags().GetString("colormap")
+		if err != nil {
+			log.Fatalf("Could not retrieve colormap: %s\n", err)
+			return
+		}
+
+		vmin, err := cmd.Flags().GetFloat64("min")
+		if err != nil {
+			log.Fatalf("Could not retrieve min: %s\n", err)
+			return
+		}
+
+		vmax, err := cmd.Flags().GetFloat64("max")
+		if err != nil {
+			log.Fatalf("Could not retrieve max: %s\n", err)
+			return
+		}
+
 		header := readHeader(fname)
 		idx := getColIndex(header, column)
 		column = header[idx]
 
 		rows := readData(fname, column)
-		min, max := dataRange(rows)
-
 		data := NewHeatMapData(rows)
-		colormap := moreland.Kindlmann()
-		rng := max - min
-		colormap.SetMin(min - 0.05*rng)
-		colormap.SetMax(max + 0.05*rng)
-
-		plt := plot.New()
-
-		heatImg := fillImage(data, colormap)
-		n, m := data.Dims()
-		pImg := plotter.NewImage(heatImg, 0, 0, float64(n), float64(m))
-		plt.Add(pImg)
-
-		barplt := plot.New()
-
-		plt.X.Label.Text = "x position (\u0394 x)"
-		plt.Y.Label.Text = "y position (\u0394 x)"
-
-		bar := plotter.ColorBar{
-			ColorMap: colormap,
+		colormap, err := pfutil.NewColorMap(cmapName)
+		if err != nil {
+			log.Fatalf("%s\n", err)
 		}
-		barplt.Add(&bar)
-
-		img := vgimg.New(4*vg.Inch, 4*vg.Inch)
-		dc := draw.New(img)
-		top := draw.Crop(dc, 0.325*vg.Inch, -0.325*vg.Inch, 3.35*vg.Inch, 0.0)
-		bottom := draw.Crop(dc, 0.325*vg.Inch, -0.325*vg.Inch, 0.0, -0.65*vg.Inch)
-		barplt.HideY()
-		barplt.Title.Text = column
-
-		barplt.Draw(top)
-		plt.Draw(bottom)
+		pfutil.SetColorRange(colormap, data, vmin, vmax)
 
+		img, err := pfutil.RenderHeatMap(data, colormap, column)
 		if err != nil {
-			log.Fatalf("Could not open output file: %s\n", err)
+			log.Fatalf("Could not render image: %s\n", err)
 		}
 
-		format := strings.ToLower(filepath.Ext(out))
-		if len(format) != 0 {
-			format = format[1:]
-		} else {
+		if filepath.Ext(out) == "" {
 			log.Fatalf("Could not extract file extension from outfile.\n")
 			return
 		}
 
-		png := vgimg.PngCanvas{Canvas: img}
-
-		file, err := os.Create(out)
-		if err != nil {
-			log.Fatalf("Could not open file: %s\n", err)
-		}
-		defer file.Close()
-
-		_, err = png.WriteTo(file)
-		if err != nil {
-			log.Fatalf("Could not write to file: %s\n", err)
+		if err := pfutil.SavePNG(out, img); err != nil {
+			log.Fatalf("Could not write image: %s\n", err)
 		}
 		log.Printf("Image written to %s\n", out)
 	},
@@ -143,6 +115,9 @@ func init() {
 	contourCmd.Flags().StringP("fname", "f", "", "CSV file with the data")
 	contourCmd.Flags().StringP("column", "c", "", "Name of the of the column to be plotted. Must be one of the names in the header of the file.")
 	contourCmd.Flags().StringP("out", "o", "gopfPlot.png", "Outfile where the resulting image is stored.")
+	contourCmd.Flags().String("colormap", "kindlmann", "Colormap. One of "+strings.Join(pfutil.ColorMapNames(), ", "))
+	contourCmd.Flags().Float64("min", 0.0, "Lower end of the color range. The range is set automatically if max <= min")
+	contourCmd.Flags().Float64("max", 0.0, "Upper end of the color range. The range is set automatically if max <= min")
 }
 
 // DataRow represents one row
@@ -283,21 +258,6 @@ func readData(fname string, column string) []DataRow {
 	return rows
 }
 
-func dataRange(records []DataRow) (float64, float64) {
-	minval := records[0].Value
-	maxval := records[0].Value
-	for _, row := range records {
-		if row.Value < minval {
-			minval = row.Value
-		}
-
-		if row.Value > maxval {
-			maxval = row.Value
-		}
-	}
-	return minval, maxval
-}
-
 func readHeader(fname string) []string {
 	file, err := os.Open(fname)
 	if err != nil {
@@ -312,23 +272,3 @@ func readHeader(fname string) []string {
 	}
 	return header
 }
-
-func fillImage(data HeatMapData, cmap palette.ColorMap) *image.RGBA64 {
-	n, m := data.Dims()
-	img := image.NewRGBA64(image.Rectangle{
-		Min: image.Point{X: 0, Y: 0},
-		Max: image.Point{X: n, Y: m},
-	})
-
-	for i := 0; i < n; i++ {
-		for j := 0; j < m; j++ {
-			color, err := cmap.At(data.Z(i, j))
-			if err != nil {
-				log.Fatalf("%s\n", err)
-				return img
-			}
-			img.Set(i, j, color)
-		}
-	}
-	return img
-}
@@ -0,0 +1,129 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/davidkleiven/gopf/pf"
+	"github.com/spf13/cobra"
+)
+
+// deleteCmd represents the delete command
+var deleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete simulations from the database",
+	Long: `Delete removes all rows belonging to the selected simulations from all tables
+in one transaction. Afterwards, the database file is compacted with VACUUM.
+
+Examples:
+
+gopf db delete mydatabase.db --simid 3 --dry-run
+
+prints the number of rows that would be deleted from each table without modifying
+the database.
+
+gopf db delete mydatabase.db --where "model=='test'"
+
+deletes all simulations where the attribute model is test (see gopf db query). The
+deletion must be confirmed, unless --yes is given.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Printf("A database name must be given.")
+			return
+		}
+
+		if _, err := os.Stat(args[0]); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		sqlDB, err := sql.Open("sqlite3", args[0])
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		defer sqlDB.Close()
+
+		simid, err := cmd.Flags().GetInt("simid")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		simIDs, ok := whereSimIDs(cmd, sqlDB)
+		if !ok {
+			if simid < 0 {
+				log.Fatalf("Either simid or where must be given\n")
+				return
+			}
+			simIDs = []int{simid}
+		}
+		if len(simIDs) == 0 {
+			fmt.Printf("No simulations matched\n")
+			return
+		}
+
+		db := pf.FieldDB{DB: sqlDB}
+		counts, err := db.DeleteSimulations(simIDs, true)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		fmt.Printf("Simulations: %v\n", simIDs)
+		printRowCounts(counts)
+		if dryRun {
+			return
+		}
+
+		if !yes && !confirm(os.Stdin, fmt.Sprintf("Delete %d simulations?", len(simIDs))) {
+			fmt.Printf("Aborted\n")
+			return
+		}
+
+		if _, err := db.DeleteSimulations(simIDs, false); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		if err := db.Vacuum(); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		fmt.Printf("Deleted %d simulations\n", len(simIDs))
+	},
+}
+
+func init() {
+	dbCmd.AddCommand(deleteCmd)
+	deleteCmd.Flags().IntP("simid", "i", -1, "Simulation ID to delete")
+	deleteCmd.Flags().Bool("dry-run", false, "Print the number of rows that would be deleted without modifying the database")
+	deleteCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+	addWhereFlag(deleteCmd)
+}
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
+	"github.com/davidkleiven/gopf/pf"
+	"github.com/davidkleiven/gopf/pfutil"
 	"github.com/spf13/cobra"
 )
 
@@ -34,6 +37,11 @@ gopf db export mydatabaset.db --type fielddata --all -o /path/to/location/datase
 
 exports all timesteps to csv files. The files will be stored in the folder /path/to/location/
 and have names dataset0.csv, dataset1.csv, dataset2.csv and so on.
+
+gopf db export mydatabase.db --type timeseries --where "temperature>300"
+
+exports the timeseries of all simulations with a temperature above 300 (see gopf db query).
+The simulation ID is appended to the name of the output files.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 1 {
@@ -69,8 +77,9 @@ and have names dataset0.csv, dataset1.csv, dataset2.csv and so on.
 			simid = newestSimulationID(db)
 		}
 
-		if outfile == "" {
-			outfile = fmt.Sprintf("%s_%d.csv", ftype, simid)
+		simIDs, multiple := whereSimIDs(cmd, db)
+		if !multiple {
+			simIDs = []int{simid}
 		}
 
 		timestep, err := cmd.Flags().GetInt("timestep")
@@ -85,21 +94,38 @@ and have names dataset0.csv, dataset1.csv, dataset2.csv and so on.
 			return
 		}
 
-		switch ftype {
-		case "timeseries", "ts":
-			exportTimeseries(db, outfile, simid)
-		case "field", "fieldData", "fd":
-			if all {
-				steps := allTimeSteps(db, simid)
-				for _, step := range steps {
-					fname := fmt.Sprintf("%s%d.csv", outfile, step)
-					exportFieldData(db, step, simid, fname)
+		if len(simIDs) == 0 {
+			fmt.Printf("No simulations matched\n")
+			return
+		}
+
+		for _, id := range simIDs {
+			fname := outfile
+			if fname == "" {
+				fname = fmt.Sprintf("%s_%d.csv", ftype, id)
+			} else if multiple {
+				fname = fmt.Sprintf("%s_%d", strings.TrimSuffix(fname, ".csv"), id)
+				if !all {
+					fname += ".csv"
+				}
+			}
+
+			switch ftype {
+			case "timeseries", "ts":
+				exportTimeseries(db, fname, id)
+			case "field", "fieldData", "fd":
+				if all {
+					steps := allTimeSteps(db, id)
+					for _, step := range steps {
+						exportFieldData(db, step, id, fmt.Sprintf("%s%d.csv", fname, step))
+					}
+				} else {
+					exportFieldData(db, timestep, id, fname)
 				}
-			} else {
-				exportFieldData(db, timestep, simid, outfile)
+			default:
+				fmt.Printf("Unknown export type %s\n", ftype)
+				return
 			}
-		default:
-			fmt.Printf("Unknown export type %s\n", ftype)
 		}
 	},
 }
@@ -111,6 +137,7 @@ func init() {
 	exportCmd.Flags().IntP("simid", "i", -1, "Simulation ID. If negative, the newest ID will be used.")
 	exportCmd.Flags().IntP("timestep", "s", 0, "Timestep to export (only relevant if type is field).")
 	exportCmd.Flags().BoolP("all", "a", false, "If given and type is field, all time steps will exported.")
+	addWhereFlag(exportCmd)
 }
 
 func appendFileExtension(fname string) string {
@@ -134,34 +161,30 @@ func exportTimeseries(db *sql.DB, outfile string, simid int) {
 		keys = append(keys, key)
 	}
 
-	// Extract maximum number timestep
-	rows, err = db.Query("SELECT MAX(timestep) FROM timeseries WHERE simId=?", simid)
-	if err != nil {
-		fmt.Printf("%s\n", err)
-		return
-	}
-
-	var maxStep int
-	for rows.Next() {
-		rows.Scan(&maxStep)
-	}
-
-	dataArray := make([]map[string]float64, maxStep+1)
-	for i := range dataArray {
-		dataArray[i] = make(map[string]float64)
-	}
-
-	rows, err = db.Query("SELECT key,value,timestep FROM timeseries WHERE simId=?", simid)
+	// Extract the data grouped by timestep. Only timesteps where data was recorded
+	// are exported
+	rows, err = db.Query("SELECT key,value,timestep,time FROM timeseries WHERE simId=? ORDER BY timestep", simid)
 	if err != nil {
 		fmt.Printf("%s\n", err)
 		return
 	}
 	var timestep int
 	var value float64
+	var time sql.NullFloat64
 
+	timesteps := []int{}
+	times := make(map[int]sql.NullFloat64)
+	dataArray := make(map[int]map[string]float64)
 	for rows.Next() {
-		rows.Scan(&key, &value, &timestep)
+		rows.Scan(&key, &value, &timestep, &time)
+		if _, ok := dataArray[timestep]; !ok {
+			timesteps = append(timesteps, timestep)
+			dataArray[timestep] = make(map[string]float64)
+		}
 		dataArray[timestep][key] = value
+		if time.Valid {
+			times[timestep] = time
+		}
 	}
 
 	out, err := os.Create(outfile)
@@ -173,11 +196,16 @@ func exportTimeseries(db *sql.DB, outfile string, simid int) {
 	writer := csv.NewWriter(out)
 	defer writer.Flush()
 
-	writer.Write(keys)
-	record := make([]string, len(keys))
-	for _, item := range dataArray {
+	writer.Write(append([]string{"timestep", "time"}, keys...))
+	record := make([]string, len(keys)+2)
+	for _, ts := range timesteps {
+		record[0] = fmt.Sprintf("%d", ts)
+		record[1] = ""
+		if t, ok := times[ts]; ok {
+			record[1] = fmt.Sprintf("%f", t.Float64)
+		}
 		for j, k := range keys {
-			record[j] = fmt.Sprintf("%f", item[k])
+			record[j+2] = fmt.Sprintf("%f", dataArray[ts][k])
 		}
 		writer.Write(record)
 	}
@@ -189,53 +217,19 @@ func exportFieldData(db *sql.DB, ts int, simid int, outfile string) {
 		ts = largestTimestep(db, simid)
 		log.Printf("Negative timestep provided. Exporting data for the largest timestep instead (=%d)\n", ts)
 	}
-	rows, err := db.Query("SELECT COUNT (*) FROM fields WHERE simId=? AND timestep=?", simid, ts)
-	var numRows int
-	for rows.Next() {
-		rows.Scan(&numRows)
-	}
-	if numRows == 0 {
-		fmt.Printf("No entries in DB for id: %d and timestep %d\n", simid, ts)
-		return
-	}
 
-	rows, err = db.Query("SELECT DISTINCT name FROM fields WHERE simId=? AND timestep=?", simid, ts)
+	// The fields may be stored either as one row per node or as blobs. Both layouts are
+	// handled by the FieldDB
+	fdb := pf.FieldDB{DB: db}
+	fields, domainSize, err := fdb.LoadWithDomainSize(simid, ts)
 	if err != nil {
 		fmt.Printf("%s\n", err)
 		return
 	}
-	keys := []string{}
-	var key string
-	for rows.Next() {
-		rows.Scan(&key)
-		keys = append(keys, key)
-	}
-
-	positions := [][]int{}
-	rows, err = db.Query("SELECT X,Y,Z FROM positions ORDER BY id")
-	var x, y, z int
-	for rows.Next() {
-		rows.Scan(&x, &y, &z)
-		positions = append(positions, []int{x, y, z})
-	}
-
-	data := make([]map[string]float64, len(positions))
-	for i := range data {
-		data[i] = make(map[string]float64)
-	}
-
-	rows, err = db.Query("SELECT name,value,positionId FROM fields WHERE simId=? AND timestep=?", simid, ts)
-	if err != nil {
-		fmt.Printf("%s\n", err)
+	if len(fields) == 0 {
+		fmt.Printf("No entries in DB for id: %d and timestep %d\n", simid, ts)
 		return
 	}
-	var name string
-	var value float64
-	var positionID int
-	for rows.Next() {
-		rows.Scan(&name, &value, &positionID)
-		data[positionID][name] = value
-	}
 
 	out, err := os.Create(outfile)
 	if err != nil {
@@ -246,51 +240,44 @@ func exportFieldData(db *sql.DB, ts int, simid int, outfile string) {
 	writer := csv.NewWriter(out)
 	defer writer.Flush()
 
-	header := make([]string, 3+len(keys))
+	header := make([]string, 3+len(fields))
 	header[0] = "X"
 	header[1] = "Y"
 	header[2] = "Z"
-	copy(header[3:], keys)
+	for i, f := range fields {
+		header[3+i] = f.Name
+	}
 	writer.Write(header)
-	record := make([]string, len(keys)+3)
-	for i, item := range data {
-		for j, v := range positions[i] {
+	record := make([]string, len(fields)+3)
+	pos3 := make([]int, 3)
+	for i := range fields[0].Data {
+		copy(pos3, pfutil.Pos(domainSize, i))
+		for j, v := range pos3 {
 			record[j] = fmt.Sprintf("%d", v)
 		}
-		for j, k := range keys {
-			record[3+j] = fmt.Sprintf("%f", item[k])
+		for j, f := range fields {
+			record[3+j] = fmt.Sprintf("%f", real(f.Data[i]))
 		}
 		writer.Write(record)
 	}
 	fmt.Printf("Field data written to %s\n", outfile)
 }
 
-// largestTimestep extracts the largest timestep from the fields table
+// largestTimestep extracts the largest timestep where fields are stored
 func largestTimestep(db *sql.DB, simid int) int {
-	rows, err := db.Query("SELECT MAX(timestep) FROM fields WHERE simId=?", simid)
-	if err != nil {
-		log.Fatalf("Could not determine maximum timestep: %s\n", err)
+	timesteps := allTimeSteps(db, simid)
+	if len(timesteps) == 0 {
+		log.Fatalf("No fields stored for simulation %d\n", simid)
 		return 0
 	}
-	var maxts int
-	for rows.Next() {
-		rows.Scan(&maxts)
-	}
-	return maxts
+	return timesteps[len(timesteps)-1]
 }
 
 func allTimeSteps(db *sql.DB, simid int) []int {
-	rows, err := db.Query("SELECT DISTINCT timestep FROM fields WHERE simId=?", simid)
-	timesteps := []int{}
+	fdb := pf.FieldDB{DB: db}
+	timesteps, err := fdb.Timesteps(simid)
 	if err != nil {
-		log.Fatalf("Coult not select timesteps: %s\n", err)
-		return timesteps
-	}
-
-	var step int
-	for rows.Next() {
-		rows.Scan(&step)
-		timesteps = append(timesteps, step)
+		log.Fatalf("Could not select timesteps: %s\n", err)
 	}
 	return timesteps
 }
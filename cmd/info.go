@@ -19,8 +19,10 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 
+	"github.com/davidkleiven/gopf/pf"
 	"github.com/spf13/cobra"
 )
 
@@ -36,9 +38,17 @@ var infoCmd = &cobra.Command{
 	Short: "Show general information/summary of the database",
 	Long: `info prints general information about the database.
 It prints the number of calcualtions, dimensions of the underlying
-simulation domain, the name of all the fields in the database,
+simulation domains (with the number of simulations having each size),
+the name of all the fields in the database,
 the name of all attributes that are attached to the simulation, and
 the name of all timeseries that are tracked.
+
+gopf db info mydatabase.db --simid 3
+
+prints the provenance of the simulation with ID 3. That is the equations, the
+registered terms and their parameters, the time stepper, the time step, the
+domain size, random seeds, the Go and gopf versions, the hostname and the
+command line that produced the simulation.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 1 {
@@ -52,6 +62,16 @@ the name of all timeseries that are tracked.
 			return
 		}
 
+		simID, err := cmd.Flags().GetInt("simid")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		if simID >= 0 {
+			printProvenance(db, simID)
+			return
+		}
+
 		// Extract the number of calculations
 		rows, err := db.Query("SELECT COUNT (*) FROM simIDs")
 		if err != nil {
@@ -64,7 +84,11 @@ the name of all timeseries that are tracked.
 		}
 
 		// Extract the names of the fields
-		rows, err = db.Query("SELECT DISTINCT name FROM fields")
+		fieldQuery := "SELECT DISTINCT name FROM fields"
+		if hasTable(db, "fieldBlobs") {
+			fieldQuery += " UNION SELECT DISTINCT name FROM fieldBlobs"
+		}
+		rows, err = db.Query(fieldQuery)
 		if err != nil {
 			log.Fatalf("%s\n", err)
 			return
@@ -76,34 +100,8 @@ the name of all timeseries that are tracked.
 			fields = append(fields, fieldName)
 		}
 
-		// Extract the dimension of the data
-		rows, err = db.Query("SELECT MAX(X) FROM positions")
-		var nx, ny, nz int
-		if err != nil {
-			log.Fatalf("%s\n", err)
-			return
-		}
-		for rows.Next() {
-			rows.Scan(&nx)
-		}
-
-		rows, err = db.Query("SELECT MAX(Y) FROM positions")
-		if err != nil {
-			log.Fatalf("%s\n", err)
-			return
-		}
-		for rows.Next() {
-			rows.Scan(&ny)
-		}
-
-		rows, err = db.Query("SELECT MAX(Z) FROM positions")
-		if err != nil {
-			log.Fatalf("%s\n", err)
-			return
-		}
-		for rows.Next() {
-			rows.Scan(&nz)
-		}
+		// Extract the dimension of the data. Each simulation may have its own domain size
+		dims := domainSizes(db)
 
 		// Extract attributes
 		rows, err = db.Query("SELECT DISTINCT key FROM simAttributes")
@@ -148,7 +146,7 @@ the name of all timeseries that are tracked.
 		fmt.Printf("| %-*s | %-*s |\n", DescWidth, "Desc.", ValueWidth, "Value")
 		fmt.Printf("%s\n", singleLine(width))
 		fmt.Printf("| %-*s | %*d |\n", DescWidth, "Num calc.", ValueWidth, numCalc)
-		fmt.Printf("| %-*s | %*s |\n", DescWidth, "Dims", ValueWidth, dimString(nx+1, ny+1, nz+1))
+		printList("Dims", dims)
 		printList("Fields", fields)
 		printList("Attributes", attrs)
 		printList("Timeseries", tnames)
@@ -160,6 +158,35 @@ func dimString(nx, ny, nz int) string {
 	return fmt.Sprintf("(%d, %d, %d)", nx, ny, nz)
 }
 
+// domainSizes returns all distinct domain sizes together with the number of simulations
+// having each size. For databases created before the domain size was stored per
+// simulation, the domain size is extracted from the positions table.
+func domainSizes(db *sql.DB) []string {
+	dims := []string{}
+	if hasTable(db, "domainSizes") {
+		rows, err := db.Query("SELECT shape, COUNT(*) FROM domainSizes GROUP BY shape ORDER BY shape")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		defer rows.Close()
+		var shape string
+		var count int
+		for rows.Next() {
+			rows.Scan(&shape, &count)
+			dims = append(dims, fmt.Sprintf("(%s) x %d", strings.Replace(shape, ",", ", ", -1), count))
+		}
+		if len(dims) > 0 {
+			return dims
+		}
+	}
+
+	var nx, ny, nz sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(X), MAX(Y), MAX(Z) FROM positions").Scan(&nx, &ny, &nz); err != nil {
+		log.Fatalf("%s\n", err)
+	}
+	return []string{dimString(int(nx.Int64)+1, int(ny.Int64)+1, int(nz.Int64)+1)}
+}
+
 func printList(name string, array []string) {
 	line := strings.Join(array, ", ")
 	desc := name
@@ -178,6 +205,64 @@ func printList(name string, array []string) {
 
 }
 
+// printProvenance prints the provenance of the simulation with the passed ID
+func printProvenance(db *sql.DB, simID int) {
+	fdb := pf.FieldDB{DB: db}
+	p, err := fdb.LoadProvenance(simID)
+	if err != nil {
+		log.Fatalf("%s\n", err)
+		return
+	}
+	if p.Stepper == "" && len(p.Equations) == 0 {
+		fmt.Printf("No provenance stored for simulation %d\n", simID)
+		return
+	}
+
+	domainSize := make([]string, len(p.DomainSize))
+	for i, n := range p.DomainSize {
+		domainSize[i] = fmt.Sprintf("%d", n)
+	}
+	seeds := []string{}
+	for name, seed := range p.Seeds {
+		seeds = append(seeds, fmt.Sprintf("%s=%d", name, seed))
+	}
+	sort.Strings(seeds)
+
+	width := DescWidth + ValueWidth + 7
+	fmt.Printf("%s\n", singleLine(width))
+	fmt.Printf("| %-*s | %-*s |\n", DescWidth, "Desc.", ValueWidth, "Value")
+	fmt.Printf("%s\n", singleLine(width))
+	fmt.Printf("| %-*s | %*d |\n", DescWidth, "Sim ID", ValueWidth, simID)
+	for i, eq := range p.Equations {
+		printList(fmt.Sprintf("Equation %d", i), []string{eq})
+	}
+	printList("Fields", p.Fields)
+	printList("Domain size", domainSize)
+	printList("Dt", []string{fmt.Sprintf("%g", p.Dt)})
+	printList("Stepper", []string{p.Stepper})
+	printList("Seeds", seeds)
+	printList("Go version", []string{p.GoVersion})
+	printList("gopf version", []string{p.GopfVersion})
+	printList("Hostname", []string{p.Hostname})
+	printList("Command line", []string{p.CommandLine})
+	fmt.Printf("%s\n", singleLine(width))
+
+	// Print the registered terms, sources and the time stepper
+	fmt.Printf("| %-*s | %-*s |\n", DescWidth, "Component", ValueWidth, "Kind, type and parameters")
+	fmt.Printf("%s\n", singleLine(width))
+	for _, c := range p.Components {
+		params := []string{}
+		for name, value := range c.Parameters {
+			params = append(params, fmt.Sprintf("%s=%s", name, value))
+		}
+		sort.Strings(params)
+		printList(c.Name, []string{fmt.Sprintf("%s %s", c.Kind, c.Type)})
+		printList("", params)
+	}
+	fmt.Printf("%s\n", singleLine(width))
+}
+
 func init() {
 	dbCmd.AddCommand(infoCmd)
+	infoCmd.Flags().IntP("simid", "i", -1, "Simulation ID. If given, the provenance of the simulation is printed.")
 }
@@ -51,6 +51,8 @@ this the three first columns gives the position (x, y, z) position of the point,
 successive columns holds the value of the field indicated in the header.
 
 To plot data along a line, at least two of -x, -y and -z must be specified.
+The only exception is data from a 1D simulation (where Y and Z are always zero).
+In that case, the data is plotted along the x-axis if none of them are given.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fname, err := cmd.Flags().GetString("fname")
@@ -88,21 +90,27 @@ To plot data along a line, at least two of -x, -y and -z must be specified.
 			return
 		}
 
+		if numSet(x, y, z) == 0 && isOneDimensional(readData(fname, "")) {
+			y = 0
+			z = 0
+		}
+
 		if numSet(x, y, z) != 2 {
 			log.Fatalf("Exactly two of x, y and set must be specified.\n")
 			return
 		}
 
-		fields, err := cmd.Flags().GetString("fields")
+		fields, err := cmd.Flags().GetString("data")
+		if err != nil {
+			log.Fatalf("Could not read fields: %s\n", err)
+			return
+		}
 
 		var fieldArray []string
 		if fields == "" {
 			fieldArray = readHeader(fname)[3:]
 		} else {
-			splitted := strings.Split(fields, ",")
-			for i := 4; i < len(splitted); i++ {
-				fieldArray = append(fieldArray, splitted[i])
-			}
+			fieldArray = strings.Split(fields, ",")
 		}
 
 		plt := plot.New()
@@ -149,6 +157,16 @@ func numSet(x, y, z int) int {
 	return numSet
 }
 
+// isOneDimensional returns true if all rows are located on the x-axis
+func isOneDimensional(rows []DataRow) bool {
+	for _, row := range rows {
+		if row.Y != 0 || row.Z != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 func init() {
 	rootCmd.AddCommand(lineplotCmd)
 
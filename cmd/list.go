@@ -3,7 +3,10 @@ package cmd
 import (
 	"database/sql"
 	"fmt"
+	"log"
+	"strings"
 
+	"github.com/davidkleiven/gopf/pf"
 	"github.com/spf13/cobra"
 )
 
@@ -23,6 +26,10 @@ gopf db list mydatabase.db -c comment
 
 lists all the comments along with the simulations ID.
 
+gopf db list mydatabase.db -c simid --tags study-A,coarse
+
+lists only the simulations that have both the tags study-A and coarse.
+
 In all cases, the items are sorted according to the time of creation
 such that the newest entries appear first.
 	`,
@@ -44,13 +51,20 @@ such that the newest entries appear first.
 			return
 		}
 
+		tags, err := cmd.Flags().GetStringSlice("tags")
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			return
+		}
+
 		sqlDB, err := sql.Open("sqlite3", args[0])
+		filter := tagFilter(sqlDB, tags)
 
 		switch content {
 		case "simId", "simid", "id":
-			showSimulationIds(sqlDB, max)
+			showSimulationIds(sqlDB, max, filter)
 		case "comment":
-			showComments(sqlDB, max)
+			showComments(sqlDB, max, filter)
 		default:
 			fmt.Printf("Unknown option %s\n", content)
 		}
@@ -61,10 +75,29 @@ func init() {
 	dbCmd.AddCommand(listCmd)
 	listCmd.Flags().StringP("content", "c", "simId", "Specify what should be listed. Can be one of simId or comment.")
 	listCmd.Flags().IntP("max", "m", 20, "Maximum number of items that are listed")
+	listCmd.Flags().StringSliceP("tags", "t", []string{}, "Only list simulations having all of the comma separated tags")
+}
+
+// tagFilter returns a WHERE clause that selects the simulations having all the tags.
+// If no tags are given, an empty string is returned
+func tagFilter(db *sql.DB, tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	fdb := pf.FieldDB{DB: db}
+	simIDs, err := fdb.FilterByTags(tags...)
+	if err != nil {
+		log.Fatalf("%s\n", err)
+	}
+	ids := make([]string, len(simIDs))
+	for i, id := range simIDs {
+		ids[i] = fmt.Sprintf("%d", id)
+	}
+	return fmt.Sprintf(" WHERE simID IN (%s)", strings.Join(ids, ","))
 }
 
-func showSimulationIds(db *sql.DB, max int) {
-	rows, err := db.Query("SELECT COUNT(*) FROM simIds")
+func showSimulationIds(db *sql.DB, max int, filter string) {
+	rows, err := db.Query("SELECT COUNT(*) FROM simIds" + filter)
 	if err != nil {
 		fmt.Printf("%s\n", err)
 		return
@@ -77,7 +110,7 @@ func showSimulationIds(db *sql.DB, max int) {
 		fmt.Printf("Showing %d of %d rows\n", max, numRows)
 	}
 
-	sql := "SELECT simID,creationTime FROM simIds ORDER BY creationTime DESC LIMIT "
+	sql := "SELECT simID,creationTime FROM simIds" + filter + " ORDER BY creationTime DESC, simID DESC LIMIT "
 	sql += fmt.Sprintf("%d", max)
 	rows, err = db.Query(sql)
 
@@ -93,8 +126,8 @@ func showSimulationIds(db *sql.DB, max int) {
 	fmt.Printf("-----------------------------------------------------------\n")
 }
 
-func showComments(db *sql.DB, max int) {
-	rows, err := db.Query("SELECT COUNT(*) FROM simIds")
+func showComments(db *sql.DB, max int, filter string) {
+	rows, err := db.Query("SELECT COUNT(*) FROM simIds" + filter)
 	if err != nil {
 		fmt.Printf("%s\n", err)
 		return
@@ -108,7 +141,7 @@ func showComments(db *sql.DB, max int) {
 	}
 
 	simIds := []int{}
-	sql := "SELECT simID,creationTime FROM simIds ORDER BY creationTime DESC LIMIT "
+	sql := "SELECT simID,creationTime FROM simIds" + filter + " ORDER BY creationTime DESC, simID DESC LIMIT "
 	sql += fmt.Sprintf("%d", max)
 	rows, err = db.Query(sql)
 	if err != nil {
@@ -0,0 +1,88 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/davidkleiven/gopf/pf"
+	"github.com/spf13/cobra"
+)
+
+// mergeCmd represents the merge command
+var mergeCmd = &cobra.Command{
+	Use:   "merge",
+	Short: "Merge several databases into one",
+	Long: `Merge copies all simulations from one or more databases into a single database.
+This is useful when the runs of a parameter sweep are executed on different nodes, where
+each run writes to its own database file.
+
+Example:
+
+gopf db merge out.db run1.db run2.db run3.db
+
+copies all simulations in run1.db, run2.db and run3.db into out.db. The output database
+is created if it does not exist. Every simulation gets a new ID in out.db, such that IDs
+of deleted simulations are never reused, and the new IDs are printed. Comments,
+attributes, tags, timeseries, provenance and fields are preserved. The input databases
+are not modified, and out.db can not be one of the inputs.
+
+Each input database is merged in a separate transaction. The domain size of each
+simulation is preserved, thus databases with different domain sizes can be merged.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 {
+			fmt.Printf("An output database and at least one input database must be given.")
+			return
+		}
+
+		db, err := sql.Open("sqlite3", args[0])
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		defer db.Close()
+
+		for _, src := range args[1:] {
+			result, err := pf.MergeDatabase(db, src)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+
+			oldIDs := []int{}
+			for id := range result.SimIDs {
+				oldIDs = append(oldIDs, id)
+			}
+			sort.Ints(oldIDs)
+			var numRows int64
+			for _, n := range result.Rows {
+				numRows += n
+			}
+			fmt.Printf("Merged %d simulations (%d rows) from %s\n", len(oldIDs), numRows, src)
+			for _, id := range oldIDs {
+				fmt.Printf("  Sim ID %d -> %d\n", id, result.SimIDs[id])
+			}
+		}
+	},
+}
+
+func init() {
+	dbCmd.AddCommand(mergeCmd)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/davidkleiven/gopf/pf"
+	"github.com/spf13/cobra"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the schema of a database to the latest version",
+	Long: `Migrate upgrades databases created by older versions of gopf in place. The
+schema version of the database is stored in the schemaVersion table. Databases created
+before the schema was versioned have version 0.
+
+Example:
+
+gopf db migrate mydatabase.db --dry-run
+
+lists the migrations that would be applied without modifying the database.
+
+gopf db migrate mydatabase.db
+
+applies all pending migrations. Each migration is applied in a separate transaction, such
+that the database is left at a well defined version if a migration fails. It is
+nevertheless recommended to make a backup copy before migrating large databases.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Printf("A database name must be given.")
+			return
+		}
+
+		if _, err := os.Stat(args[0]); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		db, err := sql.Open("sqlite3", args[0])
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		defer db.Close()
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		version, err := pf.SchemaVersion(db)
+		if err != nil {
+			log.Fatalf("Could not determine the schema version: %s\n", err)
+			return
+		}
+		fmt.Printf("Schema version: %d (latest: %d)\n", version, pf.LatestSchemaVersion())
+
+		pending, err := pf.PendingMigrations(db)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if len(pending) == 0 {
+			fmt.Printf("The database is up to date\n")
+			return
+		}
+
+		if dryRun {
+			fmt.Printf("The following migrations would be applied:\n")
+			printMigrations(pending)
+			return
+		}
+
+		applied, err := pf.Migrate(db)
+		printMigrations(applied)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		fmt.Printf("Applied %d migrations\n", len(applied))
+	},
+}
+
+// printMigrations prints the version and description of each migration
+func printMigrations(migrations []pf.Migration) {
+	for _, m := range migrations {
+		fmt.Printf("%4d: %s\n", m.Version, m.Description)
+	}
+}
+
+func init() {
+	dbCmd.AddCommand(migrateCmd)
+	migrateCmd.Flags().Bool("dry-run", false, "List the pending migrations without applying them")
+}
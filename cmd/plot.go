@@ -0,0 +1,329 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/davidkleiven/gopf/pf"
+	"github.com/spf13/cobra"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+)
+
+// dbPlotCmd represents the db plot command
+var dbPlotCmd = &cobra.Command{
+	Use:   "plot",
+	Short: "Plot timeseries stored in the database",
+	Long: `Plot draws one or more timeseries of one or several simulations on the same axes.
+
+Examples:
+
+gopf db plot mydatabase.db --keys energy -o energy.png
+
+plots the timeseries energy of the newest simulation.
+
+gopf db plot mydatabase.db --where "study=='A'" --keys energy,mass --legend temperature --logy -o sweep.pdf
+
+plots energy and mass of all simulations with the text attribute study equal to A (see
+gopf db query). Each simulation is drawn in its own color and each timeseries with its
+own dash pattern. The legend entries are built from the attribute temperature.
+Simulations can also be selected by ID (--simid 1,4,5) or by tag (--tags study-A). If
+several of --where, --simid and --tags are given, the simulations matching all of them
+are plotted.
+
+The x-axis is the timestep by default. With --xaxis time the physical time is used
+instead, and points stored without time are skipped. With --logx and --logy the axes
+use a logarithmic scale, and points with non-positive values are skipped. The format of
+the output file is given by its extension, which can be png, svg or pdf. If no keys are
+given, all timeseries of the selected simulations are plotted.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Printf("A database name must be given.")
+			return
+		}
+
+		if _, err := os.Stat(args[0]); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		sqlDB, err := sql.Open("sqlite3", args[0])
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		defer sqlDB.Close()
+
+		keys, err := cmd.Flags().GetStringSlice("keys")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		simIDs, err := cmd.Flags().GetIntSlice("simid")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		tags, err := cmd.Flags().GetStringSlice("tags")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		legend, err := cmd.Flags().GetStringSlice("legend")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		xaxis, err := cmd.Flags().GetString("xaxis")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		logx, err := cmd.Flags().GetBool("logx")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		logy, err := cmd.Flags().GetBool("logy")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		title, err := cmd.Flags().GetString("title")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		out, err := cmd.Flags().GetString("out")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		width, err := cmd.Flags().GetFloat64("width")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		height, err := cmd.Flags().GetFloat64("height")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if xaxis != "timestep" && xaxis != "time" {
+			log.Fatalf("Unknown x-axis %s. Must be one of timestep or time.\n", xaxis)
+			return
+		}
+
+		fdb := pf.FieldDB{DB: sqlDB}
+		simIDs = plotSimIDs(cmd, &fdb, simIDs, tags)
+		if len(simIDs) == 0 {
+			fmt.Printf("No simulations matched\n")
+			return
+		}
+
+		attributes, err := fdb.SimAttributes()
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		plt := plot.New()
+		plt.Title.Text = title
+		plt.X.Label.Text = capitalize(xaxis)
+		plt.Y.Label.Text = "Value"
+		if len(keys) == 1 {
+			plt.Y.Label.Text = keys[0]
+		}
+		if logx {
+			plt.X.Scale = plot.LogScale{}
+			plt.X.Tick.Marker = plot.LogTicks{}
+		}
+		if logy {
+			plt.Y.Scale = plot.LogScale{}
+			plt.Y.Tick.Marker = plot.LogTicks{}
+		}
+
+		numLines := 0
+		for i, id := range simIDs {
+			simKeys := keys
+			if len(simKeys) == 0 {
+				if simKeys, err = fdb.TimeSeriesKeys(id); err != nil {
+					log.Fatalf("%s\n", err)
+					return
+				}
+			}
+			for j, key := range simKeys {
+				data, err := fdb.LoadTimeSeries(id, key)
+				if err != nil {
+					log.Printf("%s\n", err)
+					continue
+				}
+				pts, skipped := timeseriesPoints(data, xaxis == "time", logx, logy)
+				if skipped > 0 {
+					log.Printf("Skipped %d points of %s in simulation %d\n", skipped, key, id)
+				}
+				if len(pts) == 0 {
+					continue
+				}
+
+				line, err := plotter.NewLine(pts)
+				if err != nil {
+					log.Fatalf("Could not create line: %s\n", err)
+					return
+				}
+				line.LineStyle.Color = plotutil.Color(i)
+				line.LineStyle.Dashes = plotutil.Dashes(j)
+				plt.Add(line)
+				plt.Legend.Add(plotLabel(key, id, attributes[id], legend, len(simKeys) > 1, len(simIDs) > 1), line)
+				numLines++
+			}
+		}
+
+		if numLines == 0 {
+			log.Fatalf("No timeseries to plot\n")
+			return
+		}
+
+		if err := plt.Save(vg.Length(width)*vg.Inch, vg.Length(height)*vg.Inch, out); err != nil {
+			log.Fatalf("Error when saving: %s\n", err)
+			return
+		}
+		log.Printf("Plotted %d timeseries from %d simulations to %s\n", numLines, len(simIDs), out)
+	},
+}
+
+func init() {
+	dbCmd.AddCommand(dbPlotCmd)
+	dbPlotCmd.Flags().StringSliceP("keys", "k", []string{}, "Comma separated list of timeseries to plot. Default is all timeseries.")
+	dbPlotCmd.Flags().IntSliceP("simid", "s", []int{}, "Comma separated list of simulation IDs. Default is the newest simulation if no other selection is given.")
+	dbPlotCmd.Flags().StringSliceP("tags", "t", []string{}, "Plot the simulations having all of the comma separated tags")
+	dbPlotCmd.Flags().StringSliceP("legend", "l", []string{}, "Comma separated list of attributes used in the legend. Default is the simulation ID.")
+	dbPlotCmd.Flags().StringP("xaxis", "x", "timestep", "Quantity on the x-axis. Can be one of timestep or time.")
+	dbPlotCmd.Flags().Bool("logx", false, "Use a logarithmic scale on the x-axis")
+	dbPlotCmd.Flags().Bool("logy", false, "Use a logarithmic scale on the y-axis")
+	dbPlotCmd.Flags().String("title", "", "Title of the plot")
+	dbPlotCmd.Flags().StringP("out", "o", "timeseries.png", "Output file. The extension (png, svg or pdf) selects the format.")
+	dbPlotCmd.Flags().Float64("width", 6.0, "Width of the plot in inches")
+	dbPlotCmd.Flags().Float64("height", 4.0, "Height of the plot in inches")
+	addWhereFlag(dbPlotCmd)
+	dbPlotCmd.Flags().Lookup("where").Usage = "Select the simulations with a filter expression on the attributes (see gopf db query). Combined with simid and tags."
+}
+
+// plotSimIDs returns the simulations matching all of the passed IDs, the tags and the
+// --where flag. Selectors that are not given are ignored. If nothing is given, the newest
+// simulation is returned.
+func plotSimIDs(cmd *cobra.Command, fdb *pf.FieldDB, simIDs []int, tags []string) []int {
+	selections := [][]int{}
+	if len(simIDs) > 0 {
+		selections = append(selections, simIDs)
+	}
+	if whereIDs, ok := whereSimIDs(cmd, fdb.DB); ok {
+		selections = append(selections, whereIDs)
+	}
+	if len(tags) > 0 {
+		tagIDs, err := fdb.FilterByTags(tags...)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		selections = append(selections, tagIDs)
+	}
+	if len(selections) == 0 {
+		return []int{newestSimulationID(fdb.DB)}
+	}
+	return intersectSimIDs(selections)
+}
+
+// intersectSimIDs returns the IDs that are present in all selections, in the order of
+// the first selection
+func intersectSimIDs(selections [][]int) []int {
+	ids := selections[0]
+	for _, selection := range selections[1:] {
+		present := make(map[int]bool)
+		for _, id := range selection {
+			present[id] = true
+		}
+		both := []int{}
+		for _, id := range ids {
+			if present[id] {
+				both = append(both, id)
+			}
+		}
+		ids = both
+	}
+	return ids
+}
+
+// timeseriesPoints converts the timeseries to points. Points without time (if time is
+// used as x-axis) and non-positive values on logarithmic axes are skipped. The number of
+// skipped points is returned as the second value.
+func timeseriesPoints(data pf.TimeSeriesData, useTime bool, logx bool, logy bool) (plotter.XYs, int) {
+	pts := plotter.XYs{}
+	skipped := 0
+	for i, v := range data.Values {
+		x := float64(data.Timesteps[i])
+		if useTime {
+			x = data.Times[i]
+		}
+		if math.IsNaN(x) || (logx && x <= 0.0) || (logy && v <= 0.0) {
+			skipped++
+			continue
+		}
+		pts = append(pts, plotter.XY{X: x, Y: v})
+	}
+	return pts, skipped
+}
+
+// plotLabel returns the legend entry of a line. The key is included if several keys
+// are plotted. The attributes listed in legend are included if given, otherwise the
+// simulation ID is included if several simulations are plotted.
+func plotLabel(key string, simID int, attr map[string]interface{}, legend []string, multiKey bool, multiSim bool) string {
+	parts := []string{}
+	if multiKey {
+		parts = append(parts, key)
+	}
+	if len(legend) > 0 {
+		for _, name := range legend {
+			parts = append(parts, fmt.Sprintf("%s=%s", name, formatAttribute(attr[name])))
+		}
+	} else if multiSim {
+		parts = append(parts, fmt.Sprintf("sim %d", simID))
+	}
+	if len(parts) == 0 {
+		return key
+	}
+	return strings.Join(parts, ", ")
+}
+
+// capitalize returns s with the first rune converted to upper case
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
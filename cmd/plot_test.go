@@ -0,0 +1,131 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/davidkleiven/gopf/pf"
+	"gonum.org/v1/plot/plotter"
+)
+
+func TestTimeseriesPoints(t *testing.T) {
+	data := pf.TimeSeriesData{
+		Key:       "energy",
+		Timesteps: []int{0, 1, 2, 3},
+		Times:     []float64{math.NaN(), 0.5, 1.0, 2.0},
+		Values:    []float64{-1.0, 0.0, 2.0, 4.0},
+	}
+
+	for i, test := range []struct {
+		useTime    bool
+		logx       bool
+		logy       bool
+		want       plotter.XYs
+		numSkipped int
+	}{
+		{
+			want:       plotter.XYs{{X: 0, Y: -1}, {X: 1, Y: 0}, {X: 2, Y: 2}, {X: 3, Y: 4}},
+			numSkipped: 0,
+		},
+		{
+			logx:       true,
+			want:       plotter.XYs{{X: 1, Y: 0}, {X: 2, Y: 2}, {X: 3, Y: 4}},
+			numSkipped: 1,
+		},
+		{
+			logy:       true,
+			want:       plotter.XYs{{X: 2, Y: 2}, {X: 3, Y: 4}},
+			numSkipped: 2,
+		},
+		{
+			useTime:    true,
+			want:       plotter.XYs{{X: 0.5, Y: 0}, {X: 1, Y: 2}, {X: 2, Y: 4}},
+			numSkipped: 1,
+		},
+		{
+			useTime:    true,
+			logx:       true,
+			logy:       true,
+			want:       plotter.XYs{{X: 1, Y: 2}, {X: 2, Y: 4}},
+			numSkipped: 2,
+		},
+	} {
+		pts, skipped := timeseriesPoints(data, test.useTime, test.logx, test.logy)
+		if !reflect.DeepEqual(pts, test.want) || skipped != test.numSkipped {
+			t.Errorf("Test #%d: Expected %v (%d skipped) got %v (%d skipped)\n", i, test.want, test.numSkipped, pts, skipped)
+		}
+	}
+}
+
+func TestPlotLabel(t *testing.T) {
+	attr := map[string]interface{}{"temperature": 300.0, "study": "A"}
+	for i, test := range []struct {
+		legend   []string
+		multiKey bool
+		multiSim bool
+		want     string
+	}{
+		{want: "energy"},
+		{multiKey: true, want: "energy"},
+		{multiSim: true, want: "sim 3"},
+		{multiKey: true, multiSim: true, want: "energy, sim 3"},
+		{legend: []string{"temperature"}, multiSim: true, want: "temperature=300"},
+		{legend: []string{"study", "temperature"}, multiKey: true, want: "energy, study=A, temperature=300"},
+		{legend: []string{"pressure"}, want: "pressure="},
+	} {
+		label := plotLabel("energy", 3, attr, test.legend, test.multiKey, test.multiSim)
+		if label != test.want {
+			t.Errorf("Test #%d: Expected '%s' got '%s'\n", i, test.want, label)
+		}
+	}
+}
+
+func TestIntersectSimIDs(t *testing.T) {
+	for i, test := range []struct {
+		selections [][]int
+		want       []int
+	}{
+		{selections: [][]int{{4, 1, 5}}, want: []int{4, 1, 5}},
+		{selections: [][]int{{4, 1, 5}, {1, 2, 4}}, want: []int{4, 1}},
+		{selections: [][]int{{4, 1, 5}, {1, 2, 4}, {2, 4}}, want: []int{4}},
+		{selections: [][]int{{4, 1, 5}, {}}, want: []int{}},
+	} {
+		ids := intersectSimIDs(test.selections)
+		if !reflect.DeepEqual(ids, test.want) {
+			t.Errorf("Test #%d: Expected %v got %v\n", i, test.want, ids)
+		}
+	}
+}
+
+func TestCapitalize(t *testing.T) {
+	for i, test := range []struct {
+		s    string
+		want string
+	}{
+		{s: "timestep", want: "Timestep"},
+		{s: "time", want: "Time"},
+		{s: "Time", want: "Time"},
+		{s: "ålesund", want: "Ålesund"},
+		{s: "", want: ""},
+	} {
+		if got := capitalize(test.s); got != test.want {
+			t.Errorf("Test #%d: Expected %s got %s\n", i, test.want, got)
+		}
+	}
+}
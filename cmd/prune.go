@@ -0,0 +1,150 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/davidkleiven/gopf/pf"
+	"github.com/spf13/cobra"
+)
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete field snapshots to reduce the size of the database",
+	Long: `Prune deletes field snapshots, such that only every n-th snapshot and/or the last k
+snapshots of each simulation are kept. Timeseries, attributes and comments are not
+modified. All snapshots are deleted in one transaction and afterwards the database file
+is compacted with VACUUM.
+
+Examples:
+
+gopf db prune mydatabase.db --every 10 --dry-run
+
+prints the number of rows that would be deleted if only every 10th snapshot of all
+simulations is kept.
+
+gopf db prune mydatabase.db --simid 3 --keep-last 5
+
+keeps only the last 5 snapshots of simulation 3.
+
+gopf db prune mydatabase.db --where "temperature>300" --every 2 --keep-last 1
+
+keeps every second snapshot and the last snapshot of the simulations with a temperature
+above 300 (see gopf db query). If neither simid nor where is given, all simulations are
+pruned. The deletion must be confirmed, unless --yes is given.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Printf("A database name must be given.")
+			return
+		}
+
+		if _, err := os.Stat(args[0]); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		sqlDB, err := sql.Open("sqlite3", args[0])
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		defer sqlDB.Close()
+
+		simid, err := cmd.Flags().GetInt("simid")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		every, err := cmd.Flags().GetInt("every")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		keepLast, err := cmd.Flags().GetInt("keep-last")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if every <= 0 && keepLast <= 0 {
+			log.Fatalf("Either every or keep-last must be positive\n")
+			return
+		}
+
+		db := pf.FieldDB{DB: sqlDB}
+		simIDs, ok := whereSimIDs(cmd, sqlDB)
+		if !ok {
+			if simid >= 0 {
+				simIDs = []int{simid}
+			} else if simIDs, err = db.Select(""); err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+		}
+
+		counts, err := db.PruneSnapshots(simIDs, every, keepLast, true)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		fmt.Printf("Pruning %d simulations\n", len(simIDs))
+		printRowCounts(counts)
+		if dryRun {
+			return
+		}
+
+		if !yes && !confirm(os.Stdin, "Delete the snapshots?") {
+			fmt.Printf("Aborted\n")
+			return
+		}
+
+		if _, err := db.PruneSnapshots(simIDs, every, keepLast, false); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		if err := db.Vacuum(); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		fmt.Printf("Pruned %d simulations\n", len(simIDs))
+	},
+}
+
+func init() {
+	dbCmd.AddCommand(pruneCmd)
+	pruneCmd.Flags().IntP("simid", "i", -1, "Simulation ID. If negative, all simulations are pruned.")
+	pruneCmd.Flags().IntP("every", "e", 0, "Keep every n-th snapshot (counted from the first)")
+	pruneCmd.Flags().IntP("keep-last", "k", 0, "Keep the last k snapshots")
+	pruneCmd.Flags().Bool("dry-run", false, "Print the number of rows that would be deleted without modifying the database")
+	pruneCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+	addWhereFlag(pruneCmd)
+}
@@ -0,0 +1,221 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/davidkleiven/gopf/pf"
+	"github.com/spf13/cobra"
+)
+
+// queryCmd represents the query command
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Find simulations by their attributes",
+	Long: `Query lists the simulations whose attributes (see gopf db attr) match a filter
+expression. Comparisons (==, !=, <, <=, >, >=) between an attribute and a number or a
+quoted string are combined with and, or, not and parentheses. The simulation ID can be
+referred to as simID. Comparisons involving attributes that are not set are false.
+
+Examples:
+
+gopf db query mydatabase.db --where "temperature>300 and model=='kks'"
+
+lists the IDs of all simulations with a temperature above 300 using the kks model,
+together with the value of the attributes used in the expression.
+
+gopf db query mydatabase.db --where "temperature>300" --columns temperature,dt --format csv
+
+prints the simulation ID, temperature and dt of all matching simulations as CSV.
+Supported formats are table, csv and json. If no expression is given, all simulations
+are listed along with all attributes.
+
+The same filter expression can be passed to other commands with the --where flag. For
+example,
+
+gopf db export mydatabase.db --where "model=='kks'" --type ts
+
+exports the timeseries of all simulations using the kks model.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Printf("A database name must be given.")
+			return
+		}
+
+		if _, err := os.Stat(args[0]); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		sqlDB, err := sql.Open("sqlite3", args[0])
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		defer sqlDB.Close()
+
+		where, err := cmd.Flags().GetString("where")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		columns, err := cmd.Flags().GetStringSlice("columns")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		fdb := pf.FieldDB{DB: sqlDB}
+		simIDs, err := fdb.Select(where)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		attributes, err := fdb.SimAttributes()
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if len(columns) == 0 {
+			columns = defaultQueryColumns(where, simIDs, attributes)
+		}
+
+		switch format {
+		case "table":
+			printQueryTable(simIDs, columns, attributes)
+		case "csv":
+			writeQueryCsv(simIDs, columns, attributes)
+		case "json":
+			writeQueryJSON(simIDs, columns, attributes)
+		default:
+			log.Fatalf("Unknown format %s. Must be one of table, csv or json.\n", format)
+		}
+	},
+}
+
+func init() {
+	dbCmd.AddCommand(queryCmd)
+	queryCmd.Flags().StringP("where", "w", "", "Filter expression. If empty, all simulations are listed.")
+	queryCmd.Flags().StringSliceP("columns", "c", []string{}, "Comma separated list of attributes to show. Default is the attributes used in the filter expression.")
+	queryCmd.Flags().StringP("format", "f", "table", "Output format. Can be one of table, csv or json.")
+}
+
+// defaultQueryColumns returns the attributes used in the expression. If the expression
+// is empty, all attributes of the matching simulations are returned
+func defaultQueryColumns(where string, simIDs []int, attributes map[int]map[string]interface{}) []string {
+	if where != "" {
+		q, err := pf.ParseQuery(where)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		columns := []string{}
+		for _, name := range q.Attributes() {
+			if name != "simID" {
+				columns = append(columns, name)
+			}
+		}
+		return columns
+	}
+
+	names := make(map[string]bool)
+	for _, id := range simIDs {
+		for name := range attributes[id] {
+			if name != "simID" {
+				names[name] = true
+			}
+		}
+	}
+	columns := set2slice(names)
+	sort.Strings(columns)
+	return columns
+}
+
+// formatAttribute returns a string representation of the attribute. Missing attributes
+// are represented by an empty string
+func formatAttribute(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return v
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func printQueryTable(simIDs []int, columns []string, attributes map[int]map[string]interface{}) {
+	line := fmt.Sprintf("| %-*s |", SimIDWidth, "Sim. ID")
+	for _, c := range columns {
+		line += fmt.Sprintf(" %-*s |", ColWidth, c)
+	}
+	fmt.Printf("%s\n", singleLine(len(line)))
+	fmt.Printf("%s\n", line)
+	fmt.Printf("%s\n", singleLine(len(line)))
+	for _, id := range simIDs {
+		row := fmt.Sprintf("| %-*d |", SimIDWidth, id)
+		for _, c := range columns {
+			row += fmt.Sprintf(" %*s |", ColWidth, formatAttribute(attributes[id][c]))
+		}
+		fmt.Printf("%s\n", row)
+	}
+	fmt.Printf("%s\n", singleLine(len(line)))
+	fmt.Printf("%d simulations matched\n", len(simIDs))
+}
+
+func writeQueryCsv(simIDs []int, columns []string, attributes map[int]map[string]interface{}) {
+	writer := csv.NewWriter(os.Stdout)
+	defer writer.Flush()
+	writer.Write(append([]string{"simID"}, columns...))
+	record := make([]string, len(columns)+1)
+	for _, id := range simIDs {
+		record[0] = fmt.Sprintf("%d", id)
+		for j, c := range columns {
+			record[j+1] = formatAttribute(attributes[id][c])
+		}
+		writer.Write(record)
+	}
+}
+
+func writeQueryJSON(simIDs []int, columns []string, attributes map[int]map[string]interface{}) {
+	items := make([]map[string]interface{}, len(simIDs))
+	for i, id := range simIDs {
+		items[i] = map[string]interface{}{"simID": id}
+		for _, c := range columns {
+			items[i][c] = attributes[id][c]
+		}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(items); err != nil {
+		log.Fatalf("%s\n", err)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/davidkleiven/gopf/pf"
+	"github.com/spf13/cobra"
+)
+
+// tagCmd represents the tag command
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Add, remove and filter simulations by tags",
+	Long: `Tags are used to group simulations, for example all runs that belong to one
+parameter study. Several tags are separated by commas.
+
+Examples:
+
+gopf db tag mydatabase.db --simid 3 --add study-A,coarse
+
+adds the tags study-A and coarse to the simulation with ID 3.
+
+gopf db tag mydatabase.db --simid 3 --remove coarse
+
+removes the tag coarse from the simulation with ID 3.
+
+gopf db tag mydatabase.db --simid 3
+
+lists the tags of the simulation with ID 3. If simid is not given (or is a
+negative number), the newest simulation is used.
+
+gopf db tag mydatabase.db --filter study-A,coarse
+
+lists the IDs of all simulations that have both the tags study-A and coarse.
+
+Databases created by older versions of gopf must be upgraded with
+gopf db migrate before tags can be used.
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 1 {
+			fmt.Printf("A database name must be given.")
+			return
+		}
+
+		if _, err := os.Stat(args[0]); err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		sqlDB, err := sql.Open("sqlite3", args[0])
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		defer sqlDB.Close()
+		db := pf.FieldDB{DB: sqlDB}
+
+		simID, err := cmd.Flags().GetInt("simid")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		add, err := cmd.Flags().GetStringSlice("add")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		remove, err := cmd.Flags().GetStringSlice("remove")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		filter, err := cmd.Flags().GetStringSlice("filter")
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+
+		if len(filter) > 0 {
+			simIDs, err := db.FilterByTags(filter...)
+			if err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+			fmt.Printf("%d simulations have the tags %v\n", len(simIDs), filter)
+			for _, id := range simIDs {
+				fmt.Printf("%d\n", id)
+			}
+			return
+		}
+
+		if simID < 0 {
+			simID = newestSimulationID(sqlDB)
+		}
+
+		if len(add) > 0 {
+			if err := db.AddTags(simID, add...); err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+		}
+		if len(remove) > 0 {
+			if err := db.RemoveTags(simID, remove...); err != nil {
+				log.Fatalf("%s\n", err)
+				return
+			}
+		}
+
+		tags, err := db.Tags(simID)
+		if err != nil {
+			log.Fatalf("%s\n", err)
+			return
+		}
+		fmt.Printf("Sim id %d: %v\n", simID, tags)
+	},
+}
+
+func init() {
+	dbCmd.AddCommand(tagCmd)
+	tagCmd.Flags().IntP("simid", "i", -1, "Simulation ID. If negative, the newest simulation is used.")
+	tagCmd.Flags().StringSliceP("add", "a", []string{}, "Comma separated list of tags to add")
+	tagCmd.Flags().StringSliceP("remove", "r", []string{}, "Comma separated list of tags to remove")
+	tagCmd.Flags().StringSliceP("filter", "f", []string{}, "List all simulations having all of the comma separated tags")
+}
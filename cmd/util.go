@@ -1,8 +1,16 @@
 package cmd
 
 import (
+	"bufio"
 	"database/sql"
 	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/davidkleiven/gopf/pf"
+	"github.com/spf13/cobra"
 )
 
 // SimIDWidth is the width of the simulation ID field
@@ -28,7 +36,7 @@ func singleLine(length int) string {
 
 // newestSimulationID returns the ID of the newest simulation
 func newestSimulationID(db *sql.DB) int {
-	rows, err := db.Query("SELECT simId FROM simIds ORDER BY creationTime DESC LIMIT 1")
+	rows, err := db.Query("SELECT simId FROM simIds ORDER BY creationTime DESC, simId DESC LIMIT 1")
 	if err != nil {
 		fmt.Printf("%s\n", err)
 		return 0
@@ -41,6 +49,68 @@ func newestSimulationID(db *sql.DB) int {
 	return simID
 }
 
+// hasTable returns true if the database has a table with the passed name
+func hasTable(db *sql.DB, name string) bool {
+	var count int
+	row := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?", name)
+	if err := row.Scan(&count); err != nil {
+		log.Fatalf("%s\n", err)
+	}
+	return count > 0
+}
+
+// addWhereFlag adds the --where flag used to select simulations by their attributes
+func addWhereFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("where", "w", "", "Select the simulations with a filter expression on the attributes (see gopf db query). Takes precedence over simid.")
+}
+
+// whereSimIDs returns the simulations matching the expression passed with the --where
+// flag. The second return value is false if the flag is not given.
+func whereSimIDs(cmd *cobra.Command, db *sql.DB) ([]int, bool) {
+	where, err := cmd.Flags().GetString("where")
+	if err != nil {
+		log.Fatalf("%s\n", err)
+	}
+	if where == "" {
+		return nil, false
+	}
+	fdb := pf.FieldDB{DB: db}
+	simIDs, err := fdb.Select(where)
+	if err != nil {
+		log.Fatalf("%s\n", err)
+	}
+	return simIDs, true
+}
+
+// confirm asks the user to confirm an action. True is returned if the answer is y or yes
+func confirm(in io.Reader, question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// printRowCounts prints the number of affected rows in each table
+func printRowCounts(counts map[string]int64) {
+	tables := []string{}
+	for table := range counts {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	var total int64
+	for _, table := range tables {
+		if counts[table] > 0 {
+			fmt.Printf("%-*s %d rows\n", 2*ColWidth, table, counts[table])
+		}
+		total += counts[table]
+	}
+	fmt.Printf("%-*s %d rows\n", 2*ColWidth, "Total", total)
+}
+
 // closestWhiteSpace returns the position of the first white space
 // to the left of target
 func closestWhiteSpace(line string, target int) int {
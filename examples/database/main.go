@@ -3,14 +3,12 @@
 package main
 
 import (
-	"database/sql"
+	"log"
 	"math"
 	"math/rand"
 
 	"github.com/davidkleiven/gopf/pf"
 	"github.com/davidkleiven/gopf/pfutil"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // CircleMonitor is a type that observes a field inside
@@ -52,36 +50,39 @@ func (cm *CircleMonitor) Eval(field pf.Brick, domainSize []int) CircleMonitorRes
 // DBWriter is a type for writing records to the database
 type DBWriter struct {
 	Monitors []CircleMonitor
-	DB       pf.FieldDB
+	DB       *pf.FieldDB
 	numCalls int
 }
 
-// Eval is the pf.SolverCB type and can be attached as a callback
+// Eval is the pf.SolverErrCB type and can be attached as a callback
 // to the Solver
-func (db *DBWriter) Eval(s *pf.Solver, timestep int) {
+func (db *DBWriter) Eval(s *pf.Solver, timestep int) error {
 	data := make(map[string]float64)
 	for _, monitor := range db.Monitors {
 		res := monitor.Eval(s.Model.Bricks["conc"], db.DB.DomainSize)
 		data[monitor.Name+"_mean"] = res.Mean
 		data[monitor.Name+"_std"] = res.Std
 	}
-	db.DB.TimeSeries(data, timestep)
+	if err := db.DB.TimeSeries(data, timestep); err != nil {
+		return err
+	}
 
 	// We don't write field data as often as we write timeseries data
 	// due to size
 	if timestep%10 == 0 {
-		db.DB.SaveFields(s, timestep)
+		return db.DB.SaveFields(s, timestep)
 	}
+	return nil
 }
 
 func main() {
 	dbName := "./diffusion.db"
 
-	sqlDB, _ := sql.Open("sqlite3", dbName)
-	fieldDB := pf.FieldDB{
-		DB:         sqlDB,
-		DomainSize: []int{128, 128},
+	fieldDB, err := pf.OpenFieldDB(dbName, []int{128, 128}, pf.FieldDBOptions{})
+	if err != nil {
+		log.Fatal(err)
 	}
+	defer fieldDB.Close()
 
 	comment := "This is a very long comment. We use a very long comment here "
 	comment += "in order to check that the command line interface manages to split the lines correctly "
@@ -89,18 +90,24 @@ func main() {
 	comment += "width, the reminding part of the comment should be written on the next line. Furthermore, "
 	comment += "the simulation ID should only be displayed once per comment."
 
-	fieldDB.Comment(comment)
+	if err := fieldDB.Comment(comment); err != nil {
+		log.Fatal(err)
+	}
 
 	// Add some attributes
 	attr := make(map[string]float64)
 	attr["start"] = 0.1
 	attr["meanConc"] = 0.5
-	fieldDB.SetAttr(attr)
+	if err := fieldDB.SetAttr(attr); err != nil {
+		log.Fatal(err)
+	}
 
 	// Add some text attributes
 	attrTxt := make(map[string]string)
 	attrTxt["txt"] = "textattr"
-	fieldDB.SetTextAttr(attrTxt)
+	if err := fieldDB.SetTextAttr(attrTxt); err != nil {
+		log.Fatal(err)
+	}
 
 	// Create a model and add some fields
 	conc := pf.NewField("conc", 128*128, nil)
@@ -139,8 +146,10 @@ func main() {
 	}
 
 	// Add the evaluate function
-	solver.AddCallback(writer.Eval)
+	solver.AddErrCallback(writer.Eval)
 
 	// Solve the system
-	solver.Solve(20, 10)
+	if err := solver.Solve(20, 10); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -3,15 +3,14 @@
 package main
 
 import (
-	"database/sql"
 	"encoding/csv"
 	"fmt"
+	"log"
 	"math"
 	"os"
 
 	"github.com/davidkleiven/gopf/pf"
 	"github.com/davidkleiven/gopf/pfutil"
-	_ "github.com/mattn/go-sqlite3"
 	"gonum.org/v1/gonum/mat"
 )
 
@@ -142,14 +141,16 @@ func main() {
 
 	solver := pf.NewSolver(&model, domainSize, dt)
 
-	sqlDB, _ := sql.Open("sqlite3", dbName)
-	db := pf.FieldDB{
-		DB:         sqlDB,
-		DomainSize: domainSize,
+	db, err := pf.OpenFieldDB(dbName, domainSize, pf.FieldDBOptions{})
+	if err != nil {
+		log.Fatal(err)
 	}
-	solver.AddCallback(db.SaveFields)
+	defer db.Close()
+	solver.AddErrCallback(db.SaveFields)
 	model.Summarize()
-	solver.Solve(10, 10)
+	if err := solver.Solve(10, 10); err != nil {
+		log.Fatal(err)
+	}
 
 	// Extract the current
 	current := charge.Current(density, len(density.Data), true)
@@ -33,7 +33,7 @@ import (
 type ChargeTransport struct {
 	// Conductivity return the conductivity tensor at node i. If 3D
 	// the order should be s_xx, s_yy, s_zz, s_xz, s_yz, s_xy and if 2D
-	// it should be s_xx, s_yy, s_xy. The vacuum permittivity should be embeded
+	// it should be s_xx, s_yy, s_xy. In 1D it is simply s_xx. The vacuum permittivity should be embeded
 	// in the conductivity. Thus, if the "normal" conductiviy in Ohm's law is
 	// labeled sigma, the current density is given by j = sigma*E, this function
 	// should return sigma/eps_0, where eps_0 is the vacumm permittivity
@@ -164,7 +164,9 @@ func voigtIndex2D() [][]int {
 }
 
 func voigtIndex(i, j, dim int) int {
-	if dim == 2 {
+	if dim == 1 {
+		return 0
+	} else if dim == 2 {
 		return voigtIndex2D()[i][j]
 	}
 	return voigtIndex3D()[i][j]
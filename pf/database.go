@@ -3,12 +3,15 @@ package pf
 import (
 	"database/sql"
 	"fmt"
-	"log"
-	"math/rand"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/davidkleiven/gopf/pfutil"
+
+	// The sqlite3 driver is needed by OpenFieldDB
+	_ "github.com/mattn/go-sqlite3"
 )
 
 // FieldDB is a type used for storing field data in a SQl database. A field database
@@ -18,16 +21,16 @@ import (
 // 		Describes the positions in 3D space of all nodes. The first column is the node
 // 		number in the simulation cell and the remaining columns represent the x, y and
 // 		z index, respectively. If the simulation domain is 2D, the z column is always
-//		zero.
+//		zero. The table is only filled in databases created before the domain size
+//		was stored per simulation (see domainSizes), and is used to read them.
 //
 // 2. fields (id int, name text, value real, positionId int, timestep int, simID int)
 //		Describes the value of the fields at a given timestep and point
 // 		- id: Row identifier that is auto-incremented when new rows are added
 //		- name: Name of the field
 //		- value: Value of the field
-//		- positionId: ID pointing to the positions table. The position in 3D space
-// 			of the node represented by this row, is given by the corresponding row
-// 			in the positions table
+//		- positionId: Node number in the simulation domain. The position in 3D space
+// 			of the node is given by the domain size of the simulation (see pfutil.Pos)
 //		- timestep: Timestep of the record
 //		- simID: Unique ID identifying all entries in the database written by the
 //			current simulation
@@ -48,13 +51,16 @@ import (
 //
 // 5. simIDs (simID int, creationTime text)
 //		List of all simulation IDs in the database
-//		- simID: Unique ID
+//		- simID: Unique ID allocated by the database (autoincrement)
 //		- creationTime: Timestamp for when the simulation ID was created
 //
 // 6. simTextAttributes (key TEXT, value TEXT, simID int)
 //		Same as simAttributes, apart from the value field is a string.
 //
-// 7. timeseries (key TEXT, value float, timestep int, simID int)
+//		The boundary condition along each axis is stored in this table under the
+//		keys boundaryX, boundaryY and boundaryZ when the fields are saved
+//
+// 7. timeseries (key TEXT, value float, timestep int, simID int, time float)
 //		Describes time varying data typically derived from the fields in the
 //		calculations. Some examples: peak concentration in a diffusion calculation,
 //		peak stress in an elasiticy calculation, average domain size in a spinoidal
@@ -64,336 +70,546 @@ import (
 //		- timestep: Timestep
 //		- simID: Unique ID which is common to all entries written by the current
 //			simulation
+//		- time: Physical time. NULL if the data was inserted without a time
+//
+// 8. fieldBlobs (name TEXT, timestep int, simID int, dtype TEXT, shape TEXT, compression TEXT, data BLOB)
+//		Describes the value of a field at all nodes at a given timestep. This table
+//		replaces the fields table when the blob layout is used (default)
+//		- name: Name of the field
+//		- timestep: Timestep of the record
+//		- simID: Unique ID which is common to all entries written by the current
+//			simulation
+//		- dtype: Data type of the values (float64)
+//		- shape: Comma separated domain size (e.g. 128,128)
+//		- compression: Compression of the data (none or zlib)
+//		- data: The values of all nodes (see FieldBlob)
+//
+// 9. schemaVersion (version int, description TEXT, appliedAt TEXT)
+//		List of all migrations that have been applied to the database (see Migrate)
+//
+// 10. tags (simID int, tag TEXT)
+//		Tags used to group simulations, for example all runs belonging to one study
+//		(see AddTags and FilterByTags)
+//
+// 11. provenance (simID int, key TEXT, value TEXT)
+//		Information about how the simulation was produced (time step, stepper, domain
+//		size, field names, Go and gopf versions, hostname and command line). The
+//		provenance is stored automatically by SaveFields (see also Provenance)
+//
+// 12. provenanceEquations (simID int, eqNo int, equation TEXT)
+//		The equations of the model
+//
+// 13. provenanceComponents (simID int, name TEXT, kind TEXT, type TEXT)
+//		Registered terms, sources and the time stepper
+//
+// 14. provenanceParameters (simID int, component TEXT, parameter TEXT, value TEXT)
+//		Exported parameters of the components
+//
+// 15. randomSeeds (simID int, name TEXT, seed int)
+//		Seeds of random number generators (see RecordSeed)
+//
+// 16. domainSizes (simID int, shape TEXT, spacing TEXT)
+//		Domain size (comma separated) and optional grid spacing of each simulation,
+//		such that simulations with different resolution can be stored in the same
+//		database (see LoadDomainSize)
+//
+// Both layouts are supported when the fields are loaded, such that databases created
+// before the blob layout was introduced can still be read.
+//
+// A FieldDB should be created with OpenFieldDB. All methods return an error instead of
+// terminating the process, such that the database can be used in long-running services.
 type FieldDB struct {
 	DB *sql.DB
 
-	// DomainSize of the simulation domain. This is needed in order to populate the
-	// position table
+	// DomainSize of the simulation domain. It is stored in the domainSizes table, such
+	// that simulations with different domain sizes can be stored in the same database
 	DomainSize []int
 
-	// simId is a random integer that is used to identify all items inserted in the
-	// current run
-	simID int32
+	// GridSpacing is the optional spacing between the nodes along each axis. It is
+	// stored together with the domain size if given
+	GridSpacing []float64
+
+	// Layout determines how the fields are stored. Default is FieldBlobLayout
+	Layout FieldLayout
+
+	// Compression used when the fields are stored as blobs. Default is zlib
+	Compression string
+
+	// simID is used to identify all items inserted in the current run. It is allocated
+	// by the database, such that it is unique even if several simulations write to the
+	// same database simultaneously
+	simID int
 
 	// true if the database has been initialized
 	initialized bool
-}
 
-// initialize runs a set of sql statements to build the database. Note that subsequent calls
-// to this function has no effect
-func (fdb *FieldDB) initialize() {
-	statement, err := fdb.DB.Prepare("CREATE TABLE IF NOT EXISTS simIDs (simID INTEGER UNIQUE, creationTime TEXT)")
-	if err != nil {
-		panic(err)
-	}
-	statement.Exec()
+	// true if the boundary conditions have been stored
+	boundariesStored bool
 
-	statement, err = fdb.DB.Prepare("CREATE TABLE IF NOT EXISTS positions (id INTEGER PRIMARY KEY, X INTEGER, Y INTEGER, Z INTEGER)")
-	if err != nil {
-		panic(err)
-	}
-	statement.Exec()
+	// true if the provenance of the current simulation has been stored
+	provenanceStored bool
 
-	statement, err = fdb.DB.Prepare(
-		"CREATE TABLE IF NOT EXISTS fields (id INTEGER PRIMARY KEY, name TEXT, " +
-			"value REAL, positionId INTEGER, timestep INTEGER, simID INTEGER, " +
-			"FOREIGN KEY(positionId) REFERENCES positions(id), " +
-			"FOREIGN KEY(simID) REFERENCES simIDs(simID))")
+	// true if the domain size of the current simulation has been stored
+	domainSizeStored bool
+}
 
-	if err != nil {
-		panic(err)
-	}
-	statement.Exec()
+// FieldLayout determines how fields are stored in the database
+type FieldLayout int
 
-	statement, err = fdb.DB.Prepare(
-		"CREATE TABLE IF NOT EXISTS simAttributes (key TEXT, value REAL, simID INTEGER, FOREIGN KEY(simID) REFERENCES fields(simID))")
-	if err != nil {
-		panic(err)
-	}
-	statement.Exec()
+const (
+	// FieldBlobLayout stores one row per field and timestep in the fieldBlobs table
+	FieldBlobLayout FieldLayout = iota
+
+	// FieldRowLayout stores one row per node, field and timestep in the fields table
+	FieldRowLayout
+)
 
-	statement, err = fdb.DB.Prepare(
-		"CREATE TABLE IF NOT EXISTS comments (simID INTEGER, value TEXT, " +
-			"FOREIGN KEY(simID) REFERENCES simIds(simID))")
+// BoundaryConditionProvider is an interface that is implemented by fourier
+// transforms that can report the boundary condition along each axis
+type BoundaryConditionProvider interface {
+	BoundaryConditions() []pfutil.BoundaryCondition
+}
 
-	if err != nil {
-		panic(err)
-	}
-	statement.Exec()
+// FieldDBOptions holds optional settings used when opening a field database
+type FieldDBOptions struct {
+	// Layout determines how the fields are stored. Default is FieldBlobLayout
+	Layout FieldLayout
 
-	statement, err = fdb.DB.Prepare(
-		"CREATE TABLE IF NOT EXISTS simTextAttributes (key TEXT, value TEXT, " +
-			"simID INTEGER, FOREIGN KEY(simID) REFERENCES simIds(simID))")
+	// Compression used when the fields are stored as blobs. Default is zlib
+	Compression string
+}
 
+// OpenFieldDB opens (or creates) the SQLite database at path, upgrades the schema to the
+// latest version and creates a new simulation ID. domainSize is the size of the
+// simulation domain. It can be nil if the database is only used for reading, in which
+// case SaveFields returns an error.
+//
+//	db, err := pf.OpenFieldDB("run.db", []int{128, 128}, pf.FieldDBOptions{})
+//	if err != nil {
+//		return err
+//	}
+//	defer db.Close()
+//	solver.AddErrCallback(db.SaveFields)
+func OpenFieldDB(path string, domainSize []int, opts FieldDBOptions) (*FieldDB, error) {
+	sqlDB, err := sql.Open("sqlite3", path)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	statement.Exec()
+	fdb := &FieldDB{
+		DB:          sqlDB,
+		DomainSize:  domainSize,
+		Layout:      opts.Layout,
+		Compression: opts.Compression,
+	}
+	if err := fdb.initialize(); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return fdb, nil
+}
 
-	statement, err = fdb.DB.Prepare(
-		"CREATE TABLE IF NOT EXISTS timeseries (key TEXT, value REAL, " +
-			"timestep INTEGER, simID INTEGER, FOREIGN KEY(simID) REFERENCES simIds(simID))")
+// Close closes the underlying database
+func (fdb *FieldDB) Close() error {
+	return fdb.DB.Close()
+}
 
-	if err != nil {
-		panic(err)
+// SimID returns the ID of the current simulation. The ID is assigned when the database
+// is initialized, thus zero is returned before anything has been written
+func (fdb *FieldDB) SimID() int {
+	return fdb.simID
+}
+
+// initialize builds the database by applying all pending migrations (see Migrate) and
+// creates a new simulation ID. Note that subsequent calls to this function has no effect
+func (fdb *FieldDB) initialize() error {
+	if fdb.initialized {
+		return nil
+	}
+	if _, err := Migrate(fdb.DB); err != nil {
+		return err
 	}
-	statement.Exec()
 
 	if fdb.simID == 0 {
-		source := rand.NewSource(time.Now().UnixNano())
-		generator := rand.New(source)
-		fdb.simID = generator.Int31()
-
-		// Insert into the database
-		statement, err = fdb.DB.Prepare("INSERT INTO simIDs (simID, creationTime) VALUES (?, datetime('now', 'localtime'))")
+		result, err := fdb.DB.Exec("INSERT INTO simIDs (creationTime) VALUES (datetime('now', 'localtime'))")
 		if err != nil {
-			panic(err)
+			return err
 		}
-		statement.Exec(fdb.simID)
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		fdb.simID = int(id)
 	}
 
 	fdb.initialized = true
+	return nil
 }
 
-// positionTableIsPopulated return true if the position table has been populated
-func (fdb *FieldDB) positionTableIsPopulated() bool {
-	result, err := fdb.DB.Query("SELECT COUNT(*) FROM positions")
-	if err != nil {
-		panic(err)
-	}
-	var numRows int
-	for result.Next() {
-		result.Scan(&numRows)
+// insertRealPart inserts the real part of a set of field values into the database
+func (fdb *FieldDB) insertRealPart(name string, timestep int, values []complex128) error {
+	if len(values) != pfutil.ProdInt(fdb.DomainSize) {
+		return fmt.Errorf("fielddb: the passed array does not match the specified domain size")
 	}
-	return numRows > 1
-}
-
-// populatePositionTables inserts values into the position table
-func (fdb *FieldDB) populatePositionsTable() {
-	pos3 := make([]int, 3)
-	numNodes := pfutil.ProdInt(fdb.DomainSize)
 	tx, err := fdb.DB.Begin()
-
 	if err != nil {
-		panic(err)
+		return err
 	}
-	for i := 0; i < numNodes; i++ {
-		pos := pfutil.Pos(fdb.DomainSize, i)
-		copy(pos3, pos)
-		statement, err := tx.Prepare("INSERT INTO positions (X, Y, Z) VALUES (?, ?, ?)")
+	statement, err := tx.Prepare("INSERT INTO fields (name, value, positionId, timestep, simID) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer statement.Close()
 
-		if err != nil {
+	for i := range values {
+		if _, err = statement.Exec(name, real(values[i]), i, timestep, fdb.simID); err != nil {
 			tx.Rollback()
-			return
+			return err
 		}
-		_, err = statement.Exec(pos3[0], pos3[1], pos3[2])
+	}
+	return tx.Commit()
+}
 
-		if err != nil {
-			tx.Rollback()
-			return
-		}
+// insertBlob inserts the real part of a set of field values as one blob
+func (fdb *FieldDB) insertBlob(name string, timestep int, values []complex128) error {
+	compression := fdb.Compression
+	if compression == "" {
+		compression = BlobZlib
+	}
+	blob, err := NewFieldBlob(values, fdb.DomainSize, compression)
+	if err != nil {
+		return err
 	}
-	tx.Commit()
+	_, err = fdb.DB.Exec("INSERT INTO fieldBlobs (name, timestep, simID, dtype, shape, compression, data) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?)", name, timestep, fdb.simID, blob.DType, formatShape(blob.Shape),
+		blob.Compression, blob.Data)
+	return err
 }
 
-// insertRealPart inserts the real part of a set of field values into the database
-func (fdb *FieldDB) insertRealPart(name string, timestep int, values []complex128) {
-	if !fdb.domainSizeOk() {
-		panic("FieldDB: Domain size does not match the positions table in the database")
+// SaveFields stores all the field to the database. This function satisfies the
+// SolverErrCB type, and can thus be attached as a callback to a solver with
+// AddErrCallback. Errors are then returned from Solve.
+func (fdb *FieldDB) SaveFields(s *Solver, epoch int) error {
+	if fdb.DomainSize == nil {
+		return fmt.Errorf("fielddb: the domain size must be set before fields are saved")
+	}
+	if err := fdb.initialize(); err != nil {
+		return err
 	}
 
-	if len(values) != pfutil.ProdInt(fdb.DomainSize) {
-		panic("FieldDB: The passed array does not match the specified domain size")
+	if !fdb.boundariesStored {
+		if err := fdb.storeBoundaryConditions(s.FT); err != nil {
+			return err
+		}
 	}
-	tx, _ := fdb.DB.Begin()
-	for i := range values {
-		statement, err := tx.Prepare("INSERT INTO fields (name, value, positionId, timestep, simID) VALUES (?, ?, ?, ?, ?)")
 
-		if err != nil {
-			log.Fatal(err)
-			tx.Rollback()
-			return
+	if !fdb.domainSizeStored {
+		if err := fdb.storeDomainSize(); err != nil {
+			return err
 		}
+		fdb.domainSizeStored = true
+	}
 
-		_, err = statement.Exec(name, real(values[i]), i, timestep, fdb.simID)
+	if !fdb.provenanceStored {
+		if err := fdb.StoreProvenance(NewProvenance(s, fdb.DomainSize)); err != nil {
+			return err
+		}
+		fdb.provenanceStored = true
+	}
 
+	for _, f := range s.Model.Fields {
+		var err error
+		if fdb.Layout == FieldRowLayout {
+			err = fdb.insertRealPart(f.Name, epoch, f.Data)
+		} else {
+			err = fdb.insertBlob(f.Name, epoch, f.Data)
+		}
 		if err != nil {
-			log.Fatal(err)
-			tx.Rollback()
-			return
+			return err
 		}
 	}
-	tx.Commit()
+	return nil
 }
 
-// SaveFields stores all the field to the database. This function satisfies the
-// SolverCB type, and can thus be attached as a callback to a solver
-func (fdb *FieldDB) SaveFields(s *Solver, epoch int) {
-	if !fdb.initialized {
-		fdb.initialize()
+// storeBoundaryConditions stores the boundary condition along each axis as text
+// attributes. Nothing is stored if the fourier transform does not implement the
+// BoundaryConditionProvider interface
+func (fdb *FieldDB) storeBoundaryConditions(ft FourierTransform) error {
+	if bcp, ok := ft.(BoundaryConditionProvider); ok {
+		axes := []string{"X", "Y", "Z"}
+		attr := make(map[string]string)
+		for i, bc := range bcp.BoundaryConditions() {
+			attr["boundary"+axes[i]] = bc.String()
+		}
+		if err := fdb.SetTextAttr(attr); err != nil {
+			return err
+		}
 	}
+	fdb.boundariesStored = true
+	return nil
+}
 
-	if !fdb.positionTableIsPopulated() {
-		fdb.populatePositionsTable()
+// Comment adds a comment associated with the current simulation ID
+func (fdb *FieldDB) Comment(comment string) error {
+	comment = strings.ReplaceAll(comment, "\n", " ")
+	if err := fdb.initialize(); err != nil {
+		return err
 	}
+	_, err := fdb.DB.Exec("INSERT INTO comments (simID, value) VALUES (?, ?)", fdb.simID, comment)
+	return err
+}
 
-	for _, f := range s.Model.Fields {
-		fdb.insertRealPart(f.Name, epoch, f.Data)
+// SetAttr adds a set of key-value pairs associated with the current simID
+func (fdb *FieldDB) SetAttr(attr map[string]float64) error {
+	values := make(map[string]interface{})
+	for k, v := range attr {
+		values[k] = v
 	}
+	return fdb.insertAttributes("simAttributes", values)
 }
 
-// Comment adds a comment associated with the current simulation ID
-func (fdb *FieldDB) Comment(comment string) {
-	comment = strings.ReplaceAll(comment, "\n", " ")
-	if !fdb.initialized {
-		fdb.initialize()
-	}
-	statement, err := fdb.DB.Prepare("INSERT INTO comments (simID, value) VALUES (?, ?)")
-	if err != nil {
-		log.Fatal(err)
-		return
+// SetTextAttr sets text attributes associated with the current simulation
+func (fdb *FieldDB) SetTextAttr(attr map[string]string) error {
+	values := make(map[string]interface{})
+	for k, v := range attr {
+		values[k] = v
 	}
-	statement.Exec(fdb.simID, comment)
+	return fdb.insertAttributes("simTextAttributes", values)
 }
 
-// SetAttr adds a set of key-value pairs associated with the current simID
-func (fdb *FieldDB) SetAttr(attr map[string]float64) {
-	if !fdb.initialized {
-		fdb.initialize()
+// insertAttributes inserts key-value pairs into the passed attribute table in one
+// transaction
+func (fdb *FieldDB) insertAttributes(table string, attr map[string]interface{}) error {
+	if err := fdb.initialize(); err != nil {
+		return err
 	}
 
 	tx, err := fdb.DB.Begin()
 	if err != nil {
-		log.Fatal(err)
-		return
+		return err
 	}
+	statement, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s (key, value, simID) VALUES (?, ?, ?)", table))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer statement.Close()
 
 	for k, v := range attr {
-		statement, err := tx.Prepare("INSERT INTO simAttributes (key, value, simID) VALUES (?, ?, ?)")
-		if err != nil {
-			log.Fatal(err)
+		if _, err = statement.Exec(k, v, fdb.simID); err != nil {
 			tx.Rollback()
-			return
-		}
-		_, err = statement.Exec(k, v, fdb.simID)
-		if err != nil {
-			log.Fatal(err)
-			tx.Rollback()
-			return
+			return err
 		}
 	}
-	tx.Commit()
+	return tx.Commit()
 }
 
-// SetTextAttr sets text attributes associated with the current simulation
-func (fdb *FieldDB) SetTextAttr(attr map[string]string) {
-	if !fdb.initialized {
-		fdb.initialize()
+// TimeSeries inserts data into the timeseries table
+func (fdb *FieldDB) TimeSeries(data map[string]float64, timestep int) error {
+	return fdb.insertTimeSeries(data, timestep, sql.NullFloat64{})
+}
+
+// WriteTimeSeries inserts data into the timeseries table together with the physical
+// time. It implements the TimeSeriesSink interface, such that the database can be
+// added as a sink to the solver
+func (fdb *FieldDB) WriteTimeSeries(data map[string]float64, timestep int, time float64) error {
+	return fdb.insertTimeSeries(data, timestep, sql.NullFloat64{Float64: time, Valid: true})
+}
+
+// insertTimeSeries inserts data into the timeseries table
+func (fdb *FieldDB) insertTimeSeries(data map[string]float64, timestep int, time sql.NullFloat64) error {
+	if err := fdb.initialize(); err != nil {
+		return err
 	}
 	tx, err := fdb.DB.Begin()
 	if err != nil {
-		log.Fatal(err)
-		return
+		return err
 	}
+	statement, err := tx.Prepare("INSERT INTO timeseries (key, value, timestep, simID, time) VALUES (?, ?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer statement.Close()
 
-	for k, v := range attr {
-		statement, err := tx.Prepare("INSERT INTO simTextAttributes (key, value, simID) VALUES (?, ?, ?)")
-		if err != nil {
-			log.Fatal(err)
+	for k, v := range data {
+		if _, err = statement.Exec(k, v, timestep, fdb.simID, time); err != nil {
 			tx.Rollback()
-			return
+			return err
 		}
+	}
+	return tx.Commit()
+}
 
-		_, err = statement.Exec(k, v, fdb.simID)
-		if err != nil {
-			log.Fatal(err)
-			tx.Rollback()
-			return
+// storeDomainSize stores the domain size and the grid spacing of the current simulation
+func (fdb *FieldDB) storeDomainSize() error {
+	var spacing sql.NullString
+	if fdb.GridSpacing != nil {
+		if len(fdb.GridSpacing) != len(fdb.DomainSize) {
+			return fmt.Errorf("fielddb: grid spacing %v does not match the domain size %v", fdb.GridSpacing, fdb.DomainSize)
+		}
+		items := make([]string, len(fdb.GridSpacing))
+		for i, dx := range fdb.GridSpacing {
+			items[i] = strconv.FormatFloat(dx, 'g', -1, 64)
 		}
+		spacing = sql.NullString{String: strings.Join(items, ","), Valid: true}
 	}
-	tx.Commit()
+	_, err := fdb.DB.Exec("INSERT OR REPLACE INTO domainSizes (simID, shape, spacing) VALUES (?, ?, ?)",
+		fdb.simID, formatShape(fdb.DomainSize), spacing)
+	return err
 }
 
-// TimeSeries inserts data into the timeseries table
-func (fdb *FieldDB) TimeSeries(data map[string]float64, timestep int) {
-	if !fdb.initialized {
-		fdb.initialize()
+// LoadDomainSize returns the domain size and the grid spacing of the simulation with the
+// passed ID. The grid spacing is nil if it was not stored. For databases created before
+// the domain size was stored per simulation, the domain size is taken from the positions
+// table. Nil is returned if the domain size is unknown.
+func (fdb *FieldDB) LoadDomainSize(simID int) ([]int, []float64, error) {
+	exists, err := fdb.hasTable("domainSizes")
+	if err != nil {
+		return nil, nil, err
+	}
+	if exists {
+		var shape string
+		var spacing sql.NullString
+		err := fdb.DB.QueryRow("SELECT shape, spacing FROM domainSizes WHERE simID=?", simID).Scan(&shape, &spacing)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, nil, err
+		}
+		if err == nil {
+			domainSize, err := parseShape(shape)
+			if err != nil || !spacing.Valid {
+				return domainSize, nil, err
+			}
+			dx := []float64{}
+			for _, item := range strings.Split(spacing.String, ",") {
+				v, err := strconv.ParseFloat(item, 64)
+				if err != nil {
+					return nil, nil, fmt.Errorf("fielddb: invalid grid spacing %s", spacing.String)
+				}
+				dx = append(dx, v)
+			}
+			return domainSize, dx, nil
+		}
 	}
+
 	tx, err := fdb.DB.Begin()
 	if err != nil {
-		log.Fatal(err)
-		return
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+	shape, err := positionsShape(tx, "main")
+	if err != nil || shape == "" {
+		return nil, nil, err
 	}
+	domainSize, err := parseShape(shape)
+	return domainSize, nil, err
+}
 
-	for k, v := range data {
-		statement, err := tx.Prepare("INSERT INTO timeseries (key, value, timestep, simID) VALUES (?, ?, ?, ?)")
-		if err != nil {
-			log.Fatal(err)
-			tx.Rollback()
-			return
-		}
-		_, err = statement.Exec(k, v, timestep, fdb.simID)
-		if err != nil {
-			log.Fatal(err)
-			tx.Rollback()
-			return
-		}
+// hasTable returns true if the database has a table with the passed name
+func (fdb *FieldDB) hasTable(name string) (bool, error) {
+	var count int
+	row := fdb.DB.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?", name)
+	if err := row.Scan(&count); err != nil {
+		return false, err
 	}
-	tx.Commit()
+	return count > 0, nil
 }
 
-// domainSizeOk returns true if the domain size stored locally matches the one
-// in the database. If domainSize is nil, this function always return true.
-func (fdb *FieldDB) domainSizeOk() bool {
-	if fdb.DomainSize == nil {
-		return true
+// Load loads all the fields from a database and return a list of Field
+// simID is the ID of the simulation that the field should be loaded from, and
+// timestep is the timestep from which the fields should be initialized.
+// The fields are sorted by name.
+func (fdb *FieldDB) Load(simID int, timestep int) ([]Field, error) {
+	fields, _, err := fdb.LoadWithDomainSize(simID, timestep)
+	return fields, err
+}
+
+// LoadWithDomainSize loads all fields from the database (see Load) together with the
+// size of the simulation domain. Fields stored both in the row layout and in the blob
+// layout are loaded.
+func (fdb *FieldDB) LoadWithDomainSize(simID int, timestep int) ([]Field, []int, error) {
+	fields, domainSize, err := fdb.loadRows(simID, timestep)
+	if err != nil {
+		return nil, nil, err
 	}
+	blobFields, blobDomainSize, err := fdb.loadBlobs(simID, timestep)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(blobFields) > 0 {
+		domainSize = blobDomainSize
+	}
+	fields = append(fields, blobFields...)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields, domainSize, nil
+}
 
-	columns := []string{"X", "Y", "Z"}
+// loadBlobs loads all fields stored in the blob layout
+func (fdb *FieldDB) loadBlobs(simID int, timestep int) ([]Field, []int, error) {
+	fields := []Field{}
+	exists, err := fdb.hasTable("fieldBlobs")
+	if err != nil || !exists {
+		return fields, nil, err
+	}
+	rows, err := fdb.DB.Query("SELECT name, dtype, shape, compression, data FROM fieldBlobs "+
+		"WHERE simID=? AND timestep=?", simID, timestep)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
 
-	for i := 0; i < len(fdb.DomainSize); i++ {
-		sqlStr := fmt.Sprintf("SELECT MAX(%s) FROM positions", columns[i])
-		rows, err := fdb.DB.Query(sqlStr)
-		if err != nil {
-			log.Fatal(err)
-			return false
+	var domainSize []int
+	for rows.Next() {
+		var name, shape string
+		var blob FieldBlob
+		if err := rows.Scan(&name, &blob.DType, &shape, &blob.Compression, &blob.Data); err != nil {
+			return nil, nil, err
 		}
-
-		var maxval int
-		for rows.Next() {
-			rows.Scan(&maxval)
+		blob.Shape, err = parseShape(shape)
+		if err != nil {
+			return nil, nil, err
 		}
-		if maxval != fdb.DomainSize[i]-1 {
-			return false
+		values, err := blob.Values()
+		if err != nil {
+			return nil, nil, err
 		}
+		fields = append(fields, realField(name, values))
+		domainSize = blob.Shape
 	}
-	return true
+	return fields, domainSize, rows.Err()
 }
 
-// Load loads all the fields from a database and return a list of Field
-// simID is the ID of the simulation that the field should be loaded from, and
-// timestep is the timestep from which the fields should be initialized.
-func (fdb *FieldDB) Load(simID int, timestep int) []Field {
+// loadRows loads all fields stored in the row layout. The domain size is extracted
+// from the domainSizes table (see LoadDomainSize)
+func (fdb *FieldDB) loadRows(simID int, timestep int) ([]Field, []int, error) {
 	fieldNames := []string{}
 	rows, err := fdb.DB.Query("SELECT DISTINCT name FROM fields WHERE simID=? "+
 		"AND timestep=? ORDER BY name", simID, timestep)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
 	}
 	var name string
 	for rows.Next() {
-		rows.Scan(&name)
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
 		fieldNames = append(fieldNames, name)
 	}
+	rows.Close()
+	if len(fieldNames) == 0 {
+		return []Field{}, nil, nil
+	}
 
-	var numNodes int
-	rows, err = fdb.DB.Query("SELECT COUNT(*) FROM positions")
+	domainSize, _, err := fdb.LoadDomainSize(simID)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
 	}
-	for rows.Next() {
-		rows.Scan(&numNodes)
+	if domainSize == nil {
+		return nil, nil, fmt.Errorf("fielddb: the domain size of simulation %d is unknown", simID)
 	}
+	numNodes := pfutil.ProdInt(domainSize)
+
 	fields := make(map[string]Field)
 	for _, key := range fieldNames {
 		fields[key] = NewField(key, numNodes, nil)
@@ -401,10 +617,19 @@ func (fdb *FieldDB) Load(simID int, timestep int) []Field {
 
 	rows, err = fdb.DB.Query("SELECT name, value, positionId FROM fields "+
 		"WHERE simID=? AND timestep=?", simID, timestep)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
 	var value float64
 	var positionID int
 	for rows.Next() {
-		rows.Scan(&name, &value, &positionID)
+		if err := rows.Scan(&name, &value, &positionID); err != nil {
+			return nil, nil, err
+		}
+		if positionID < 0 || positionID >= numNodes {
+			return nil, nil, fmt.Errorf("fielddb: position %d is outside the domain %v", positionID, domainSize)
+		}
 		fields[name].Data[positionID] = complex(value, 0.0)
 	}
 
@@ -412,19 +637,110 @@ func (fdb *FieldDB) Load(simID int, timestep int) []Field {
 	for i, fieldName := range fieldNames {
 		fieldArray[i] = fields[fieldName]
 	}
-	return fieldArray
+	return fieldArray, domainSize, rows.Err()
+}
+
+// Timesteps returns all timesteps where fields are stored for the passed simulation ID
+// in increasing order
+func (fdb *FieldDB) Timesteps(simID int) ([]int, error) {
+	query := "SELECT DISTINCT timestep FROM fields WHERE simID=?"
+	args := []interface{}{simID}
+	exists, err := fdb.hasTable("fieldBlobs")
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		query += " UNION SELECT DISTINCT timestep FROM fieldBlobs WHERE simID=?"
+		args = append(args, simID)
+	}
+	rows, err := fdb.DB.Query(query+" ORDER BY timestep", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	timesteps := []int{}
+	var step int
+	for rows.Next() {
+		if err := rows.Scan(&step); err != nil {
+			return nil, err
+		}
+		timesteps = append(timesteps, step)
+	}
+	return timesteps, rows.Err()
 }
 
 // LoadLast loads the fields from the latest timestep available for the
 // passed simulation ID
-func (fdb *FieldDB) LoadLast(simID int) []Field {
-	rows, err := fdb.DB.Query("SELECT MAX(timestep) FROM fields WHERE simID=?", simID)
+func (fdb *FieldDB) LoadLast(simID int) ([]Field, error) {
+	timesteps, err := fdb.Timesteps(simID)
+	if err != nil {
+		return nil, err
+	}
+	if len(timesteps) == 0 {
+		return []Field{}, nil
+	}
+	return fdb.Load(simID, timesteps[len(timesteps)-1])
+}
+
+// TimeSeriesData holds one timeseries of a simulation. Times is NaN for values stored
+// without the physical time (see TimeSeries)
+type TimeSeriesData struct {
+	Key       string
+	Timesteps []int
+	Times     []float64
+	Values    []float64
+}
+
+// TimeSeriesKeys returns the names of all timeseries of the passed simulation ID sorted
+// alphabetically
+func (fdb *FieldDB) TimeSeriesKeys(simID int) ([]string, error) {
+	rows, err := fdb.DB.Query("SELECT DISTINCT key FROM timeseries WHERE simID=? ORDER BY key", simID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// LoadTimeSeries loads the timeseries with the passed key for the passed simulation ID
+// ordered by timestep. An error is returned if the simulation has no such timeseries.
+func (fdb *FieldDB) LoadTimeSeries(simID int, key string) (TimeSeriesData, error) {
+	data := TimeSeriesData{Key: key}
+	rows, err := fdb.DB.Query("SELECT timestep, time, value FROM timeseries WHERE simID=? AND key=? ORDER BY timestep", simID, key)
 	if err != nil {
-		panic(err)
+		return data, err
 	}
-	var maxTs int
+	defer rows.Close()
+
 	for rows.Next() {
-		rows.Scan(&maxTs)
+		var timestep int
+		var time sql.NullFloat64
+		var value float64
+		if err := rows.Scan(&timestep, &time, &value); err != nil {
+			return data, err
+		}
+		if !time.Valid {
+			time.Float64 = math.NaN()
+		}
+		data.Timesteps = append(data.Timesteps, timestep)
+		data.Times = append(data.Times, time.Float64)
+		data.Values = append(data.Values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return data, err
+	}
+	if len(data.Values) == 0 {
+		return data, fmt.Errorf("fielddb: simulation %d has no timeseries named %s", simID, key)
 	}
-	return fdb.Load(simID, maxTs)
+	return data, nil
 }
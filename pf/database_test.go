@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/davidkleiven/gopf/pfutil"
@@ -34,8 +35,9 @@ func TestInitialize(t *testing.T) {
 	}
 
 	expectTables := []string{
-		"comments", "fields", "positions", "simAttributes", "simIDs",
-		"simTextAttributes", "timeseries",
+		"comments", "domainSizes", "fieldBlobs", "fields", "positions", "provenance", "provenanceComponents",
+		"provenanceEquations", "provenanceParameters", "randomSeeds", "schemaVersion",
+		"simAttributes", "simIDs", "simTextAttributes", "sqlite_sequence", "tags", "timeseries",
 	}
 
 	// Extract all table names
@@ -58,45 +60,29 @@ func TestInitialize(t *testing.T) {
 		}
 	}
 
-	if db.positionTableIsPopulated() {
-		t.Errorf("Position table is not populated")
+	var numPositions int
+	db.DB.QueryRow("SELECT COUNT(*) FROM positions").Scan(&numPositions)
+	if numPositions != 0 {
+		t.Errorf("Expected an empty position table got %d rows\n", numPositions)
 	}
 }
 
-func TestPopulatePositionTable(t *testing.T) {
-	dbName := "./testpositiontable.db"
+func TestLoadDomainSizeFromPositions(t *testing.T) {
+	dbName := "./testpositiontable1D.db"
 	sqlDB, _ := sql.Open("sqlite3", dbName)
 	defer os.Remove(dbName)
+	defer sqlDB.Close()
 
-	db := FieldDB{
-		DB:         sqlDB,
-		DomainSize: []int{3, 3},
-	}
+	// Databases created before the domain size was stored per simulation only have
+	// the positions table
+	db := FieldDB{DB: sqlDB}
 	db.initialize()
-	db.populatePositionsTable()
-
-	expect := [][]int{
-		{0, 0, 0},
-		{0, 1, 0},
-		{0, 2, 0},
-		{1, 0, 0},
-		{1, 1, 0},
-		{1, 2, 0},
-		{2, 0, 0},
-		{2, 1, 0},
-		{2, 2, 0},
+	for i := 0; i < 4; i++ {
+		db.DB.Exec("INSERT INTO positions (X, Y, Z) VALUES (?, 0, 0)", i)
 	}
 
-	rows, _ := db.DB.Query("SELECT X, Y, Z FROM positions ORDER BY id")
-
-	var x, y, z int
-	count := 0
-	for rows.Next() {
-		rows.Scan(&x, &y, &z)
-		if x != expect[count][0] || y != expect[count][1] || z != expect[count][2] {
-			t.Errorf("Expected %v\nGot %d %d %d", expect[count], x, y, z)
-		}
-		count++
+	if domainSize, _, err := db.LoadDomainSize(db.SimID()); err != nil || len(domainSize) != 1 || domainSize[0] != 4 {
+		t.Errorf("Expected domain size [4] from the positions table got %v (%v)\n", domainSize, err)
 	}
 }
 
@@ -114,13 +100,12 @@ func TestInsertRealPart(t *testing.T) {
 		data[i] = complex(float64(i), 0.0)
 	}
 	db.initialize()
-	db.populatePositionsTable()
 	db.insertRealPart("myfield", 2, data)
 
 	rows, _ := db.DB.Query("SELECT name, timestep, value, simID FROM fields")
 	var name string
 	var timestep int
-	var simID int32
+	var simID int
 	var value float64
 	rowCount := 0
 	for rows.Next() {
@@ -152,19 +137,23 @@ func TestSaveFields(t *testing.T) {
 	db := FieldDB{
 		DB:         sqlDB,
 		DomainSize: ds,
+		Layout:     FieldRowLayout,
+	}
+	if err := db.SaveFields(solver, 1); err != nil {
+		t.Errorf("%s\n", err)
 	}
-	db.SaveFields(solver, 1)
 
-	rows, _ := db.DB.Query("SELECT COUNT(*) FROM positions")
+	// The positions of the nodes are given by the domain size of the simulation
 	var numRows int
-	for rows.Next() {
-		rows.Scan(&numRows)
+	db.DB.QueryRow("SELECT COUNT(*) FROM positions").Scan(&numRows)
+	if numRows != 0 {
+		t.Errorf("Expected no positions got %d\n", numRows)
 	}
-	if numRows != 9 {
-		t.Errorf("Expected 9 positions got %d\n", numRows)
+	if domainSize, _, err := db.LoadDomainSize(db.SimID()); err != nil || !reflect.DeepEqual(domainSize, ds) {
+		t.Errorf("Expected domain size %v got %v (%v)\n", ds, domainSize, err)
 	}
 
-	rows, _ = db.DB.Query("SELECT COUNT(*) FROM fields")
+	rows, _ := db.DB.Query("SELECT COUNT(*) FROM fields")
 	for rows.Next() {
 		rows.Scan(&numRows)
 	}
@@ -173,6 +162,164 @@ func TestSaveFields(t *testing.T) {
 	}
 }
 
+func TestSaveFieldsWithoutDomainSize(t *testing.T) {
+	model := NewModel()
+	model.AddField(NewField("conc", 9, nil))
+	model.AddEquation("dconc/dt = -conc")
+	solver := NewSolver(&model, []int{3, 3}, 0.1)
+
+	for i, layout := range []FieldLayout{FieldRowLayout, FieldBlobLayout} {
+		dbName := "./testSaveFieldsWithoutDomainSize.db"
+		sqlDB, _ := sql.Open("sqlite3", dbName)
+		db := FieldDB{DB: sqlDB, Layout: layout}
+
+		// Valid input must not panic, thus an error is expected instead
+		if err := db.SaveFields(solver, 0); err == nil {
+			t.Errorf("Test #%d: Expected an error when no domain size is known\n", i)
+		}
+		sqlDB.Close()
+		os.Remove(dbName)
+	}
+}
+
+func TestSaveFieldsBlob(t *testing.T) {
+	field1 := NewField("field1", 12, nil)
+	field2 := NewField("field2", 12, nil)
+	for i := range field1.Data {
+		field1.Data[i] = complex(float64(i), 0.0)
+		field2.Data[i] = complex(-float64(i), 0.0)
+	}
+	model := NewModel()
+	model.AddField(field1)
+	model.AddField(field2)
+	model.AddEquation("dfield1/dt = -field1")
+	model.AddEquation("dfield2/dt = -field2")
+
+	ds := []int{3, 4}
+	solver := NewSolver(&model, ds, 0.1)
+
+	dbName := "./testSaveFieldsBlob.db"
+	sqlDB, _ := sql.Open("sqlite3", dbName)
+	defer os.Remove(dbName)
+
+	for i, compression := range []string{"", BlobNoCompression, BlobZlib} {
+		db := FieldDB{
+			DB:          sqlDB,
+			DomainSize:  ds,
+			Compression: compression,
+		}
+		if err := db.SaveFields(solver, 1); err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+
+		var numRows int
+		row := db.DB.QueryRow("SELECT COUNT(*) FROM fields WHERE simID=?", db.simID)
+		row.Scan(&numRows)
+		if numRows != 0 {
+			t.Errorf("Test #%d: Expected no rows in the fields table got %d\n", i, numRows)
+		}
+
+		var name, dtype, shape, comp string
+		var data []byte
+		row = db.DB.QueryRow("SELECT name, dtype, shape, compression, data FROM fieldBlobs WHERE simID=? ORDER BY name", db.simID)
+		if err := row.Scan(&name, &dtype, &shape, &comp, &data); err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+		expectComp := compression
+		if expectComp == "" {
+			expectComp = BlobZlib
+		}
+		if name != "field1" || dtype != "float64" || shape != "3,4" || comp != expectComp {
+			t.Errorf("Test #%d: Unexpected metadata %s %s %s %s\n", i, name, dtype, shape, comp)
+		}
+
+		fields, domainSize, err := db.LoadWithDomainSize(int(db.simID), 1)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+		if len(fields) != 2 || len(domainSize) != 2 || domainSize[0] != 3 || domainSize[1] != 4 {
+			t.Errorf("Test #%d: Expected two fields with domain size [3 4] got %d fields and %v\n", i, len(fields), domainSize)
+			continue
+		}
+		if !pfutil.CmplxEqualApprox(fields[0].Data, field1.Data, 1e-14) {
+			t.Errorf("Test #%d: Expected\n%v\nGot\n%v\n", i, field1.Data, fields[0].Data)
+		}
+	}
+}
+
+func TestFieldBlobErrors(t *testing.T) {
+	values := make([]complex128, 6)
+	if _, err := NewFieldBlob(values, []int{2, 2}, BlobZlib); err == nil {
+		t.Errorf("Expected error when the shape does not match the number of values\n")
+	}
+	if _, err := NewFieldBlob(values, []int{2, 3}, "gzip"); err == nil {
+		t.Errorf("Expected error for unknown compression\n")
+	}
+	blob, _ := NewFieldBlob(values, []int{2, 3}, BlobNoCompression)
+	blob.Shape = []int{2, 4}
+	if _, err := blob.Values(); err == nil {
+		t.Errorf("Expected error when the blob does not match the shape\n")
+	}
+}
+
+func TestFieldBlobEncoding(t *testing.T) {
+	values := []complex128{complex(1.0, 3.0), complex(-2.5, 0.0)}
+
+	// Little endian float64 representation of 1.0 and -2.5
+	expect := []byte{0, 0, 0, 0, 0, 0, 0xf0, 0x3f, 0, 0, 0, 0, 0, 0, 0x04, 0xc0}
+	blob, err := NewFieldBlob(values, []int{2}, BlobNoCompression)
+	if err != nil || !reflect.DeepEqual(blob.Data, expect) {
+		t.Errorf("Expected\n%v\nGot\n%v (%v)\n", expect, blob.Data, err)
+	}
+
+	for i, compression := range []string{BlobNoCompression, BlobZlib} {
+		blob, _ := NewFieldBlob(values, []int{2}, compression)
+		decoded, err := blob.Values()
+		if err != nil || !reflect.DeepEqual(decoded, []float64{1.0, -2.5}) {
+			t.Errorf("Test #%d: Expected [1 -2.5] got %v (%v)\n", i, decoded, err)
+		}
+	}
+}
+
+func TestBoundaryConditionsStored(t *testing.T) {
+	field := NewField("field", 12, nil)
+	model := NewModel()
+	model.AddField(field)
+	model.AddEquation("dfield/dt = LAP field")
+
+	ds := []int{3, 4}
+	solver := NewSolver(&model, ds, 0.1)
+	solver.FT = pfutil.NewR2RTransform(ds, []pfutil.BoundaryCondition{pfutil.NeumannDCT1, pfutil.Periodic})
+
+	dbName := "./testBoundaryConditions.db"
+	sqlDB, _ := sql.Open("sqlite3", dbName)
+	defer os.Remove(dbName)
+	db := FieldDB{
+		DB:         sqlDB,
+		DomainSize: ds,
+	}
+	db.SaveFields(solver, 0)
+	db.SaveFields(solver, 1)
+
+	rows, _ := db.DB.Query("SELECT key, value FROM simTextAttributes ORDER BY key")
+	expect := [][]string{{"boundaryX", "neumann (DCT-I)"}, {"boundaryY", "periodic"}}
+	var key, value string
+	count := 0
+	for rows.Next() {
+		rows.Scan(&key, &value)
+		if count < len(expect) && (key != expect[count][0] || value != expect[count][1]) {
+			t.Errorf("Expected %v got %s %s\n", expect[count], key, value)
+		}
+		count++
+	}
+	if count != len(expect) {
+		t.Errorf("Expected %d attributes got %d\n", len(expect), count)
+	}
+}
+
 func TestComment(t *testing.T) {
 	dbName := "./testcomment.db"
 	sqlDB, _ := sql.Open("sqlite3", dbName)
@@ -183,7 +330,7 @@ func TestComment(t *testing.T) {
 	comment := "This is a comment"
 	db.Comment(comment)
 	rows, _ := db.DB.Query("SELECT simID, value FROM comments")
-	var simID int32
+	var simID int
 	var value string
 	for rows.Next() {
 		rows.Scan(&simID, &value)
@@ -362,23 +509,31 @@ func (c *constant) Eval(i int, bricks map[string]Brick) complex128 {
 }
 
 func TestLoadFields(t *testing.T) {
-	temperature := NewField("temperature", 16, nil)
-	concentration := NewField("conc", 16, nil)
-	model := NewModel()
-	model.AddField(temperature)
-	model.AddField(concentration)
-	rateTemp := constant{
-		Value: 0.1,
-	}
-	rateConc := constant{
-		Value: -0.1,
-	}
+	for _, layout := range []FieldLayout{FieldRowLayout, FieldBlobLayout} {
+		temperature := NewField("temperature", 16, nil)
+		concentration := NewField("conc", 16, nil)
+		model := NewModel()
+		model.AddField(temperature)
+		model.AddField(concentration)
+		rateTemp := constant{
+			Value: 0.1,
+		}
+		rateConc := constant{
+			Value: -0.1,
+		}
+
+		model.RegisterFunction("RATE_TEMP", rateTemp.Eval)
+		model.RegisterFunction("RATE_CONC", rateConc.Eval)
 
-	model.RegisterFunction("RATE_TEMP", rateTemp.Eval)
-	model.RegisterFunction("RATE_CONC", rateConc.Eval)
+		model.AddEquation("dtemperature/dt = RATE_TEMP")
+		model.AddEquation("dconcentration/dt = RATE_CONC")
+		loadFieldsLayout(t, &model, layout)
+	}
+}
 
-	model.AddEquation("dtemperature/dt = RATE_TEMP")
-	model.AddEquation("dconcentration/dt = RATE_CONC")
+func loadFieldsLayout(t *testing.T, model *Model, layout FieldLayout) {
+	temperature := NewField("temperature", 16, nil)
+	concentration := NewField("conc", 16, nil)
 
 	dbName := "test_load.db"
 	db, _ := sql.Open("sqlite3", dbName)
@@ -387,15 +542,25 @@ func TestLoadFields(t *testing.T) {
 	fieldDB := FieldDB{
 		DB:         db,
 		DomainSize: []int{4, 4},
+		Layout:     layout,
 	}
 
-	solver := NewSolver(&model, fieldDB.DomainSize, 1.0)
-	solver.AddCallback(fieldDB.SaveFields)
-	solver.Solve(10, 1)
+	solver := NewSolver(model, fieldDB.DomainSize, 1.0)
+	solver.AddErrCallback(fieldDB.SaveFields)
+	if err := solver.Solve(10, 1); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
 
 	// Run tests
 	for step := 0; step < 10; step++ {
-		fields := fieldDB.Load(int(fieldDB.simID), step)
+		fields, err := fieldDB.Load(int(fieldDB.simID), step)
+		if err != nil {
+			t.Errorf("%s\n", err)
+		}
+		if len(fields) != 2 {
+			t.Errorf("Layout %d: Expected 2 fields got %d\n", layout, len(fields))
+		}
 
 		// Fill temperature and concentration fields with the expected values
 		for i := range temperature.Data {
@@ -413,7 +578,10 @@ func TestLoadFields(t *testing.T) {
 	}
 
 	// Test load last
-	fields := fieldDB.LoadLast(int(fieldDB.simID))
+	fields, err := fieldDB.LoadLast(int(fieldDB.simID))
+	if err != nil {
+		t.Errorf("%s\n", err)
+	}
 	expect := []Field{
 		concentration, temperature,
 	}
@@ -423,3 +591,224 @@ func TestLoadFields(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteTimeSeriesStoresTime(t *testing.T) {
+	dbName := "./testwritetimeseries.db"
+	sqlDB, _ := sql.Open("sqlite3", dbName)
+	defer os.Remove(dbName)
+	db := FieldDB{
+		DB: sqlDB,
+	}
+	db.WriteTimeSeries(map[string]float64{"energy": -0.1}, 10, 2.5)
+	db.TimeSeries(map[string]float64{"energy": -0.2}, 11)
+
+	rows, _ := db.DB.Query("SELECT timestep,time FROM timeseries ORDER BY timestep ASC")
+	expect := []sql.NullFloat64{{Float64: 2.5, Valid: true}, {}}
+	count := 0
+	var timestep int
+	var time sql.NullFloat64
+	for rows.Next() {
+		rows.Scan(&timestep, &time)
+		if time.Valid != expect[count].Valid || math.Abs(time.Float64-expect[count].Float64) > 1e-10 {
+			t.Errorf("Timestep %d: Expected %v got %v\n", timestep, expect[count], time)
+		}
+		count++
+	}
+	if count != len(expect) {
+		t.Errorf("Expected %d rows got %d\n", len(expect), count)
+	}
+}
+
+func TestLoadTimeSeries(t *testing.T) {
+	dbName := "./testloadtimeseries.db"
+	sqlDB, _ := sql.Open("sqlite3", dbName)
+	defer os.Remove(dbName)
+	db := FieldDB{
+		DB: sqlDB,
+	}
+	db.WriteTimeSeries(map[string]float64{"energy": -0.2, "mass": 1.0}, 20, 5.0)
+	db.WriteTimeSeries(map[string]float64{"energy": -0.1}, 10, 2.5)
+	db.TimeSeries(map[string]float64{"energy": -0.3}, 30)
+
+	keys, err := db.TimeSeriesKeys(db.SimID())
+	if err != nil {
+		t.Errorf("%s\n", err)
+	}
+	if !reflect.DeepEqual(keys, []string{"energy", "mass"}) {
+		t.Errorf("Expected [energy mass] got %v\n", keys)
+	}
+
+	data, err := db.LoadTimeSeries(db.SimID(), "energy")
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	if !reflect.DeepEqual(data.Timesteps, []int{10, 20, 30}) {
+		t.Errorf("Expected timesteps [10 20 30] got %v\n", data.Timesteps)
+	}
+	if !reflect.DeepEqual(data.Values, []float64{-0.1, -0.2, -0.3}) {
+		t.Errorf("Expected values [-0.1 -0.2 -0.3] got %v\n", data.Values)
+	}
+	if data.Times[0] != 2.5 || data.Times[1] != 5.0 || !math.IsNaN(data.Times[2]) {
+		t.Errorf("Expected times [2.5 5 NaN] got %v\n", data.Times)
+	}
+
+	if _, err := db.LoadTimeSeries(db.SimID(), "volume"); err == nil {
+		t.Errorf("Expected error for unknown key\n")
+	}
+}
+
+func TestOpenFieldDB(t *testing.T) {
+	dbName := "./testOpenFieldDB.db"
+	defer os.Remove(dbName)
+
+	db, err := OpenFieldDB(dbName, []int{4, 4}, FieldDBOptions{Compression: BlobNoCompression})
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	if db.SimID() == 0 {
+		t.Errorf("Expected a simulation ID to be assigned\n")
+	}
+	if err := db.Comment("opened with OpenFieldDB"); err != nil {
+		t.Errorf("%s\n", err)
+	}
+
+	model := NewModel()
+	model.AddField(NewField("conc", 16, nil))
+	model.AddEquation("dconc/dt = LAP conc")
+	solver := NewSolver(&model, db.DomainSize, 0.1)
+	solver.AddErrCallback(db.SaveFields)
+	if err := solver.Solve(2, 1); err != nil {
+		t.Errorf("%s\n", err)
+	}
+
+	var compression string
+	db.DB.QueryRow("SELECT compression FROM fieldBlobs WHERE simID=?", db.SimID()).Scan(&compression)
+	if compression != BlobNoCompression {
+		t.Errorf("Expected compression %s got %s\n", BlobNoCompression, compression)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Errorf("%s\n", err)
+	}
+
+	// Errors after the database is closed should be returned to the caller
+	if err := db.Comment("closed"); err == nil {
+		t.Errorf("Expected error when commenting on a closed database\n")
+	}
+	if err := db.SetAttr(map[string]float64{"value": 1.0}); err == nil {
+		t.Errorf("Expected error when setting attributes on a closed database\n")
+	}
+	if _, err := db.Load(db.SimID(), 0); err == nil {
+		t.Errorf("Expected error when loading from a closed database\n")
+	}
+
+	// The failing callback should stop the solver instead of terminating the process
+	numCalls := 0
+	solver.AddCallback(func(s *Solver, epoch int) { numCalls++ })
+	if err := solver.Solve(3, 1); err == nil {
+		t.Errorf("Expected the solver to return the error of the callback\n")
+	}
+	if numCalls != 1 {
+		t.Errorf("Expected the solver to stop after the first epoch. Callback called %d times\n", numCalls)
+	}
+}
+
+func TestOpenFieldDBInvalidPath(t *testing.T) {
+	if _, err := OpenFieldDB("/nonexistent/dir/db.db", nil, FieldDBOptions{}); err == nil {
+		t.Errorf("Expected error when the database can not be created\n")
+	}
+}
+
+func TestOpenFieldDBWithoutDomainSize(t *testing.T) {
+	dbName := "./testOpenFieldDBWithoutDomainSize.db"
+	defer os.Remove(dbName)
+
+	db, err := OpenFieldDB(dbName, nil, FieldDBOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	model := NewModel()
+	model.AddField(NewField("conc", 16, nil))
+	model.AddEquation("dconc/dt = -conc")
+	solver := NewSolver(&model, []int{4, 4}, 0.1)
+	if err := db.SaveFields(solver, 0); err == nil {
+		t.Errorf("Expected an error when saving without a domain size\n")
+	}
+
+	for _, table := range []string{"fieldBlobs", "domainSizes", "positions"} {
+		var count int
+		db.DB.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count)
+		if count != 0 {
+			t.Errorf("%s: Expected no rows got %d\n", table, count)
+		}
+	}
+}
+
+func TestMixedDomainSizes(t *testing.T) {
+	dbName := "./testMixedDomainSizes.db"
+	defer os.Remove(dbName)
+
+	domainSizes := [][]int{{4, 4}, {8, 8}, {2, 3, 4}}
+	ids := []int{}
+	for i, ds := range domainSizes {
+		for _, layout := range []FieldLayout{FieldRowLayout, FieldBlobLayout} {
+			db, err := OpenFieldDB(dbName, ds, FieldDBOptions{Layout: layout})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i == 1 {
+				db.GridSpacing = []float64{0.5, 0.25}
+			}
+			model := NewModel()
+			field := NewField("conc", pfutil.ProdInt(ds), nil)
+			for j := range field.Data {
+				field.Data[j] = complex(float64(j), 0.0)
+			}
+			model.AddField(field)
+			model.AddEquation("dconc/dt = conc")
+			solver := NewSolver(&model, ds, 0.1)
+			if err := db.SaveFields(solver, 0); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, db.SimID())
+			db.Close()
+		}
+	}
+
+	sqlDB, _ := sql.Open("sqlite3", dbName)
+	defer sqlDB.Close()
+	db := FieldDB{DB: sqlDB}
+	for i, id := range ids {
+		want := domainSizes[i/2]
+		fields, domainSize, err := db.LoadWithDomainSize(id, 0)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(domainSize, want) {
+			t.Errorf("Test #%d: Expected domain size %v got %v\n", i, want, domainSize)
+		}
+		if len(fields) != 1 || len(fields[0].Data) != pfutil.ProdInt(want) {
+			t.Errorf("Test #%d: Unexpected number of nodes\n", i)
+			continue
+		}
+		for j, v := range fields[0].Data {
+			if real(v) != float64(j) {
+				t.Errorf("Test #%d: Expected %d at node %d got %f\n", i, j, j, real(v))
+				break
+			}
+		}
+
+		_, spacing, err := db.LoadDomainSize(id)
+		if i/2 == 1 && (err != nil || !reflect.DeepEqual(spacing, []float64{0.5, 0.25})) {
+			t.Errorf("Test #%d: Expected grid spacing [0.5 0.25] got %v (%v)\n", i, spacing, err)
+		}
+		if i/2 != 1 && spacing != nil {
+			t.Errorf("Test #%d: Expected no grid spacing got %v\n", i, spacing)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package pf
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/davidkleiven/gopf/pfutil"
+)
+
+// TwoThirdsRule is a modal filter implementing Orszag's 2/3-rule. All modes where
+// at least one of the frequency components is larger than or equal to 1/3 (e.g. two
+// thirds of the Nyquist frequency) are removed. If the fields are kept truncated
+// in this way, products of two fields do not alias into the retained modes. Thus,
+// quadratic nonlinearities are free of aliasing errors when this filter is passed
+// to the time stepper
+//
+//	solver.Stepper.SetFilter(&pf.TwoThirdsRule{})
+type TwoThirdsRule struct{}
+
+// Eval evaluates the filter from the argument passed by ApplyModalFilter (the length
+// of the frequency vector scaled by 2/pi). This is only used when the filter is
+// applied to a radial frequency. ApplyModalFilter uses EvalFreq
+func (t *TwoThirdsRule) Eval(x float64) float64 {
+	return t.keep(x * math.Pi / 2.0)
+}
+
+// EvalFreq returns 1 if all the frequency components are below 1/3 and zero otherwise
+func (t *TwoThirdsRule) EvalFreq(freq []float64) float64 {
+	for _, f := range freq {
+		if t.keep(f) == 0.0 {
+			return 0.0
+		}
+	}
+	return 1.0
+}
+
+// keep returns 1 if the frequency is retained and 0 otherwise
+func (t *TwoThirdsRule) keep(f float64) float64 {
+	if 3.0*math.Abs(f) >= 1.0-1e-10 {
+		return 0.0
+	}
+	return 1.0
+}
+
+// Dealiaser is a generic interface for methods removing aliasing errors from the
+// derived fields originating from products of fields in the equations (e.g. conc^2*eta)
+type Dealiaser interface {
+	// DerivedFieldCalc returns a calculator for the product given by desc
+	DerivedFieldCalc(desc string, fields []Field) DerivedFieldCalc
+}
+
+// TwoThirdsDealiaser evaluates the derived fields by truncating each factor according
+// to the 2/3-rule, forming the product in real space and truncating the result. FT is
+// the fourier transformer of the solver
+type TwoThirdsDealiaser struct {
+	FT FourierTransform
+}
+
+// DerivedFieldCalc returns a calculator for the product given by desc
+func (td *TwoThirdsDealiaser) DerivedFieldCalc(desc string, fields []Field) DerivedFieldCalc {
+	fieldMap := make(map[string]*Field)
+	for i := range fields {
+		fieldMap[fields[i].Name] = &fields[i]
+	}
+	fieldNames, powers := parseFactors(desc)
+
+	var work []complex128
+	return func(data []complex128) {
+		if len(work) != len(data) {
+			work = make([]complex128, len(data))
+		}
+		for i := range data {
+			data[i] = 1.0
+		}
+
+		for j := range fieldNames {
+			copy(work, fieldMap[fieldNames[j]].Data)
+			td.truncate(work)
+			for i := range data {
+				data[i] *= cmplx.Pow(work[i], complex(powers[j], 0.0))
+			}
+		}
+		td.truncate(data)
+	}
+}
+
+// truncate removes the modes that are filtered out by the 2/3-rule from the real space data
+func (td *TwoThirdsDealiaser) truncate(data []complex128) {
+	td.FT.FFT(data)
+	ApplyModalFilter(&TwoThirdsRule{}, td.FT.Freq, data)
+	td.FT.IFFT(data)
+	pfutil.DivRealScalar(data, float64(len(data)))
+}
+
+// ThreeHalvesPadding evaluates the derived fields on a grid that is 3/2 times larger
+// along each axis. The factors are spectrally interpolated onto the fine grid by
+// zero-padding, the product is formed on the fine grid and the result is truncated
+// back to the original modes. Quadratic products are thereby evaluated without
+// aliasing errors, while for higher order products the errors are reduced. The
+// Nyquist modes are removed on even grids, as they cannot be represented unambiguously
+// on the fine grid.
+type ThreeHalvesPadding struct {
+	DomainSize []int
+	coarse     *pfutil.FFTWWrapper
+	fine       *pfutil.FFTWWrapper
+
+	// fineIdx holds the index on the fine grid of each mode of the original grid.
+	// Nyquist modes are marked by -1
+	fineIdx []int
+}
+
+// NewThreeHalvesPadding returns a new padding scheme for the passed domain size
+func NewThreeHalvesPadding(domainSize []int) *ThreeHalvesPadding {
+	fineSize := make([]int, len(domainSize))
+	for i, n := range domainSize {
+		fineSize[i] = (3*n + 1) / 2
+	}
+
+	fineIdx := make([]int, pfutil.ProdInt(domainSize))
+	for i := range fineIdx {
+		pos := pfutil.Pos(domainSize, i)
+		nyquist := false
+		for j := range pos {
+			n := domainSize[j]
+			if 2*pos[j] == n {
+				nyquist = true
+				break
+			}
+			k := pos[j]
+			if 2*k > n {
+				k -= n
+			}
+			pos[j] = (k + fineSize[j]) % fineSize[j]
+		}
+
+		if nyquist {
+			fineIdx[i] = -1
+		} else {
+			fineIdx[i] = pfutil.NodeIdx(fineSize, pos)
+		}
+	}
+
+	return &ThreeHalvesPadding{
+		DomainSize: domainSize,
+		coarse:     pfutil.NewFFTW(domainSize),
+		fine:       pfutil.NewFFTW(fineSize),
+		fineIdx:    fineIdx,
+	}
+}
+
+// DerivedFieldCalc returns a calculator for the product given by desc
+func (tp *ThreeHalvesPadding) DerivedFieldCalc(desc string, fields []Field) DerivedFieldCalc {
+	fieldMap := make(map[string]*Field)
+	for i := range fields {
+		fieldMap[fields[i].Name] = &fields[i]
+	}
+	fieldNames, powers := parseFactors(desc)
+
+	coarse := make([]complex128, len(tp.fineIdx))
+	fine := make([]complex128, pfutil.ProdInt(tp.fine.Dimensions))
+	product := make([]complex128, len(fine))
+	return func(data []complex128) {
+		for i := range product {
+			product[i] = 1.0
+		}
+
+		for j := range fieldNames {
+			tp.interpolate(fieldMap[fieldNames[j]].Data, coarse, fine)
+			for i := range product {
+				product[i] *= cmplx.Pow(fine[i], complex(powers[j], 0.0))
+			}
+		}
+		tp.restrict(product, data)
+	}
+}
+
+// interpolate places the spectral interpolation of data onto the fine grid in fine.
+// coarse is used as workspace
+func (tp *ThreeHalvesPadding) interpolate(data []complex128, coarse []complex128, fine []complex128) {
+	copy(coarse, data)
+	tp.coarse.FFT(coarse)
+	for i := range fine {
+		fine[i] = 0.0
+	}
+	for i, idx := range tp.fineIdx {
+		if idx >= 0 {
+			fine[idx] = coarse[i]
+		}
+	}
+	tp.fine.IFFT(fine)
+	pfutil.DivRealScalar(fine, float64(len(coarse)))
+}
+
+// restrict transfers the modes of the fine grid data that are representable on the
+// original grid to data. The fine grid data is overwritten
+func (tp *ThreeHalvesPadding) restrict(fine []complex128, data []complex128) {
+	tp.fine.FFT(fine)
+	for i, idx := range tp.fineIdx {
+		if idx >= 0 {
+			data[i] = fine[idx]
+		} else {
+			data[i] = 0.0
+		}
+	}
+	tp.coarse.IFFT(data)
+	pfutil.DivRealScalar(data, float64(len(fine)))
+}
+
+// SetDealiasing sets the method used to remove aliasing errors from the derived
+// fields that originate from products of fields in the equations (e.g. conc^2*eta).
+// The method applies both to derived fields that already exist and to the ones
+// created by later calls to AddEquation. Passing nil restores the plain pointwise
+// product. Example:
+//
+//	model.SetDealiasing(pf.NewThreeHalvesPadding(domainSize))
+func (m *Model) SetDealiasing(d Dealiaser) {
+	m.dealiaser = d
+	for i := range m.DerivedFields {
+		if m.isFieldProduct(m.DerivedFields[i].Name) {
+			m.DerivedFields[i].Calc = m.derivedFieldCalc(m.DerivedFields[i].Name)
+		}
+	}
+}
+
+// derivedFieldCalc returns the calculator for a product of fields
+func (m *Model) derivedFieldCalc(desc string) DerivedFieldCalc {
+	if m.dealiaser != nil {
+		return m.dealiaser.DerivedFieldCalc(desc, m.Fields)
+	}
+	return DerivedFieldCalcFromDesc(desc, m.Fields)
+}
+
+// isFieldProduct returns true if desc is a product of the fields in the model
+func (m *Model) isFieldProduct(desc string) bool {
+	names, _ := parseFactors(desc)
+	for _, name := range names {
+		found := false
+		for _, f := range m.Fields {
+			if f.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
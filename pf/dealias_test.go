@@ -0,0 +1,117 @@
+package pf
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/davidkleiven/gopf/pfutil"
+)
+
+func TestTwoThirdsRule(t *testing.T) {
+	N := 12
+	ft := pfutil.NewFFTW([]int{N, N})
+	data := make([]complex128, N*N)
+	for i := range data {
+		data[i] = 1.0
+	}
+	ApplyModalFilter(&TwoThirdsRule{}, ft.Freq, data)
+
+	for i := range data {
+		pos := pfutil.Pos([]int{N, N}, i)
+		expect := 1.0
+		for _, p := range pos {
+			k := p
+			if k > N/2 {
+				k -= N
+			}
+			if 3*int(math.Abs(float64(k))) >= N {
+				expect = 0.0
+			}
+		}
+		if math.Abs(real(data[i])-expect) > 1e-10 {
+			t.Errorf("Mode %v: Expected %f got %f\n", pos, expect, real(data[i]))
+		}
+	}
+}
+
+func TestDealiasedProducts(t *testing.T) {
+	for i, test := range []struct {
+		N         int
+		k1        float64
+		k2        float64
+		dealiaser func(N int) Dealiaser
+		expect    func(x float64) float64
+	}{
+		// Mode 10 aliases onto mode -6 and mode 8 is the Nyquist mode
+		{
+			N:  16,
+			k1: 3.0,
+			k2: 5.0,
+			dealiaser: func(N int) Dealiaser {
+				return NewThreeHalvesPadding([]int{N})
+			},
+			expect: func(x float64) float64 {
+				return 1.0 + 0.5*math.Cos(6.0*x) + math.Cos(2.0*x)
+			},
+		},
+		// Modes with |k| >= 6 are removed
+		{
+			N:  18,
+			k1: 2.0,
+			k2: 4.0,
+			dealiaser: func(N int) Dealiaser {
+				return &TwoThirdsDealiaser{FT: pfutil.NewFFTW([]int{N})}
+			},
+			expect: func(x float64) float64 {
+				return 1.0 + 0.5*math.Cos(4.0*x) + math.Cos(2.0*x)
+			},
+		},
+	} {
+		m := NewModel()
+		conc := NewField("conc", test.N, nil)
+		for j := range conc.Data {
+			x := 2.0 * math.Pi * float64(j) / float64(test.N)
+			conc.Data[j] = complex(math.Cos(test.k1*x)+math.Cos(test.k2*x), 0.0)
+		}
+		m.AddField(conc)
+		m.AddEquation("dconc/dt = conc^2")
+		m.SetDealiasing(test.dealiaser(test.N))
+		m.SyncDerivedFields()
+
+		b := m.Bricks["conc^2"]
+		for j := 0; j < test.N; j++ {
+			x := 2.0 * math.Pi * float64(j) / float64(test.N)
+			if cmplx.Abs(b.Get(j)-complex(test.expect(x), 0.0)) > 1e-10 {
+				t.Errorf("Test #%d: Node %d: Expected %f got %v\n", i, j, test.expect(x), b.Get(j))
+			}
+		}
+	}
+}
+
+func TestThreeHalvesPaddingRestoresProduct(t *testing.T) {
+	// Products that are resolved on the original grid are unaffected by padding
+	domainSize := []int{8, 9, 10}
+	N := pfutil.ProdInt(domainSize)
+	eta := NewField("eta", N, nil)
+	conc := NewField("conc", N, nil)
+	for i := range eta.Data {
+		pos := pfutil.Pos(domainSize, i)
+		x := 2.0 * math.Pi * float64(pos[0]) / float64(domainSize[0])
+		z := 2.0 * math.Pi * float64(pos[2]) / float64(domainSize[2])
+		eta.Data[i] = complex(math.Cos(x), 0.0)
+		conc.Data[i] = complex(math.Sin(z)+0.5, 0.0)
+	}
+
+	padding := NewThreeHalvesPadding(domainSize)
+	calc := padding.DerivedFieldCalc("conc*eta", []Field{eta, conc})
+	data := make([]complex128, N)
+	calc(data)
+
+	for i := range data {
+		expect := eta.Data[i] * conc.Data[i]
+		if cmplx.Abs(data[i]-expect) > 1e-10 {
+			t.Errorf("Node %d: Expected %v got %v\n", i, expect, data[i])
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package pf
 
 import (
+	"fmt"
 	"math"
+	"strings"
 
 	"gonum.org/v1/gonum/floats"
 )
@@ -28,3 +30,53 @@ func (l LaplacianN) Eval(freq Frequency, ft []complex128) []complex128 {
 	}
 	return ft
 }
+
+// FractionalLaplacian represents the operator -(-LAP)^Power, where Power is an arbitrary
+// real number. In the fourier domain the operator is given by -|k|^(2*Power), which is
+// continuous in Power. For Power = 1 it coincides with the ordinary laplacian, and it is
+// dissipative for all positive powers. In equations it is written as FLAP^Power (e.g.
+// FLAP^0.75 conc), and LAP^Power with a non-integer power is also mapped to this
+// operator (see laplacianOperator).
+type FractionalLaplacian struct {
+	Power float64
+}
+
+// Eval implements the fourier transformed operator. freq is a function that returns the
+// frequency at index i of the passed array. ft is the fourier transformed field
+func (l FractionalLaplacian) Eval(freq Frequency, ft []complex128) []complex128 {
+	for i := range ft {
+		k := 2.0 * math.Pi * floats.Norm(freq(i), 2)
+		ft[i] *= complex(-math.Pow(k, 2.0*l.Power), 0.0)
+	}
+	return ft
+}
+
+// laplacianOperator returns the laplacian raised to the passed power (LAP^p). Integer
+// powers give LaplacianN, which is (-|k|^2)^n in the fourier domain. A negative number
+// raised to a non-integer power is not real, thus non-integer powers give the fractional
+// laplacian -(-LAP)^p with the symbol -|k|^(2p). The two conventions agree for odd
+// powers, but differ in sign for even powers: LAP^2 is +|k|^4, while LAP^1.999 is close
+// to -|k|^4. Use FLAP^p for an operator that is continuous in p. Negative powers are
+// rejected, since the operator is singular at k = 0.
+func laplacianOperator(power float64) (DiffOp, error) {
+	if power < 0.0 {
+		return nil, fmt.Errorf("rhsbuilder: LAP^%v requires a non-negative power", power)
+	}
+	if math.Abs(power-math.Round(power)) < 1e-10 {
+		return LaplacianN{Power: int(math.Round(power))}.Eval, nil
+	}
+	return FractionalLaplacian{Power: power}.Eval, nil
+}
+
+// laplacianFromPrefix returns the operator corresponding to a laplacian prefix in an
+// equation (LAP, LAP^p or FLAP^p)
+func laplacianFromPrefix(prefix string) (DiffOp, error) {
+	power := GetPower(prefix)
+	if !strings.HasPrefix(prefix, "FLAP") {
+		return laplacianOperator(power)
+	}
+	if power < 0.0 {
+		return nil, fmt.Errorf("rhsbuilder: FLAP^%v requires a non-negative power", power)
+	}
+	return FractionalLaplacian{Power: power}.Eval, nil
+}
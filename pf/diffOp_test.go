@@ -55,3 +55,57 @@ func TestLaplacianN(t *testing.T) {
 		}
 	}
 }
+
+func TestFractionalLaplacianContinuousAcrossTwo(t *testing.T) {
+	freq := func(i int) []float64 {
+		return []float64{0.1}
+	}
+	unit := func(freq Frequency, t float64, data []complex128) {
+		data[0] = complex(1.0, 0.0)
+	}
+
+	// The operator is built through the same prefix parsing as equation strings
+	k4 := math.Pow(2.0*math.Pi*0.1, 4.0)
+	for i, prefix := range []string{"FLAP^1.9999", "FLAP^2", "FLAP^2.0001"} {
+		prefixes := getKnownPrefixes(prefix + "conc")
+		data := make([]complex128, 1)
+		constructFunc(unit, prefixes)(freq, 0.0, data)
+		if math.Abs(real(data[0])+k4) > 1e-3*k4 {
+			t.Errorf("Test #%d: %s: Expected approximately %f got %f\n", i, prefix, -k4, real(data[0]))
+		}
+	}
+}
+
+func TestLaplacianNonIntegerPower(t *testing.T) {
+	freq := func(i int) []float64 {
+		return []float64{0.1}
+	}
+	unit := func(freq Frequency, t float64, data []complex128) {
+		data[0] = complex(1.0, 0.0)
+	}
+
+	// Non-integer powers of LAP are mapped to the fractional laplacian
+	k := 2.0 * math.Pi * 0.1
+	for i, test := range []struct {
+		prefix string
+		expect float64
+	}{
+		{prefix: "LAP^0.75", expect: -math.Pow(k, 1.5)},
+		{prefix: "LAP^1.9999", expect: -math.Pow(k, 3.9998)},
+		{prefix: "LAP^2", expect: math.Pow(k, 4.0)},
+		{prefix: "LAP^3", expect: -math.Pow(k, 6.0)},
+	} {
+		prefixes := getKnownPrefixes(test.prefix + "conc")
+		data := make([]complex128, 1)
+		constructFunc(unit, prefixes)(freq, 0.0, data)
+		if math.Abs(real(data[0])-test.expect) > 1e-10*math.Abs(test.expect) {
+			t.Errorf("Test #%d: %s: Expected %f got %f\n", i, test.prefix, test.expect, real(data[0]))
+		}
+	}
+
+	for i, prefix := range []string{"LAP^-1", "FLAP^-0.5"} {
+		if _, err := laplacianFromPrefix(prefix); err == nil {
+			t.Errorf("Test #%d: Expected error for %s\n", i, prefix)
+		}
+	}
+}
@@ -33,7 +33,7 @@ func (eu *Euler) Step(m *Model) {
 			d[j] = (d[j] + cDt*rhs[j]) / (complex(1.0, 0.0) - cDt*denum[j])
 		}
 
-		if eu.Filter != nil {
+		if filterApplies(eu.Filter, m.Fields[i].Name) {
 			ApplyModalFilter(eu.Filter, eu.FT.Freq, d)
 		}
 	}
@@ -0,0 +1,126 @@
+package pf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/davidkleiven/gopf/pfutil"
+)
+
+// Supported compression schemes for field blobs
+const (
+	BlobNoCompression = "none"
+	BlobZlib          = "zlib"
+)
+
+// blobDType is the data type of all blobs written by FieldDB (little endian float64)
+const blobDType = "float64"
+
+// FieldBlob represents the value of a field at all nodes stored as one binary blob.
+// The values are stored as little endian numbers in the same order as the nodes in the
+// simulation domain (see pfutil.Pos).
+// - DType: Data type of each value. Currently only float64 is supported
+// - Shape: Size of the simulation domain
+// - Compression: Compression scheme (none or zlib)
+type FieldBlob struct {
+	DType       string
+	Shape       []int
+	Compression string
+	Data        []byte
+}
+
+// NewFieldBlob encodes the real part of the values into a blob using the passed compression
+func NewFieldBlob(values []complex128, shape []int, compression string) (FieldBlob, error) {
+	if len(values) != pfutil.ProdInt(shape) {
+		return FieldBlob{}, fmt.Errorf("fieldblob: %d values does not match the shape %v", len(values), shape)
+	}
+	raw := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(raw[8*i:], math.Float64bits(real(v)))
+	}
+
+	blob := FieldBlob{
+		DType:       blobDType,
+		Shape:       shape,
+		Compression: compression,
+	}
+	switch compression {
+	case BlobNoCompression:
+		blob.Data = raw
+	case BlobZlib:
+		compressed := new(bytes.Buffer)
+		writer := zlib.NewWriter(compressed)
+		if _, err := writer.Write(raw); err != nil {
+			return FieldBlob{}, err
+		}
+		if err := writer.Close(); err != nil {
+			return FieldBlob{}, err
+		}
+		blob.Data = compressed.Bytes()
+	default:
+		return FieldBlob{}, fmt.Errorf("fieldblob: unknown compression %s", compression)
+	}
+	return blob, nil
+}
+
+// Values decodes the blob
+func (fb FieldBlob) Values() ([]float64, error) {
+	if fb.DType != blobDType {
+		return nil, fmt.Errorf("fieldblob: unsupported dtype %s", fb.DType)
+	}
+
+	raw := fb.Data
+	switch fb.Compression {
+	case BlobNoCompression:
+	case BlobZlib:
+		reader, err := zlib.NewReader(bytes.NewReader(fb.Data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		raw, err = ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("fieldblob: unknown compression %s", fb.Compression)
+	}
+
+	numNodes := pfutil.ProdInt(fb.Shape)
+	if len(raw) != 8*numNodes {
+		return nil, fmt.Errorf("fieldblob: blob has %d bytes, expected %d for shape %v", len(raw), 8*numNodes, fb.Shape)
+	}
+	values := make([]float64, numNodes)
+	for i := range values {
+		values[i] = math.Float64frombits(binary.LittleEndian.Uint64(raw[8*i:]))
+	}
+	return values, nil
+}
+
+// formatShape returns a comma separated representation of the shape
+func formatShape(shape []int) string {
+	items := make([]string, len(shape))
+	for i, s := range shape {
+		items[i] = strconv.Itoa(s)
+	}
+	return strings.Join(items, ",")
+}
+
+// parseShape parses a comma separated shape
+func parseShape(shape string) ([]int, error) {
+	result := []int{}
+	for _, item := range strings.Split(shape, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return nil, fmt.Errorf("fieldblob: invalid shape %s", shape)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
@@ -0,0 +1,224 @@
+package pf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// FieldReader is a generic interface for types that can read fields written by one of
+// the output writers. The epoch is the same as was passed to the writer. The fields can
+// be used to initialise a new model or to restart a simulation (see InitFromReader)
+type FieldReader interface {
+	ReadFields(epoch int) ([]Field, error)
+}
+
+// InitFromReader reads the fields of the given epoch and copies the data into the
+// fields of the model with the same name. Fields that are not part of the model are
+// added to the model. Thus, it can be used both to initialise a new model and to restart
+// from the output of a previous simulation. When restarting, the StartEpoch of the solver
+// should be set accordingly
+func InitFromReader(m *Model, r FieldReader, epoch int) error {
+	fields, err := r.ReadFields(epoch)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if m.IsFieldName(f.Name) {
+			target := m.Fields[m.fieldIndex(f.Name)]
+			if len(target.Data) != len(f.Data) {
+				return fmt.Errorf("fieldreader: %s has %d nodes, but %d nodes were read", f.Name, len(target.Data), len(f.Data))
+			}
+			copy(target.Data, f.Data)
+		} else {
+			m.AddField(f)
+		}
+	}
+	return nil
+}
+
+// fieldIndex returns the position of the field with the passed name in Fields. If there
+// is no such field, -1 is returned
+func (m *Model) fieldIndex(name string) int {
+	for i, f := range m.Fields {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// realField returns a new field holding the passed real data
+func realField(name string, data []float64) Field {
+	field := NewField(name, len(data), nil)
+	for i, v := range data {
+		field.Data[i] = complex(v, 0.0)
+	}
+	return field
+}
+
+// CsvReader reads fields written by CsvIO. Note that CsvIO writes the values with
+// six decimals
+type CsvReader struct {
+	Prefix string
+}
+
+// ReadFields reads all fields in <prefix>_<epoch>.csv
+func (cr *CsvReader) ReadFields(epoch int) ([]Field, error) {
+	return readCsv(fmt.Sprintf("%s_%d.csv", cr.Prefix, epoch))
+}
+
+// Float64Reader reads fields written by Float64IO. Since the binary files do not contain
+// the field names, the names of the fields to read must be given.
+type Float64Reader struct {
+	Prefix string
+	Fields []string
+}
+
+// ReadFields reads <prefix>_<field>_<epoch>.bin for all fields
+func (fr *Float64Reader) ReadFields(epoch int) ([]Field, error) {
+	fields := []Field{}
+	for _, name := range fr.Fields {
+		data, err := readFloat64(fmt.Sprintf("%s_%s_%d.bin", fr.Prefix, name, epoch), binary.BigEndian)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, realField(name, data))
+	}
+	return fields, nil
+}
+
+// Uint8Range holds the minimum and maximum value of a field stored by Uint8IO
+type Uint8Range struct {
+	Min float64
+	Max float64
+}
+
+// uint8RangeFile returns the name of the sidecar file of a binary file
+func uint8RangeFile(fname string) string {
+	return strings.TrimSuffix(fname, filepath.Ext(fname)) + ".json"
+}
+
+// writeUint8Range stores the range as JSON
+func writeUint8Range(fname string, r Uint8Range) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fname, data, 0644)
+}
+
+// Uint8Reader reads fields written by Uint8IO. The values are rescaled to the original
+// range using the JSON sidecar written together with each binary file. Since the data is
+// stored with 8 bits, the values are only accurate to (max - min)/255
+type Uint8Reader struct {
+	Prefix string
+	Fields []string
+}
+
+// ReadFields reads <prefix>_<field>_<epoch>.bin for all fields
+func (ur *Uint8Reader) ReadFields(epoch int) ([]Field, error) {
+	fields := []Field{}
+	for _, name := range ur.Fields {
+		fname := fmt.Sprintf("%s_%s_%d.bin", ur.Prefix, name, epoch)
+		raw, err := ioutil.ReadFile(fname)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := ioutil.ReadFile(uint8RangeFile(fname))
+		if err != nil {
+			return nil, fmt.Errorf("uint8reader: could not read the range of %s: %s", fname, err)
+		}
+		var rng Uint8Range
+		if err := json.Unmarshal(content, &rng); err != nil {
+			return nil, err
+		}
+
+		data := make([]float64, len(raw))
+		for i, v := range raw {
+			data[i] = rng.Min + float64(v)*(rng.Max-rng.Min)/255.0
+		}
+		fields = append(fields, realField(name, data))
+	}
+	return fields, nil
+}
+
+// NpyReader reads fields written by NpyIO. If Archive is true, all fields in the .npz file
+// are read and Fields is ignored.
+type NpyReader struct {
+	Prefix  string
+	Fields  []string
+	Archive bool
+}
+
+// ReadFields reads <prefix>_<field>_<epoch>.npy for all fields or <prefix>_<epoch>.npz
+func (nr *NpyReader) ReadFields(epoch int) ([]Field, error) {
+	if nr.Archive {
+		fields, _, err := LoadNpz(fmt.Sprintf("%s_%d.npz", nr.Prefix, epoch))
+		return fields, err
+	}
+	fields := []Field{}
+	for _, name := range nr.Fields {
+		field, _, err := LoadNpy(fmt.Sprintf("%s_%s_%d.npy", nr.Prefix, name, epoch), name)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// XDMFReader reads fields from a XDMF collection created by WriteXDMF. The epoch is the
+// position of the grid in the temporal collection. The binary files are located relative
+// to the directory of the XDMF file
+type XDMFReader struct {
+	Fname string
+}
+
+// ReadFields reads all attributes of the grid at position epoch in the collection
+func (xr *XDMFReader) ReadFields(epoch int) ([]Field, error) {
+	content, err := ioutil.ReadFile(xr.Fname)
+	if err != nil {
+		return nil, err
+	}
+	var xdmf XDMF
+	if err := xml.Unmarshal(content, &xdmf); err != nil {
+		return nil, err
+	}
+
+	grids := xdmf.Domain.Grid.Grids
+	if epoch < 0 || epoch >= len(grids) {
+		return nil, fmt.Errorf("xdmfreader: epoch %d is not in the collection of %d grids", epoch, len(grids))
+	}
+
+	dir := filepath.Dir(xr.Fname)
+	fields := []Field{}
+	for _, attr := range grids[epoch].Attributes {
+		fname := strings.TrimSpace(attr.DataItem.Value)
+		if !filepath.IsAbs(fname) {
+			fname = filepath.Join(dir, fname)
+		}
+		data, err := readXDMFDataItem(fname, attr.DataItem)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, realField(attr.Name, data))
+	}
+	return fields, nil
+}
+
+// readXDMFDataItem reads the binary data referenced by a data item
+func readXDMFDataItem(fname string, item XDMFDataItem) ([]float64, error) {
+	if item.Format != "Binary" || item.Precision != 8 {
+		return nil, fmt.Errorf("xdmfreader: only binary float64 data is supported")
+	}
+	if item.Endian == "Big" {
+		return readFloat64(fname, binary.BigEndian)
+	}
+	return readFloat64(fname, binary.LittleEndian)
+}
@@ -0,0 +1,153 @@
+package pf
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"testing"
+)
+
+// readerTestSolver returns a solver with two fields with known values
+func readerTestSolver() *Solver {
+	N := 12
+	m := NewModel()
+	conc := NewField("conc", N, nil)
+	eta := NewField("eta", N, nil)
+	for i := range conc.Data {
+		conc.Data[i] = complex(0.1*float64(i), 0.0)
+		eta.Data[i] = complex(-0.5+0.05*float64(i), 0.0)
+	}
+	m.AddField(conc)
+	m.AddField(eta)
+	m.AddEquation("dconc/dt = LAP conc")
+	m.AddEquation("deta/dt = LAP eta")
+	return NewSolver(&m, []int{4, 3}, 0.1)
+}
+
+func TestFieldReaders(t *testing.T) {
+	domainSize := []int{4, 3}
+	prefix := "fieldReaderTest"
+	files := []string{}
+	defer func() {
+		for _, f := range files {
+			os.Remove(f)
+		}
+	}()
+
+	for i, test := range []struct {
+		write  func(s *Solver)
+		reader FieldReader
+		files  []string
+		tol    float64
+	}{
+		{
+			write: func(s *Solver) {
+				io := CsvIO{Prefix: prefix, DomainSize: domainSize}
+				io.SaveFields(s, 1)
+			},
+			reader: &CsvReader{Prefix: prefix},
+			files:  []string{prefix + "_1.csv"},
+			tol:    1e-6,
+		},
+		{
+			write: func(s *Solver) {
+				io := NewFloat64IO(prefix)
+				io.SaveFields(s, 1)
+			},
+			reader: &Float64Reader{Prefix: prefix, Fields: []string{"conc", "eta"}},
+			files:  []string{prefix + "_conc_1.bin", prefix + "_eta_1.bin"},
+			tol:    1e-12,
+		},
+		{
+			write: func(s *Solver) {
+				io := NewUint8IO(prefix)
+				io.SaveFields(s, 1)
+			},
+			reader: &Uint8Reader{Prefix: prefix, Fields: []string{"conc", "eta"}},
+			files:  []string{prefix + "_conc_1.bin", prefix + "_eta_1.bin", prefix + "_conc_1.json", prefix + "_eta_1.json"},
+			tol:    1.1 / 255.0,
+		},
+		{
+			write: func(s *Solver) {
+				io := NewNpyIO(prefix, domainSize)
+				io.Archive = true
+				io.SaveFields(s, 1)
+			},
+			reader: &NpyReader{Prefix: prefix, Archive: true},
+			files:  []string{prefix + "_1.npz"},
+			tol:    1e-12,
+		},
+		{
+			write: func(s *Solver) {
+				io := NewFloat64IO(prefix)
+				io.SaveFields(s, 0)
+				io.SaveFields(s, 1)
+				WriteXDMF(prefix+".xdmf", []string{"conc", "eta"}, prefix, 2, domainSize)
+			},
+			reader: &XDMFReader{Fname: prefix + ".xdmf"},
+			files:  []string{prefix + ".xdmf", prefix + "_conc_0.bin", prefix + "_eta_0.bin", prefix + "_conc_1.bin", prefix + "_eta_1.bin"},
+			tol:    1e-12,
+		},
+	} {
+		solver := readerTestSolver()
+		test.write(solver)
+		files = append(files, test.files...)
+
+		fields, err := test.reader.ReadFields(1)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+		if len(fields) != 2 {
+			t.Errorf("Test #%d: Expected 2 fields got %d\n", i, len(fields))
+			continue
+		}
+		for j, f := range fields {
+			expect := solver.Model.Fields[j]
+			if f.Name != expect.Name {
+				t.Errorf("Test #%d: Expected name %s got %s\n", i, expect.Name, f.Name)
+			}
+			for k := range expect.Data {
+				if math.Abs(real(f.Data[k]-expect.Data[k])) > test.tol {
+					t.Errorf("Test #%d: %s: Expected %v got %v\n", i, f.Name, expect.Data, f.Data)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestInitFromReader(t *testing.T) {
+	prefix := "initFromReaderTest"
+	solver := readerTestSolver()
+	io := NewFloat64IO(prefix)
+	io.SaveFields(solver, 3)
+	for _, name := range []string{"conc", "eta"} {
+		defer os.Remove(fmt.Sprintf("%s_%s_3.bin", prefix, name))
+	}
+
+	m := NewModel()
+	m.AddField(NewField("conc", 12, nil))
+	reader := Float64Reader{Prefix: prefix, Fields: []string{"conc", "eta"}}
+	if err := InitFromReader(&m, &reader, 3); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	if len(m.Fields) != 2 {
+		t.Errorf("Expected the eta field to be added. Got %d fields\n", len(m.Fields))
+		return
+	}
+	for i := range m.Fields {
+		for j := range m.Fields[i].Data {
+			if math.Abs(real(m.Fields[i].Data[j]-solver.Model.Fields[i].Data[j])) > 1e-12 {
+				t.Errorf("Field %s was not initialised\n", m.Fields[i].Name)
+				break
+			}
+		}
+	}
+
+	// Missing files give an error
+	if err := InitFromReader(&m, &reader, 4); err == nil {
+		t.Errorf("Expected an error when the files do not exist\n")
+	}
+}
@@ -25,7 +25,9 @@ func NewUint8IO(prefix string) Uint8IO {
 }
 
 // SaveFields can be passed as a callback to the solver. It stores each
-// field in a raw binary file.
+// field in a raw binary file. The minimum and maximum value of each field are stored
+// in a JSON sidecar file with the same name and the extension .json, such that the data
+// can be rescaled when it is read back (see Uint8Reader)
 func (u *Uint8IO) SaveFields(s *Solver, epoch int) {
 	for _, f := range s.Model.Fields {
 		fname := fmt.Sprintf("%s_%s_%d.bin", u.Prefix, f.Name, epoch)
@@ -38,6 +40,10 @@ func (u *Uint8IO) SaveFields(s *Solver, epoch int) {
 		}
 		binary.Write(out, binary.BigEndian, uint8Rep)
 		out.Close()
+
+		if err := writeUint8Range(uint8RangeFile(fname), Uint8Range{Min: min, Max: max}); err != nil {
+			panic(err)
+		}
 	}
 }
 
@@ -64,22 +70,30 @@ func (fl *Float64IO) SaveFields(s *Solver, epoch int) {
 // LoadFloat64 loads an array of float64 encoded as binary data
 // it is assumed that the it is stored with BigEndian
 func LoadFloat64(fname string) []float64 {
-	infile, err := os.Open(fname)
+	data, err := readFloat64(fname, binary.BigEndian)
 	if err != nil {
 		panic(err)
 	}
+	return data
+}
+
+// readFloat64 loads an array of float64 stored with the passed byte order
+func readFloat64(fname string, order binary.ByteOrder) ([]float64, error) {
+	infile, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
 	defer infile.Close()
 
 	stats, err := infile.Stat()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	size := stats.Size()
-	bytes := make([]byte, size)
-
-	data := make([]float64, len(bytes)/8)
-	binary.Read(infile, binary.BigEndian, data)
-	return data
+	data := make([]float64, stats.Size()/8)
+	if err := binary.Read(infile, order, data); err != nil {
+		return nil, err
+	}
+	return data, nil
 }
 
 // SaveFloat64 writes a float sice to a binary file. BigEndian is used.
@@ -115,18 +129,28 @@ func (cio *CsvIO) SaveFields(s *Solver, epoch int) {
 
 // LoadCSV loads data from CSV file and returns an array of fields
 func LoadCSV(fname string) []Field {
+	fields, err := readCsv(fname)
+	if err != nil {
+		panic(err)
+	}
+	return fields
+}
+
+// readCsv loads data from a CSV file written by SaveCsv
+func readCsv(fname string) ([]Field, error) {
 	infile, err := os.Open(fname)
 	if err != nil {
-		msg := fmt.Sprintf("Could not open file: %s\n", err)
-		panic(msg)
+		return nil, fmt.Errorf("Could not open file: %s", err)
 	}
 	defer infile.Close()
 
 	reader := csv.NewReader(infile)
 	header, err := reader.Read()
 	if err != nil {
-		log.Fatalf("Could not read header: %s\n", header)
-		return nil
+		return nil, fmt.Errorf("Could not read header: %s", err)
+	}
+	if len(header) < 3 {
+		return nil, fmt.Errorf("Expected at least three columns (X, Y, Z) got %v", header)
 	}
 
 	fields := make([]Field, len(header)-3)
@@ -138,13 +162,16 @@ func LoadCSV(fname string) []Field {
 	for {
 		record, err := reader.Read()
 		if err == io.EOF {
-			return fields
+			return fields, nil
 		} else if err != nil {
-			log.Fatalf("Could not read record: %s\n", err)
+			return nil, fmt.Errorf("Could not read record: %s", err)
 		}
 
 		for i := 0; i < len(fields); i++ {
-			v, _ := strconv.ParseFloat(record[i+3], 64)
+			v, err := strconv.ParseFloat(record[i+3], 64)
+			if err != nil {
+				return nil, err
+			}
 			fields[i].Data = append(fields[i].Data, complex(v, 0.0))
 		}
 	}
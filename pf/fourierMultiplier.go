@@ -0,0 +1,111 @@
+package pf
+
+import (
+	"math"
+	"sort"
+
+	"github.com/davidkleiven/gopf/pfutil"
+)
+
+// RadialKernel is an isotropic kernel in the fourier domain. It is a function of the
+// wavenumber k = 2*pi*|f|
+type RadialKernel func(k float64) float64
+
+// NewTabulatedKernel returns a kernel that linearly interpolates the tabulated values.
+// k has to be sorted in increasing order. Outside the tabulated range, the kernel takes
+// the value at the closest end point
+func NewTabulatedKernel(k []float64, values []float64) RadialKernel {
+	if len(k) != len(values) || len(k) == 0 {
+		panic("fouriermultiplier: k and values must have the same non-zero length")
+	}
+	if !sort.Float64sAreSorted(k) {
+		panic("fouriermultiplier: k must be sorted in increasing order")
+	}
+	kCopy := make([]float64, len(k))
+	copy(kCopy, k)
+	valCopy := make([]float64, len(values))
+	copy(valCopy, values)
+
+	return func(x float64) float64 {
+		idx := sort.SearchFloat64s(kCopy, x)
+		if idx == 0 {
+			return valCopy[0]
+		} else if idx == len(kCopy) {
+			return valCopy[len(valCopy)-1]
+		}
+		w := (x - kCopy[idx-1]) / (kCopy[idx] - kCopy[idx-1])
+		return (1.0-w)*valCopy[idx-1] + w*valCopy[idx]
+	}
+}
+
+// FractionalLaplacianKernel returns the kernel -k^(2*power) of the fractional laplacian
+// -(-LAP)^power
+func FractionalLaplacianKernel(power float64) RadialKernel {
+	return func(k float64) float64 {
+		return -math.Pow(k, 2.0*power)
+	}
+}
+
+// kernelOperator returns an operator that multiplies the fourier transformed field by
+// the kernel
+func kernelOperator(kernel RadialKernel) DiffOp {
+	return func(freq Frequency, ft []complex128) []complex128 {
+		for i := range ft {
+			f := freq(i)
+			ft[i] *= complex(kernel(2.0*math.Pi*math.Sqrt(pfutil.Dot(f, f))), 0.0)
+		}
+		return ft
+	}
+}
+
+// FourierMultiplier implements a term that in the fourier domain is given by
+// Prefactor*K(|k|)*<field>, where K is an isotropic kernel and field is the name of a field.
+// Field is only used when the term is treated explicitly. When used in a model, the term
+// should be registered as an implicit term, and can then be referred to by its name
+// in the equations
+//
+//	kernel := pf.FourierMultiplier{Kernel: pf.FractionalLaplacianKernel(0.75), Prefactor: 1.0}
+//	model.RegisterImplicitTerm("NONLOCAL", &kernel, nil)
+//	model.AddEquation("dconc/dt = NONLOCAL")
+//
+// Alternatively, the kernel can be registered by name with Model.RegisterKernel and
+// applied to any expression in the equations (e.g. dconc/dt = NONLOCAL conc)
+type FourierMultiplier struct {
+	Kernel    RadialKernel
+	Field     string
+	Prefactor float64
+}
+
+// evaluate returns the prefactor times the kernel at the passed frequency
+func (fm *FourierMultiplier) evaluate(f []float64) complex128 {
+	k := 2.0 * math.Pi * math.Sqrt(pfutil.Dot(f, f))
+	return complex(fm.Prefactor*fm.Kernel(k), 0.0)
+}
+
+// Construct builds the rhs required to represent the term
+func (fm *FourierMultiplier) Construct(bricks map[string]Brick) Term {
+	return func(freq Frequency, t float64, out []complex128) {
+		for i := range out {
+			out[i] = fm.evaluate(freq(i))
+		}
+	}
+}
+
+// OnStepFinished is simply included to satisfy the UserDefinedTerm interface
+func (fm *FourierMultiplier) OnStepFinished(t float64, bricks map[string]Brick) {}
+
+// ExplicitFourierMultiplier is the explicit variant of FourierMultiplier. The term
+// should be registered as an explicit term
+type ExplicitFourierMultiplier struct {
+	FourierMultiplier
+}
+
+// Construct returns a function that evaluates the RHS of the PDE
+func (efm *ExplicitFourierMultiplier) Construct(bricks map[string]Brick) Term {
+	return func(freq Frequency, t float64, out []complex128) {
+		brick := bricks[efm.Field]
+		for i := range out {
+			out[i] = efm.evaluate(freq(i)) * brick.Get(i)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package pf
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTabulatedKernel(t *testing.T) {
+	kernel := NewTabulatedKernel([]float64{0.0, 1.0, 3.0}, []float64{1.0, 2.0, 0.0})
+	for i, test := range []struct {
+		k      float64
+		expect float64
+	}{
+		{k: -1.0, expect: 1.0},
+		{k: 0.0, expect: 1.0},
+		{k: 0.5, expect: 1.5},
+		{k: 2.0, expect: 1.0},
+		{k: 5.0, expect: 0.0},
+	} {
+		if v := kernel(test.k); math.Abs(v-test.expect) > 1e-10 {
+			t.Errorf("Test #%d: Expected %f got %f\n", i, test.expect, v)
+		}
+	}
+}
+
+func TestFractionalLaplacianSolver(t *testing.T) {
+	N := 32
+	dt := 0.1
+	power := 0.75
+	k := 2.0 * math.Pi / float64(N)
+	kernel := -math.Pow(k, 2.0*power)
+
+	for i, test := range []struct {
+		setup   func(m *Model)
+		damping float64
+	}{
+		{
+			setup: func(m *Model) {
+				m.AddEquation("dconc/dt = FLAP^0.75 conc")
+			},
+			damping: 1.0 / (1.0 - dt*kernel),
+		},
+		{
+			setup: func(m *Model) {
+				m.AddEquation("dconc/dt = LAP^0.75 conc")
+			},
+			damping: 1.0 / (1.0 - dt*kernel),
+		},
+		{
+			setup: func(m *Model) {
+				m.RegisterKernel("NONLOCAL", FractionalLaplacianKernel(power))
+				m.AddEquation("dconc/dt = NONLOCAL conc")
+			},
+			damping: 1.0 / (1.0 - dt*kernel),
+		},
+		{
+			setup: func(m *Model) {
+				term := FourierMultiplier{Kernel: FractionalLaplacianKernel(power), Prefactor: 1.0}
+				m.RegisterImplicitTerm("NONLOCAL", &term, nil)
+				m.AddEquation("dconc/dt = NONLOCAL")
+			},
+			damping: 1.0 / (1.0 - dt*kernel),
+		},
+		{
+			setup: func(m *Model) {
+				term := ExplicitFourierMultiplier{
+					FourierMultiplier: FourierMultiplier{
+						Kernel:    FractionalLaplacianKernel(power),
+						Field:     "conc",
+						Prefactor: 1.0,
+					},
+				}
+				m.RegisterExplicitTerm("NONLOCAL", &term, nil)
+				m.AddEquation("dconc/dt = NONLOCAL")
+			},
+			damping: 1.0 + dt*kernel,
+		},
+	} {
+		m := NewModel()
+		conc := NewField("conc", N, nil)
+		for j := range conc.Data {
+			conc.Data[j] = complex(math.Cos(k*float64(j)), 0.0)
+		}
+		m.AddField(conc)
+		test.setup(&m)
+
+		solver := NewSolver(&m, []int{N}, dt)
+		solver.Solve(1, 1)
+
+		for j := range conc.Data {
+			expect := test.damping * math.Cos(k*float64(j))
+			if math.Abs(real(conc.Data[j])-expect) > 1e-10 {
+				t.Errorf("Test #%d: Node %d: Expected %f got %f\n", i, j, expect, real(conc.Data[j]))
+			}
+		}
+	}
+}
+
+func TestKernelOnNonLinearTerm(t *testing.T) {
+	N := 32
+	dt := 0.1
+	k := 2.0 * math.Pi / float64(N)
+
+	m := NewModel()
+	conc := NewField("conc", N, nil)
+	for j := range conc.Data {
+		conc.Data[j] = complex(math.Cos(k*float64(j)), 0.0)
+	}
+	m.AddField(conc)
+	m.RegisterKernel("NONLOCAL", FractionalLaplacianKernel(0.75))
+	m.AddEquation("dconc/dt = NONLOCAL*conc^2")
+
+	solver := NewSolver(&m, []int{N}, dt)
+	solver.Solve(1, 1)
+
+	// cos^2 = (1 + cos(2kx))/2, and the kernel vanishes at k = 0
+	for j := range conc.Data {
+		x := k * float64(j)
+		expect := math.Cos(x) - dt*0.5*math.Pow(2.0*k, 1.5)*math.Cos(2.0*x)
+		if math.Abs(real(conc.Data[j])-expect) > 1e-10 {
+			t.Errorf("Node %d: Expected %f got %f\n", j, expect, real(conc.Data[j]))
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package pf
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/davidkleiven/gopf/pfutil"
+)
+
+// BulkTerm represents a local free energy density f(c_1, c_2, ...) that depends on
+// the values of the fields listed in Fields. Density is passed the values of the fields
+// in the same order as in Fields. Deriv should return the partial derivatives with respect
+// to each of the fields. If Deriv is nil, the derivatives are obtained via central finite
+// differences of Density
+type BulkTerm struct {
+	Fields  []string
+	Density func(values []float64) float64
+	Deriv   func(values []float64) []float64
+}
+
+// partialDeriv returns the derivative of the density with respect to field number j
+func (b *BulkTerm) partialDeriv(values []float64, j int) float64 {
+	if b.Deriv != nil {
+		return b.Deriv(values)[j]
+	}
+	orig := values[j]
+	h := 1e-6 * math.Max(1.0, math.Abs(orig))
+	values[j] = orig + h
+	fp := b.Density(values)
+	values[j] = orig - h
+	fm := b.Density(values)
+	values[j] = orig
+	return (fp - fm) / (2.0 * h)
+}
+
+// fieldIndex returns the position of field in Fields. If the term does not depend on
+// the field, -1 is returned
+func (b *BulkTerm) fieldIndex(field string) int {
+	for i, f := range b.Fields {
+		if f == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// values returns the real part of the fields at node i
+func (b *BulkTerm) values(i int, bricks map[string]Brick) []float64 {
+	values := make([]float64, len(b.Fields))
+	for j, f := range b.Fields {
+		values[j] = real(bricks[f].Get(i))
+	}
+	return values
+}
+
+// GradientTerm represents the energy density 0.5*K_ij dc/dx_i dc/dx_j, where c is the
+// field given by Field. If K has length one, the term is isotropic (e.g. K_ij = K*delta_ij).
+// Otherwise, K is the full (row-major) 2x2 or 3x3 coefficient tensor, in the same way as
+// for TensorialHessian
+type GradientTerm struct {
+	Field string
+	K     []float64
+}
+
+// coeff returns the i, j element of the coefficient tensor
+func (g *GradientTerm) coeff(i, j int) float64 {
+	if len(g.K) == 1 {
+		if i == j {
+			return g.K[0]
+		}
+		return 0.0
+	}
+	th := TensorialHessian{K: g.K}
+	return th.GetCoeff(i, j)
+}
+
+// symbol returns K_ij k_i k_j, where k = 2*pi*f
+func (g *GradientTerm) symbol(f []float64) float64 {
+	value := 0.0
+	for i := range f {
+		for j := range f {
+			value += 4.0 * math.Pi * math.Pi * f[i] * f[j] * g.coeff(i, j)
+		}
+	}
+	return value
+}
+
+// NonlocalTerm represents the energy
+//
+// 0.5*Prefactor*int int c(r) K(|r - r'|) c(r') dr dr'
+//
+// where c is the field given by Field and Kernel is the fourier transform of K
+type NonlocalTerm struct {
+	Field     string
+	Kernel    RadialKernel
+	Prefactor float64
+}
+
+// symbol returns the prefactor times the kernel
+func (n *NonlocalTerm) symbol(f []float64) float64 {
+	k := 2.0 * math.Pi * math.Sqrt(pfutil.Dot(f, f))
+	return n.Prefactor * n.Kernel(k)
+}
+
+// FreeEnergy represents a free energy functional consisting of bulk terms, gradient
+// terms and nonlocal terms. The evolution equations are obtained from the variational
+// derivative of the functional. Allen-Cahn (non-conserved) dynamics is given by
+//
+// dc/dt = -M*dF/dc
+//
+// and Cahn-Hilliard (conserved) dynamics is given by
+//
+// dc/dt = M*LAP dF/dc
+//
+// where M is the mobility. The contributions from the gradient and nonlocal terms are
+// linear in the field and are treated implicitly, while the bulk terms are treated explicitly.
+// The fields has to be added to the model before the equations are generated.
+//
+//	fe := pf.FreeEnergy{
+//		Bulk:     []pf.BulkTerm{{Fields: []string{"conc"}, Density: doubleWell}},
+//		Gradient: []pf.GradientTerm{{Field: "conc", K: []float64{1.0}}},
+//	}
+//	fe.CahnHilliard(&model, "conc", 1.0)
+type FreeEnergy struct {
+	Bulk     []BulkTerm
+	Gradient []GradientTerm
+	Nonlocal []NonlocalTerm
+}
+
+// AllenCahn adds the Allen-Cahn equation for the passed field to the model
+func (fe *FreeEnergy) AllenCahn(m *Model, field string, mobility float64) {
+	fe.addEquation(m, field, mobility, false)
+}
+
+// CahnHilliard adds the Cahn-Hilliard equation for the passed field to the model
+func (fe *FreeEnergy) CahnHilliard(m *Model, field string, mobility float64) {
+	fe.addEquation(m, field, mobility, true)
+}
+
+// addEquation registers the terms required to represent the equation of the
+// passed field and adds the equation to the model
+func (fe *FreeEnergy) addEquation(m *Model, field string, mobility float64, conserved bool) {
+	if !m.IsFieldName(field) {
+		panic(fmt.Sprintf("freeenergy: %s is not a field in the model", field))
+	}
+	bulkName := fmt.Sprintf("FREE_ENERGY_BULK_%s", field)
+	linearName := fmt.Sprintf("FREE_ENERGY_LINEAR_%s", field)
+	bulk := freeEnergyBulkTerm{
+		FreeEnergy: fe,
+		Field:      field,
+		Mobility:   mobility,
+		Conserved:  conserved,
+	}
+	linear := freeEnergyLinearTerm{
+		FreeEnergy: fe,
+		Field:      field,
+		Mobility:   mobility,
+		Conserved:  conserved,
+	}
+	m.RegisterExplicitTerm(bulkName, &bulk, []DerivedField{bulk.DerivedField(m.NumNodes(), m.Bricks)})
+	m.RegisterImplicitTerm(linearName, &linear, nil)
+	m.AddEquation(fmt.Sprintf("d%s/dt = %s + %s", field, bulkName, linearName))
+}
+
+// BulkDeriv returns the derivative of the bulk energy density with respect to the passed
+// field at node i. The fields in bricks should be the real space representation
+func (fe *FreeEnergy) BulkDeriv(field string, i int, bricks map[string]Brick) float64 {
+	deriv := 0.0
+	for j := range fe.Bulk {
+		idx := fe.Bulk[j].fieldIndex(field)
+		if idx == -1 {
+			continue
+		}
+		deriv += fe.Bulk[j].partialDeriv(fe.Bulk[j].values(i, bricks), idx)
+	}
+	return deriv
+}
+
+// linearSymbol returns the fourier representation of the variational derivative of the
+// gradient and nonlocal terms with respect to the passed field (apart from the field itself)
+func (fe *FreeEnergy) linearSymbol(field string, f []float64) float64 {
+	value := 0.0
+	for i := range fe.Gradient {
+		if fe.Gradient[i].Field == field {
+			value += fe.Gradient[i].symbol(f)
+		}
+	}
+	for i := range fe.Nonlocal {
+		if fe.Nonlocal[i].Field == field {
+			value += fe.Nonlocal[i].symbol(f)
+		}
+	}
+	return value
+}
+
+// GetEnergy evaluates the total free energy. The fields in bricks should be the real
+// space representation. ft is used to evaluate the gradient and nonlocal contributions
+func (fe *FreeEnergy) GetEnergy(bricks map[string]Brick, ft FourierTransform, domainSize []int) float64 {
+	numNodes := pfutil.ProdInt(domainSize)
+	energy := 0.0
+	for i := range fe.Bulk {
+		for j := 0; j < numNodes; j++ {
+			energy += fe.Bulk[i].Density(fe.Bulk[i].values(j, bricks))
+		}
+	}
+
+	fields := make(map[string]bool)
+	for _, g := range fe.Gradient {
+		fields[g.Field] = true
+	}
+	for _, n := range fe.Nonlocal {
+		fields[n.Field] = true
+	}
+
+	// By Parseval's theorem, the gradient and nonlocal contributions can be evaluated
+	// as 0.5*sum_k symbol(k)*|c_k|^2/N
+	data := make([]complex128, numNodes)
+	for field := range fields {
+		b := bricks[field]
+		for i := range data {
+			data[i] = b.Get(i)
+		}
+		ft.FFT(data)
+		for i := range data {
+			v := data[i]
+			power := real(v)*real(v) + imag(v)*imag(v)
+			energy += 0.5 * fe.linearSymbol(field, ft.Freq(i)) * power / float64(numNodes)
+		}
+	}
+	return energy
+}
+
+// freeEnergyBulkTerm is the explicit term representing the bulk contribution to the
+// equation of Field
+type freeEnergyBulkTerm struct {
+	FreeEnergy *FreeEnergy
+	Field      string
+	Mobility   float64
+	Conserved  bool
+}
+
+// derivedFieldName returns the name of the derived field holding the bulk derivative
+func (fb *freeEnergyBulkTerm) derivedFieldName() string {
+	return fmt.Sprintf("FREE_ENERGY_BULK_DERIV_%s", fb.Field)
+}
+
+// DerivedField returns the derived field that holds the derivative of the bulk energy
+// density multiplied by the mobility
+func (fb *freeEnergyBulkTerm) DerivedField(numNodes int, bricks map[string]Brick) DerivedField {
+	return DerivedField{
+		Name: fb.derivedFieldName(),
+		Data: make([]complex128, numNodes),
+		Calc: func(out []complex128) {
+			for i := range out {
+				out[i] = complex(fb.Mobility*fb.FreeEnergy.BulkDeriv(fb.Field, i, bricks), 0.0)
+			}
+		},
+	}
+}
+
+// Construct returns the fourier transformed contribution from the bulk terms
+func (fb *freeEnergyBulkTerm) Construct(bricks map[string]Brick) Term {
+	return func(freq Frequency, t float64, field []complex128) {
+		b := bricks[fb.derivedFieldName()]
+		for i := range field {
+			field[i] = -b.Get(i)
+		}
+		if fb.Conserved {
+			lap := LaplacianN{Power: 1}
+			flipSign(field)
+			lap.Eval(freq, field)
+		}
+	}
+}
+
+// OnStepFinished does nothing
+func (fb *freeEnergyBulkTerm) OnStepFinished(t float64, bricks map[string]Brick) {}
+
+// freeEnergyLinearTerm is the implicit term representing the gradient and nonlocal
+// contributions to the equation of Field
+type freeEnergyLinearTerm struct {
+	FreeEnergy *FreeEnergy
+	Field      string
+	Mobility   float64
+	Conserved  bool
+}
+
+// Construct returns the fourier representation of the implicit term
+func (fl *freeEnergyLinearTerm) Construct(bricks map[string]Brick) Term {
+	return func(freq Frequency, t float64, field []complex128) {
+		for i := range field {
+			f := freq(i)
+			value := fl.Mobility * fl.FreeEnergy.linearSymbol(fl.Field, f)
+			if fl.Conserved {
+				value *= 4.0 * math.Pi * math.Pi * pfutil.Dot(f, f)
+			}
+			field[i] = complex(-value, 0.0)
+		}
+	}
+}
+
+// OnStepFinished does nothing
+func (fl *freeEnergyLinearTerm) OnStepFinished(t float64, bricks map[string]Brick) {}
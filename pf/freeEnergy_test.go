@@ -0,0 +1,120 @@
+package pf
+
+import (
+	"math"
+	"testing"
+
+	"github.com/davidkleiven/gopf/pfutil"
+)
+
+func TestBulkTermNumericalDerivative(t *testing.T) {
+	bulk := BulkTerm{
+		Fields: []string{"conc", "eta"},
+		Density: func(v []float64) float64 {
+			return v[0]*v[0]*v[1] + math.Sin(v[1])
+		},
+	}
+	values := []float64{0.3, 0.7}
+	if d := bulk.partialDeriv(values, 0); math.Abs(d-2.0*0.3*0.7) > 1e-8 {
+		t.Errorf("Expected %f got %f\n", 2.0*0.3*0.7, d)
+	}
+	if d := bulk.partialDeriv(values, 1); math.Abs(d-(0.09+math.Cos(0.7))) > 1e-8 {
+		t.Errorf("Expected %f got %f\n", 0.09+math.Cos(0.7), d)
+	}
+}
+
+func TestFreeEnergyEquations(t *testing.T) {
+	N := 32
+	dt := 0.1
+	mobility := 0.5
+	a := 0.8
+	kappa := 2.0
+	k := 2.0 * math.Pi / float64(N)
+	quadratic := BulkTerm{
+		Fields:  []string{"conc"},
+		Density: func(v []float64) float64 { return 0.5 * a * v[0] * v[0] },
+	}
+	gradient := GradientTerm{Field: "conc", K: []float64{kappa}}
+
+	for i, test := range []struct {
+		fe        FreeEnergy
+		conserved bool
+		factor    float64
+	}{
+		// Bulk terms are explicit
+		{
+			fe:     FreeEnergy{Bulk: []BulkTerm{quadratic}},
+			factor: 1.0 - dt*mobility*a,
+		},
+		{
+			fe:        FreeEnergy{Bulk: []BulkTerm{quadratic}},
+			conserved: true,
+			factor:    1.0 - dt*mobility*a*k*k,
+		},
+		// Gradient terms are implicit
+		{
+			fe:     FreeEnergy{Gradient: []GradientTerm{gradient}},
+			factor: 1.0 / (1.0 + dt*mobility*kappa*k*k),
+		},
+		{
+			fe:        FreeEnergy{Gradient: []GradientTerm{gradient}},
+			conserved: true,
+			factor:    1.0 / (1.0 + dt*mobility*kappa*k*k*k*k),
+		},
+		{
+			fe: FreeEnergy{
+				Nonlocal: []NonlocalTerm{{Field: "conc", Kernel: func(k float64) float64 { return 1.0 }, Prefactor: a}},
+			},
+			factor: 1.0 / (1.0 + dt*mobility*a),
+		},
+	} {
+		m := NewModel()
+		conc := NewField("conc", N, nil)
+		for j := range conc.Data {
+			conc.Data[j] = complex(math.Cos(k*float64(j)), 0.0)
+		}
+		m.AddField(conc)
+		if test.conserved {
+			test.fe.CahnHilliard(&m, "conc", mobility)
+		} else {
+			test.fe.AllenCahn(&m, "conc", mobility)
+		}
+
+		solver := NewSolver(&m, []int{N}, dt)
+		solver.Solve(1, 1)
+		for j := range conc.Data {
+			expect := test.factor * math.Cos(k*float64(j))
+			if math.Abs(real(conc.Data[j])-expect) > 1e-8 {
+				t.Errorf("Test #%d: Node %d: Expected %f got %f\n", i, j, expect, real(conc.Data[j]))
+			}
+		}
+	}
+}
+
+func TestFreeEnergyGetEnergy(t *testing.T) {
+	N := 16
+	k := 2.0 * math.Pi / float64(N)
+	conc := NewField("conc", N*N, nil)
+	for i := range conc.Data {
+		pos := pfutil.Pos([]int{N, N}, i)
+		conc.Data[i] = complex(math.Cos(k*float64(pos[1])), 0.0)
+	}
+	bricks := map[string]Brick{"conc": &conc}
+
+	fe := FreeEnergy{
+		Bulk: []BulkTerm{{
+			Fields:  []string{"conc"},
+			Density: func(v []float64) float64 { return 0.5 * v[0] * v[0] },
+		}},
+		Gradient: []GradientTerm{{Field: "conc", K: []float64{2.0, 0.0, 0.0, 1.0}}},
+		Nonlocal: []NonlocalTerm{{Field: "conc", Kernel: func(k float64) float64 { return 1.0 }, Prefactor: 3.0}},
+	}
+
+	// Average of cos^2 and sin^2 is 1/2. The field only varies along the second axis
+	numNodes := float64(N * N)
+	expect := 0.25*numNodes + 0.25*k*k*numNodes + 0.75*numNodes
+	energy := fe.GetEnergy(bricks, pfutil.NewFFTW([]int{N, N}), []int{N, N})
+	if math.Abs(energy-expect) > 1e-8 {
+		t.Errorf("Expected %f got %f\n", expect, energy)
+	}
+}
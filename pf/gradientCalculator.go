@@ -14,10 +14,22 @@ type GradientCalculator struct {
 	KeepNyquist bool
 }
 
+// Differentiator is an interface that is implemented by fourier transforms that
+// can not represent the gradient as a multiplication in the transformed domain
+// (see for example pfutil.R2RTransform). Derivative should calculate the derivative
+// of the real space data along the passed axis and place the result in data
+type Differentiator interface {
+	Derivative(data []complex128, axis int) []complex128
+}
+
 // Calculate calculates the gradient of the data passed
 // data contain the field in real-space
 func (g *GradientCalculator) Calculate(indata []complex128, data []complex128) {
 	copy(data, indata)
+	if d, ok := g.FT.(Differentiator); ok {
+		d.Derivative(data, g.Comp)
+		return
+	}
 	g.FT.FFT(data)
 	for i := range data {
 		f := g.FT.Freq(i)[g.Comp]
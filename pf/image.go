@@ -0,0 +1,154 @@
+package pf
+
+import (
+	"fmt"
+	"image"
+	"sort"
+
+	"github.com/davidkleiven/gopf/pfutil"
+)
+
+// ImageIO renders fields to PNG images, using the same layout as the contour command.
+// The frames of each field are collected and written as an animated GIF
+// (<prefix>_<field>.gif) when Close is called after the simulation. The PNG images are
+// written after each epoch, such that the evolution of the microstructure can be
+// inspected while the simulation is running. If a run is interrupted before Close, no
+// animation is written, but the images of all completed epochs are kept. For three
+// dimensional domains, the plane where the coordinate along the axis SliceNormal equals
+// SlicePos is rendered.
+//
+//	img := pf.NewImageIO("run", domainSize)
+//	img.ColorMap = "blackbody"
+//	solver.AddErrCallback(img.SaveFields)
+//	if err := solver.Solve(nepochs, nsteps); err != nil {
+//		log.Fatal(err)
+//	}
+//	img.Close()
+//
+// If Max > Min the color range is fixed, otherwise it is set from the data in each frame.
+// Delay is the time between frames in the GIF in 100ths of a second.
+type ImageIO struct {
+	Prefix      string
+	DomainSize  []int
+	Fields      []string
+	ColorMap    string
+	Min         float64
+	Max         float64
+	SliceNormal int
+	SlicePos    int
+	Delay       int
+	frames      map[string][]*image.Paletted
+}
+
+// NewImageIO returns a new ImageIO that renders all fields with the default colormap and
+// an automatic color range. For three dimensional domains, the default slice is the
+// plane in the middle of the domain normal to the z-axis
+func NewImageIO(prefix string, domainSize []int) ImageIO {
+	img := ImageIO{
+		Prefix:     prefix,
+		DomainSize: domainSize,
+		ColorMap:   "kindlmann",
+		Delay:      20,
+		frames:     make(map[string][]*image.Paletted),
+	}
+	if len(domainSize) == 3 {
+		img.SliceNormal = 2
+		img.SlicePos = domainSize[2] / 2
+	}
+	return img
+}
+
+// SaveFields renders the selected fields (all fields if Fields is empty) to
+// <prefix>_<field>_<epoch>.png and adds the frames to the animations. It satisfies the
+// SolverErrCB type, and can thus be attached to a solver via AddErrCallback. Failures
+// (e.g. an unknown field, an unknown colormap or a failed write) are returned, such that
+// the solver stops with an error instead of panicking
+func (ii *ImageIO) SaveFields(s *Solver, epoch int) error {
+	names, err := ii.fieldNames(s.Model)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		field := s.Model.Fields[s.Model.fieldIndex(name)]
+		title := fmt.Sprintf("%s (t = %.3g)", name, s.Stepper.GetTime())
+		fname := fmt.Sprintf("%s_%s_%d.png", ii.Prefix, name, epoch)
+		if err := ii.AddFrame(name, field.realPart(), title, fname); err != nil {
+			return fmt.Errorf("imageio: could not render %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Close writes the animation of each field to <prefix>_<field>.gif and releases the
+// frames
+func (ii *ImageIO) Close() error {
+	names := []string{}
+	for name := range ii.frames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := ii.SaveGIF(name, fmt.Sprintf("%s_%s.gif", ii.Prefix, name)); err != nil {
+			return err
+		}
+		delete(ii.frames, name)
+	}
+	return nil
+}
+
+// fieldNames returns the names of the fields to render
+func (ii *ImageIO) fieldNames(m *Model) ([]string, error) {
+	if len(ii.Fields) > 0 {
+		for _, name := range ii.Fields {
+			if !m.IsFieldName(name) {
+				return nil, fmt.Errorf("imageio: unknown field %s", name)
+			}
+		}
+		return ii.Fields, nil
+	}
+	names := make([]string, len(m.Fields))
+	for i, f := range m.Fields {
+		names[i] = f.Name
+	}
+	return names, nil
+}
+
+// AddFrame renders the data (or a slice of it for three dimensional domains) as a heat
+// map and adds it to the animation of the passed field. If fname is not empty, the
+// image is also stored as PNG.
+func (ii *ImageIO) AddFrame(name string, data []float64, title string, fname string) error {
+	heatMap, err := pfutil.NewHeatMap(data, ii.DomainSize, ii.SliceNormal, ii.SlicePos)
+	if err != nil {
+		return err
+	}
+	cmap, err := pfutil.NewColorMap(ii.ColorMap)
+	if err != nil {
+		return err
+	}
+	pfutil.SetColorRange(cmap, heatMap, ii.Min, ii.Max)
+
+	canvas, err := pfutil.RenderHeatMap(heatMap, cmap, title)
+	if err != nil {
+		return err
+	}
+	if fname != "" {
+		if err := pfutil.SavePNG(fname, canvas); err != nil {
+			return err
+		}
+	}
+	if ii.frames == nil {
+		ii.frames = make(map[string][]*image.Paletted)
+	}
+	ii.frames[name] = append(ii.frames[name], pfutil.PalettedImage(canvas.Image(), pfutil.GifPalette(cmap)))
+	return nil
+}
+
+// NumFrames returns the number of frames in the animation of the passed field
+func (ii *ImageIO) NumFrames(name string) int {
+	return len(ii.frames[name])
+}
+
+// SaveGIF stores all frames of the passed field as an animated GIF
+func (ii *ImageIO) SaveGIF(name string, fname string) error {
+	return pfutil.SaveGIF(fname, ii.frames[name], ii.Delay)
+}
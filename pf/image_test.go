@@ -0,0 +1,107 @@
+package pf
+
+import (
+	"fmt"
+	"image/gif"
+	"image/png"
+	"os"
+	"testing"
+)
+
+func TestImageIO(t *testing.T) {
+	for i, domainSize := range [][]int{{8, 6}, {4, 6, 5}} {
+		N := 1
+		for _, n := range domainSize {
+			N *= n
+		}
+		model := NewModel()
+		conc := NewField("conc", N, nil)
+		for j := range conc.Data {
+			conc.Data[j] = complex(float64(j%7), 0.0)
+		}
+		model.AddField(conc)
+		model.AddField(NewField("eta", N, nil))
+		model.AddEquation("dconc/dt = LAP conc")
+		model.AddEquation("deta/dt = LAP eta")
+
+		prefix := fmt.Sprintf("imageio_test%d", i)
+		imgIO := NewImageIO(prefix, domainSize)
+		imgIO.Fields = []string{"conc"}
+		solver := NewSolver(&model, domainSize, 0.01)
+		solver.AddErrCallback(imgIO.SaveFields)
+		if err := solver.Solve(3, 1); err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+		}
+
+		fname := prefix + "_conc.gif"
+		if _, err := os.Stat(fname); err == nil {
+			t.Errorf("Test #%d: the animation should not be written before Close\n", i)
+		}
+		if err := imgIO.Close(); err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+		}
+		if n := imgIO.NumFrames("conc"); n != 0 {
+			t.Errorf("Test #%d: Expected frames to be released got %d\n", i, n)
+		}
+
+		for epoch := 0; epoch < 3; epoch++ {
+			fname := fmt.Sprintf("%s_conc_%d.png", prefix, epoch)
+			infile, err := os.Open(fname)
+			if err != nil {
+				t.Errorf("Test #%d: %s\n", i, err)
+				continue
+			}
+			if _, err := png.Decode(infile); err != nil {
+				t.Errorf("Test #%d: %s\n", i, err)
+			}
+			infile.Close()
+			os.Remove(fname)
+		}
+
+		if _, err := os.Stat(fmt.Sprintf("%s_eta_0.png", prefix)); err == nil {
+			t.Errorf("Test #%d: eta should not be rendered\n", i)
+		}
+
+		infile, err := os.Open(fname)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+		anim, err := gif.DecodeAll(infile)
+		infile.Close()
+		os.Remove(fname)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+		if len(anim.Image) != 3 {
+			t.Errorf("Test #%d: Expected 3 frames got %d\n", i, len(anim.Image))
+		}
+	}
+}
+
+func TestImageIOErrorStopsSolver(t *testing.T) {
+	domainSize := []int{8, 6}
+	for i, test := range []struct {
+		prefix   string
+		colorMap string
+		fields   []string
+	}{
+		{prefix: "imageio_err", colorMap: "unknownColormap"},
+		{prefix: "imageio_err", colorMap: "kindlmann", fields: []string{"unknown"}},
+		{prefix: "/nonexistent/dir/imageio_err", colorMap: "kindlmann"},
+	} {
+		model := NewModel()
+		model.AddField(NewField("conc", 48, nil))
+		model.AddEquation("dconc/dt = LAP conc")
+
+		imgIO := NewImageIO(test.prefix, domainSize)
+		imgIO.ColorMap = test.colorMap
+		imgIO.Fields = test.fields
+		solver := NewSolver(&model, domainSize, 0.01)
+		solver.AddErrCallback(imgIO.SaveFields)
+		if err := solver.Solve(2, 1); err == nil {
+			t.Errorf("Test #%d: Expected the error to be returned from Solve\n", i)
+		}
+	}
+}
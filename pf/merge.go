@@ -0,0 +1,259 @@
+package pf
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MergeResult summarizes the outcome of MergeDatabase
+// - SimIDs: Maps the simulation IDs in the source database to the IDs in the destination
+// - Rows: Number of rows copied to each table
+type MergeResult struct {
+	SimIDs map[int]int
+	Rows   map[string]int64
+}
+
+// MergeDatabase copies all simulations from the database at srcPath into dst in one
+// transaction. The schema of dst is upgraded to the latest version (see Migrate), while
+// the source database is not modified. Every simulation gets a new ID allocated by dst.
+// Hence, IDs of simulations that were deleted from dst are never reused, and references
+// to them can not point to a merged simulation. The domain size of each simulation is
+// preserved, such that databases with different domain sizes can be merged. An error is
+// returned if srcPath is the file of dst.
+func MergeDatabase(dst *sql.DB, srcPath string) (MergeResult, error) {
+	result := MergeResult{
+		SimIDs: make(map[int]int),
+		Rows:   make(map[string]int64),
+	}
+	srcInfo, err := os.Stat(srcPath)
+	if err != nil {
+		return result, err
+	}
+	dstPath, err := mainDatabaseFile(dst)
+	if err != nil {
+		return result, err
+	}
+	if dstPath != "" {
+		if dstInfo, err := os.Stat(dstPath); err == nil && os.SameFile(srcInfo, dstInfo) {
+			return result, fmt.Errorf("merge %s: the source is the destination database", srcPath)
+		}
+	}
+	if _, err := Migrate(dst); err != nil {
+		return result, err
+	}
+
+	// ATTACH is only valid for one connection and can not be run inside a transaction.
+	// Thus, a dedicated connection is used
+	ctx := context.Background()
+	conn, err := dst.Conn(ctx)
+	if err != nil {
+		return result, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE ? AS src", srcPath); err != nil {
+		return result, err
+	}
+	defer conn.ExecContext(ctx, "DETACH DATABASE src")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	if err := mergeTx(tx, &result); err != nil {
+		tx.Rollback()
+		return MergeResult{SimIDs: make(map[int]int), Rows: make(map[string]int64)}, fmt.Errorf("merge %s: %s", srcPath, err)
+	}
+	return result, tx.Commit()
+}
+
+// mergeTx copies all data from the attached database src to main
+func mergeTx(tx *sql.Tx, result *MergeResult) error {
+	srcTables, err := schemaTables(tx, "src")
+	if err != nil {
+		return err
+	}
+	if !srcTables["simIDs"] {
+		return fmt.Errorf("the source has no simIDs table")
+	}
+	if err := mergePositions(tx, srcTables); err != nil {
+		return err
+	}
+
+	// Allocate simulation IDs
+	rows, err := tx.Query("SELECT simID, creationTime FROM src.simIDs WHERE simID IS NOT NULL ORDER BY simID")
+	if err != nil {
+		return err
+	}
+	type simulation struct {
+		id           int
+		creationTime sql.NullString
+	}
+	simulations := []simulation{}
+	for rows.Next() {
+		var s simulation
+		if err := rows.Scan(&s.id, &s.creationTime); err != nil {
+			rows.Close()
+			return err
+		}
+		simulations = append(simulations, s)
+	}
+	rows.Close()
+
+	if _, err := tx.Exec("CREATE TEMP TABLE IF NOT EXISTS simIDMap (old INTEGER, new INTEGER)"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM temp.simIDMap"); err != nil {
+		return err
+	}
+	for _, s := range simulations {
+		res, err := tx.Exec("INSERT INTO main.simIDs (creationTime) VALUES (?)", s.creationTime)
+		if err != nil {
+			return err
+		}
+		newID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO temp.simIDMap (old, new) VALUES (?, ?)", s.id, newID); err != nil {
+			return err
+		}
+		result.SimIDs[s.id] = int(newID)
+	}
+	result.Rows["simIDs"] = int64(len(simulations))
+
+	// Copy all tables that refer to a simulation
+	dstTables, err := schemaTables(tx, "main")
+	if err != nil {
+		return err
+	}
+	for table := range srcTables {
+		if table == "simIDs" || !dstTables[table] {
+			continue
+		}
+		srcCols, _, err := tableColumns(tx, "src", table)
+		if err != nil {
+			return err
+		}
+		if !srcCols["simID"] {
+			continue
+		}
+		dstCols, primaryKey, err := tableColumns(tx, "main", table)
+		if err != nil {
+			return err
+		}
+
+		columns := []string{}
+		selected := []string{}
+		for col := range srcCols {
+			if !dstCols[col] || col == primaryKey {
+				continue
+			}
+			columns = append(columns, col)
+			if col == "simID" {
+				selected = append(selected, "m.new")
+			} else {
+				selected = append(selected, "t."+col)
+			}
+		}
+		query := fmt.Sprintf("INSERT INTO main.%s (%s) SELECT %s FROM src.%s AS t JOIN temp.simIDMap AS m ON t.simID = m.old",
+			table, strings.Join(columns, ", "), strings.Join(selected, ", "), table)
+		res, err := tx.Exec(query)
+		if err != nil {
+			return err
+		}
+		if result.Rows[table], err = res.RowsAffected(); err != nil {
+			return err
+		}
+	}
+
+	// Sources created before the domain size was stored per simulation
+	return backfillDomainSizes(tx, "src", "m.new", "JOIN temp.simIDMap AS m ON t.simID = m.old")
+}
+
+// mergePositions copies the positions table if the destination has no positions. The
+// domain size of each simulation is stored in the domainSizes table, thus the positions
+// tables do not need to be compatible
+func mergePositions(tx *sql.Tx, srcTables map[string]bool) error {
+	if !srcTables["positions"] {
+		return nil
+	}
+	var numDst int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM main.positions").Scan(&numDst); err != nil {
+		return err
+	}
+	if numDst > 0 {
+		return nil
+	}
+	_, err := tx.Exec("INSERT INTO main.positions (id, X, Y, Z) SELECT id, X, Y, Z FROM src.positions")
+	return err
+}
+
+// mainDatabaseFile returns the file of the main database. An empty string is returned for
+// in-memory databases
+func mainDatabaseFile(db *sql.DB) (string, error) {
+	rows, err := db.Query("PRAGMA database_list")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var seq int
+	var name, file string
+	for rows.Next() {
+		if err := rows.Scan(&seq, &name, &file); err != nil {
+			return "", err
+		}
+		if name == "main" {
+			return file, nil
+		}
+	}
+	return "", rows.Err()
+}
+
+// schemaTables returns the names of all tables in the schema (main or an attached database)
+func schemaTables(tx *sql.Tx, schema string) (map[string]bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("SELECT name FROM %s.sqlite_master WHERE type='table'", schema))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tables := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables[name] = true
+	}
+	return tables, rows.Err()
+}
+
+// tableColumns returns the columns of a table and the name of the INTEGER PRIMARY KEY
+// column (empty if the table has none)
+func tableColumns(tx *sql.Tx, schema string, table string) (map[string]bool, string, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA %s.table_info(%s)", schema, table))
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	primaryKey := ""
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, ctype string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &ctype, &notNull, &dflt, &pk); err != nil {
+			return nil, "", err
+		}
+		columns[name] = true
+		if pk == 1 && strings.EqualFold(ctype, "INTEGER") {
+			primaryKey = name
+		}
+	}
+	return columns, primaryKey, rows.Err()
+}
@@ -0,0 +1,195 @@
+package pf
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/davidkleiven/gopf/pfutil"
+)
+
+func TestMergeDatabase(t *testing.T) {
+	src1 := "./testMergeSrc1.db"
+	src2 := "./testMergeSrc2.db"
+	legacy := "./testMergeLegacy.db"
+	dstName := "./testMergeDst.db"
+	for _, name := range []string{src1, src2, legacy, dstName} {
+		defer os.Remove(name)
+	}
+
+	// Both sources have simulation IDs 1 and 2
+	ids1 := createSimulations(t, src1, 2, 3)
+	ids2 := createSimulations(t, src2, 2, 3)
+	createLegacyDB(t, legacy).Close()
+
+	dst, _ := sql.Open("sqlite3", dstName)
+	defer dst.Close()
+
+	res1, err := MergeDatabase(dst, src1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res1.SimIDs) != len(ids1) {
+		t.Errorf("Expected %d simulations to be merged got %v\n", len(ids1), res1.SimIDs)
+	}
+
+	res2, err := MergeDatabase(dst, src2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ids2 {
+		newID := res2.SimIDs[id]
+		if newID <= res1.SimIDs[ids1[len(ids1)-1]] {
+			t.Errorf("Expected simulation %d to get a new ID got %d\n", id, newID)
+		}
+	}
+	if res2.Rows["simIDs"] != 2 || res2.Rows["comments"] != 2 || res2.Rows["tags"] != 2 {
+		t.Errorf("Unexpected number of copied rows %v\n", res2.Rows)
+	}
+
+	res3, err := MergeDatabase(dst, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res3.SimIDs[7] != 5 || res3.Rows["timeseries"] != 1 {
+		t.Errorf("Expected legacy simulation 7 to get ID 5 with one timeseries row got %v\n", res3)
+	}
+
+	var numSims int
+	dst.QueryRow("SELECT COUNT(*) FROM simIDs").Scan(&numSims)
+	if numSims != 5 {
+		t.Errorf("Expected 5 simulations got %d\n", numSims)
+	}
+
+	// Fields of the remapped simulations must be readable in both layouts
+	db := FieldDB{DB: dst}
+	for _, id := range ids2 {
+		timesteps, err := db.Timesteps(res2.SimIDs[id])
+		if err != nil || len(timesteps) != 3 {
+			t.Errorf("Expected 3 timesteps got %v (%v)\n", timesteps, err)
+		}
+		fields, domainSize, err := db.LoadWithDomainSize(res2.SimIDs[id], 0)
+		if err != nil || len(fields) != 1 || len(fields[0].Data) != 16 || domainSize[0] != 4 {
+			t.Errorf("Could not load fields of merged simulation (%v)\n", err)
+		}
+	}
+	var comment string
+	dst.QueryRow("SELECT value FROM comments WHERE simID=?", res3.SimIDs[7]).Scan(&comment)
+	if comment != "legacy run" {
+		t.Errorf("Expected comment 'legacy run' got %s\n", comment)
+	}
+
+	// Merging a missing file fails
+	if _, err := MergeDatabase(dst, "./notExisting.db"); err == nil {
+		t.Errorf("Expected error for a missing source\n")
+	}
+
+	// Merging the destination into itself would duplicate all rows
+	abs, _ := filepath.Abs(dstName)
+	for _, name := range []string{dstName, abs} {
+		if _, err := MergeDatabase(dst, name); err == nil {
+			t.Errorf("Expected error when merging %s into itself\n", name)
+		}
+	}
+	dst.QueryRow("SELECT COUNT(*) FROM simIDs").Scan(&numSims)
+	if numSims != 5 {
+		t.Errorf("Expected 5 simulations after merging into itself got %d\n", numSims)
+	}
+}
+
+func TestMergeDoesNotReuseDeletedIDs(t *testing.T) {
+	srcName := "./testMergeReuseSrc.db"
+	dstName := "./testMergeReuseDst.db"
+	for _, name := range []string{srcName, dstName} {
+		defer os.Remove(name)
+	}
+
+	// The simulation in the destination is deleted, such that its ID is unused
+	ids := createSimulations(t, srcName, 1, 1)
+	deleted := createSimulations(t, dstName, 1, 1)
+	dst, _ := sql.Open("sqlite3", dstName)
+	defer dst.Close()
+	db := FieldDB{DB: dst}
+	if _, err := db.DeleteSimulations(deleted, false); err != nil {
+		t.Fatal(err)
+	}
+	if ids[0] != deleted[0] {
+		t.Fatalf("Expected both databases to start at the same ID got %d and %d\n", ids[0], deleted[0])
+	}
+
+	res, err := MergeDatabase(dst, srcName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.SimIDs[ids[0]] == deleted[0] {
+		t.Errorf("Merged simulation reused the ID %d of a deleted simulation\n", deleted[0])
+	}
+}
+
+func TestMergeDifferentDomainSizes(t *testing.T) {
+	srcName := "./testMergeDomainSrc.db"
+	legacyName := "./testMergeDomainLegacy.db"
+	dstName := "./testMergeDomainDst.db"
+	for _, name := range []string{srcName, legacyName, dstName} {
+		defer os.Remove(name)
+	}
+
+	// The source has one 4x4 simulation stored as blobs and one stored as rows
+	ids := createSimulations(t, srcName, 2, 1)
+
+	// The legacy database has a 1D domain with three nodes and no domainSizes table
+	legacy := createLegacyDB(t, legacyName)
+	for _, stmt := range []string{
+		"INSERT INTO positions (X, Y, Z) VALUES (0, 0, 0), (1, 0, 0), (2, 0, 0)",
+		"INSERT INTO fields (name, value, positionId, timestep, simID) VALUES ('conc', 1.0, 0, 0, 7), ('conc', 2.0, 1, 0, 7), ('conc', 3.0, 2, 0, 7)",
+	} {
+		if _, err := legacy.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	legacy.Close()
+
+	// The destination has a 2x2 simulation stored as rows
+	db, err := OpenFieldDB(dstName, []int{2, 2}, FieldDBOptions{Layout: FieldRowLayout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	model := NewModel()
+	model.AddField(NewField("conc", 4, nil))
+	model.AddEquation("dconc/dt = conc")
+	solver := NewSolver(&model, []int{2, 2}, 0.1)
+	if err := db.SaveFields(solver, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := MergeDatabase(db.DB, srcName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resLegacy, err := MergeDatabase(db.DB, legacyName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, test := range []struct {
+		simID      int
+		domainSize []int
+	}{
+		{simID: db.SimID(), domainSize: []int{2, 2}},
+		{simID: res.SimIDs[ids[0]], domainSize: []int{4, 4}},
+		{simID: res.SimIDs[ids[1]], domainSize: []int{4, 4}},
+		{simID: resLegacy.SimIDs[7], domainSize: []int{3}},
+	} {
+		fields, domainSize, err := db.LoadWithDomainSize(test.simID, 0)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(domainSize, test.domainSize) || len(fields[0].Data) != pfutil.ProdInt(test.domainSize) {
+			t.Errorf("Test #%d: Expected domain size %v got %v\n", i, test.domainSize, domainSize)
+		}
+	}
+}
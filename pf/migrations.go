@@ -0,0 +1,374 @@
+package pf
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Migration upgrades the schema of a field database from Version-1 to Version. All
+// migrations must be safe to apply to databases created before the schema was
+// versioned, where some of the changes may already be present.
+type Migration struct {
+	Version     int
+	Description string
+	Apply       func(tx *sql.Tx) error
+}
+
+// migrations is the ordered list of all migrations. New migrations must be appended
+// with a version one larger than the last one
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "Create the initial tables",
+		Apply: execAll(
+			"CREATE TABLE IF NOT EXISTS simIDs (simID INTEGER UNIQUE, creationTime TEXT)",
+			"CREATE TABLE IF NOT EXISTS positions (id INTEGER PRIMARY KEY, X INTEGER, Y INTEGER, Z INTEGER)",
+			"CREATE TABLE IF NOT EXISTS fields (id INTEGER PRIMARY KEY, name TEXT, "+
+				"value REAL, positionId INTEGER, timestep INTEGER, simID INTEGER, "+
+				"FOREIGN KEY(positionId) REFERENCES positions(id), "+
+				"FOREIGN KEY(simID) REFERENCES simIDs(simID))",
+			"CREATE TABLE IF NOT EXISTS simAttributes (key TEXT, value REAL, simID INTEGER, FOREIGN KEY(simID) REFERENCES fields(simID))",
+			"CREATE TABLE IF NOT EXISTS comments (simID INTEGER, value TEXT, FOREIGN KEY(simID) REFERENCES simIds(simID))",
+			"CREATE TABLE IF NOT EXISTS simTextAttributes (key TEXT, value TEXT, simID INTEGER, FOREIGN KEY(simID) REFERENCES simIds(simID))",
+			"CREATE TABLE IF NOT EXISTS timeseries (key TEXT, value REAL, timestep INTEGER, simID INTEGER, FOREIGN KEY(simID) REFERENCES simIds(simID))",
+		),
+	},
+	{
+		Version:     2,
+		Description: "Add the time column to the timeseries table",
+		Apply: func(tx *sql.Tx) error {
+			hasTime, err := hasColumn(tx, "timeseries", "time")
+			if err != nil || hasTime {
+				return err
+			}
+			_, err = tx.Exec("ALTER TABLE timeseries ADD COLUMN time REAL")
+			return err
+		},
+	},
+	{
+		Version:     3,
+		Description: "Create the fieldBlobs table",
+		Apply: execAll(
+			"CREATE TABLE IF NOT EXISTS fieldBlobs (name TEXT, timestep INTEGER, simID INTEGER, " +
+				"dtype TEXT, shape TEXT, compression TEXT, data BLOB, " +
+				"UNIQUE(simID, name, timestep), FOREIGN KEY(simID) REFERENCES simIDs(simID))",
+		),
+	},
+	{
+		Version:     4,
+		Description: "Let all foreign keys on simID reference simIDs(simID)",
+		Apply: func(tx *sql.Tx) error {
+			tables := []struct {
+				name    string
+				columns string
+				schema  string
+			}{
+				{
+					name:    "simAttributes",
+					columns: "key, value, simID",
+					schema:  "key TEXT, value REAL, simID INTEGER",
+				},
+				{
+					name:    "comments",
+					columns: "simID, value",
+					schema:  "simID INTEGER, value TEXT",
+				},
+				{
+					name:    "simTextAttributes",
+					columns: "key, value, simID",
+					schema:  "key TEXT, value TEXT, simID INTEGER",
+				},
+				{
+					name:    "timeseries",
+					columns: "key, value, timestep, simID, time",
+					schema:  "key TEXT, value REAL, timestep INTEGER, simID INTEGER, time REAL",
+				},
+			}
+
+			// SQLite can not alter constraints. Thus, the tables are rebuilt
+			for _, table := range tables {
+				tmp := table.name + "_migrate"
+				statements := []string{
+					fmt.Sprintf("CREATE TABLE %s (%s, FOREIGN KEY(simID) REFERENCES simIDs(simID))", tmp, table.schema),
+					fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", tmp, table.columns, table.columns, table.name),
+					fmt.Sprintf("DROP TABLE %s", table.name),
+					fmt.Sprintf("ALTER TABLE %s RENAME TO %s", tmp, table.name),
+				}
+				if err := execAll(statements...)(tx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     5,
+		Description: "Add indices on (simID, timestep, name)",
+		Apply: execAll(
+			"CREATE INDEX IF NOT EXISTS fieldsSimIDTimestepName ON fields (simID, timestep, name)",
+			"CREATE INDEX IF NOT EXISTS fieldBlobsSimIDTimestepName ON fieldBlobs (simID, timestep, name)",
+			"CREATE INDEX IF NOT EXISTS timeseriesSimIDTimestepKey ON timeseries (simID, timestep, key)",
+		),
+	},
+	{
+		Version:     6,
+		Description: "Allocate simulation IDs with autoincrement",
+		Apply: execAll(
+			"CREATE TABLE simIDs_migrate (simID INTEGER PRIMARY KEY AUTOINCREMENT, creationTime TEXT)",
+			"INSERT INTO simIDs_migrate (simID, creationTime) SELECT simID, creationTime FROM simIDs WHERE simID IS NOT NULL",
+			"DROP TABLE simIDs",
+			"ALTER TABLE simIDs_migrate RENAME TO simIDs",
+		),
+	},
+	{
+		Version:     7,
+		Description: "Create the tags table",
+		Apply: execAll(
+			"CREATE TABLE IF NOT EXISTS tags (simID INTEGER, tag TEXT, UNIQUE(simID, tag), FOREIGN KEY(simID) REFERENCES simIDs(simID))",
+			"CREATE INDEX IF NOT EXISTS tagsTag ON tags (tag)",
+		),
+	},
+	{
+		Version:     8,
+		Description: "Create the provenance tables",
+		Apply: execAll(
+			"CREATE TABLE IF NOT EXISTS provenance (simID INTEGER, key TEXT, value TEXT, "+
+				"UNIQUE(simID, key), FOREIGN KEY(simID) REFERENCES simIDs(simID))",
+			"CREATE TABLE IF NOT EXISTS provenanceEquations (simID INTEGER, eqNo INTEGER, equation TEXT, "+
+				"FOREIGN KEY(simID) REFERENCES simIDs(simID))",
+			"CREATE TABLE IF NOT EXISTS provenanceComponents (simID INTEGER, name TEXT, kind TEXT, type TEXT, "+
+				"FOREIGN KEY(simID) REFERENCES simIDs(simID))",
+			"CREATE TABLE IF NOT EXISTS provenanceParameters (simID INTEGER, component TEXT, parameter TEXT, value TEXT, "+
+				"FOREIGN KEY(simID) REFERENCES simIDs(simID))",
+			"CREATE TABLE IF NOT EXISTS randomSeeds (simID INTEGER, name TEXT, seed INTEGER, "+
+				"UNIQUE(simID, name), FOREIGN KEY(simID) REFERENCES simIDs(simID))",
+		),
+	},
+	{
+		Version:     9,
+		Description: "Store the domain size of each simulation",
+		Apply: func(tx *sql.Tx) error {
+			_, err := tx.Exec("CREATE TABLE IF NOT EXISTS domainSizes (simID INTEGER UNIQUE, shape TEXT, spacing TEXT, " +
+				"FOREIGN KEY(simID) REFERENCES simIDs(simID))")
+			if err != nil {
+				return err
+			}
+
+			// Before this migration, all simulations shared the domain size given by the
+			// positions table
+			return backfillDomainSizes(tx, "main", "simID", "")
+		},
+	},
+}
+
+// LatestSchemaVersion is the schema version of databases after all migrations are applied
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// execAll returns a function that executes all statements in the transaction
+func execAll(statements ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, s := range statements {
+			if _, err := tx.Exec(s); err != nil {
+				return fmt.Errorf("%s: %s", s, err)
+			}
+		}
+		return nil
+	}
+}
+
+// hasColumn returns true if the table has a column with the passed name
+func hasColumn(tx *sql.Tx, table string, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, ctype string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &ctype, &notNull, &dflt, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// positionsShape returns the domain size described by the positions table in the passed
+// schema (main or an attached database). Trailing dimensions of length one are removed.
+// An empty string is returned if the table is empty.
+func positionsShape(tx *sql.Tx, schema string) (string, error) {
+	var numNodes int
+	var nx, ny, nz sql.NullInt64
+	row := tx.QueryRow(fmt.Sprintf("SELECT COUNT(*), MAX(X), MAX(Y), MAX(Z) FROM %s.positions", schema))
+	if err := row.Scan(&numNodes, &nx, &ny, &nz); err != nil {
+		return "", err
+	}
+	if numNodes == 0 {
+		return "", nil
+	}
+	shape := []int{int(nx.Int64) + 1, int(ny.Int64) + 1, int(nz.Int64) + 1}
+	for len(shape) > 1 && shape[len(shape)-1] == 1 {
+		shape = shape[:len(shape)-1]
+	}
+	return formatShape(shape), nil
+}
+
+// backfillDomainSizes inserts the domain size of simulations in the schema (main or an
+// attached database) that are not yet present in main.domainSizes. The domain size is
+// taken from the fieldBlobs table if present, otherwise from the positions table.
+// simIDExpr is the expression giving the simulation ID in main and join is appended to
+// the FROM clause (used to map simulation IDs when databases are merged)
+func backfillDomainSizes(tx *sql.Tx, schema string, simIDExpr string, join string) error {
+	tables, err := schemaTables(tx, schema)
+	if err != nil {
+		return err
+	}
+	if tables["fieldBlobs"] {
+		query := fmt.Sprintf("INSERT OR IGNORE INTO main.domainSizes (simID, shape) SELECT %s, MIN(t.shape) "+
+			"FROM %s.fieldBlobs AS t %s GROUP BY t.simID", simIDExpr, schema, join)
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	if !tables["fields"] || !tables["positions"] {
+		return nil
+	}
+	shape, err := positionsShape(tx, schema)
+	if err != nil || shape == "" {
+		return err
+	}
+	query := fmt.Sprintf("INSERT OR IGNORE INTO main.domainSizes (simID, shape) SELECT DISTINCT %s, ? "+
+		"FROM %s.fields AS t %s", simIDExpr, schema, join)
+	_, err = tx.Exec(query, shape)
+	return err
+}
+
+// SchemaVersion returns the schema version of the database. Databases created before
+// the schema was versioned have version 0.
+func SchemaVersion(db *sql.DB) (int, error) {
+	var count int
+	row := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='schemaVersion'")
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	var version sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(version) FROM schemaVersion").Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// PendingMigrations returns the migrations that have not been applied to the database
+func PendingMigrations(db *sql.DB) ([]Migration, error) {
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	pending := []Migration{}
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// busyTimeout is the time in milliseconds a connection waits for locks held by other
+// connections before it fails with "database is locked"
+const busyTimeout = 5000
+
+// Migrate upgrades the database to the latest schema version and returns the migrations
+// that were applied. Each migration is applied in a separate transaction together with
+// the update of the schemaVersion table, such that the database is left at a well defined
+// version if a migration fails. Several processes may migrate the same database
+// concurrently. The migrations are then applied by whichever process first obtains the
+// write lock, and the others skip them.
+func Migrate(db *sql.DB) ([]Migration, error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("PRAGMA busy_timeout = %d", busyTimeout)); err != nil {
+		return nil, err
+	}
+	_, err = conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schemaVersion (version INTEGER UNIQUE, description TEXT, appliedAt TEXT)")
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := PendingMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []Migration{}
+	for _, m := range pending {
+		ok, err := applyMigration(ctx, conn, m)
+		if err != nil {
+			return applied, err
+		}
+		if ok {
+			applied = append(applied, m)
+		}
+	}
+	return applied, nil
+}
+
+// lockForWriting takes the write lock of the database at the start of tx. database/sql
+// starts deferred transactions, and executing a statement that modifies nothing in table
+// has the same effect as BEGIN IMMEDIATE: other connections can not write until tx ends,
+// thus everything read through tx stays valid.
+func lockForWriting(tx *sql.Tx, table string) error {
+	_, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE 0", table))
+	return err
+}
+
+// applyMigration applies m unless it has already been applied by another connection, and
+// reports whether it was applied. The write lock is taken before the version is read,
+// such that the version can not change until the transaction is committed.
+func applyMigration(ctx context.Context, conn *sql.Conn, m Migration) (bool, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	if err := lockForWriting(tx, "schemaVersion"); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	var version sql.NullInt64
+	if err := tx.QueryRow("SELECT MAX(version) FROM schemaVersion").Scan(&version); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if int(version.Int64) >= m.Version {
+		return false, tx.Rollback()
+	}
+
+	if err := m.Apply(tx); err != nil {
+		tx.Rollback()
+		return false, fmt.Errorf("migration %d (%s) failed: %s", m.Version, m.Description, err)
+	}
+	_, err = tx.Exec("INSERT INTO schemaVersion (version, description, appliedAt) VALUES (?, ?, datetime('now', 'localtime'))",
+		m.Version, m.Description)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
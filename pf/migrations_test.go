@@ -0,0 +1,202 @@
+package pf
+
+import (
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// createLegacyDB creates a database with the schema used before the schema was versioned
+func createLegacyDB(t *testing.T, fname string) *sql.DB {
+	db, err := sql.Open("sqlite3", fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := []string{
+		"CREATE TABLE simIDs (simID INTEGER UNIQUE, creationTime TEXT)",
+		"CREATE TABLE positions (id INTEGER PRIMARY KEY, X INTEGER, Y INTEGER, Z INTEGER)",
+		"CREATE TABLE fields (id INTEGER PRIMARY KEY, name TEXT, value REAL, positionId INTEGER, timestep INTEGER, simID INTEGER, " +
+			"FOREIGN KEY(positionId) REFERENCES positions(id), FOREIGN KEY(simID) REFERENCES simIDs(simID))",
+		"CREATE TABLE simAttributes (key TEXT, value REAL, simID INTEGER, FOREIGN KEY(simID) REFERENCES fields(simID))",
+		"CREATE TABLE comments (simID INTEGER, value TEXT, FOREIGN KEY(simID) REFERENCES simIds(simID))",
+		"CREATE TABLE simTextAttributes (key TEXT, value TEXT, simID INTEGER, FOREIGN KEY(simID) REFERENCES simIds(simID))",
+		"CREATE TABLE timeseries (key TEXT, value REAL, timestep INTEGER, simID INTEGER, FOREIGN KEY(simID) REFERENCES simIds(simID))",
+		"INSERT INTO simIDs (simID, creationTime) VALUES (7, '2020-01-01')",
+		"INSERT INTO simAttributes (key, value, simID) VALUES ('temperature', 300.0, 7)",
+		"INSERT INTO comments (simID, value) VALUES (7, 'legacy run')",
+		"INSERT INTO simTextAttributes (key, value, simID) VALUES ('boundaryX', 'periodic', 7)",
+		"INSERT INTO timeseries (key, value, timestep, simID) VALUES ('mean', 0.5, 3, 7)",
+	}
+	for _, s := range statements {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestMigrateLegacyDB(t *testing.T) {
+	dbName := "./testMigrateLegacy.db"
+	defer os.Remove(dbName)
+	db := createLegacyDB(t, dbName)
+	defer db.Close()
+
+	version, err := SchemaVersion(db)
+	if err != nil || version != 0 {
+		t.Errorf("Expected version 0 got %d (%v)\n", version, err)
+	}
+
+	pending, err := PendingMigrations(db)
+	if err != nil || len(pending) != LatestSchemaVersion() {
+		t.Errorf("Expected %d pending migrations got %d (%v)\n", LatestSchemaVersion(), len(pending), err)
+	}
+
+	// Dry run should not modify the database
+	version, _ = SchemaVersion(db)
+	if version != 0 {
+		t.Errorf("Listing the pending migrations changed the version to %d\n", version)
+	}
+
+	applied, err := Migrate(db)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	if len(applied) != LatestSchemaVersion() {
+		t.Errorf("Expected %d applied migrations got %d\n", LatestSchemaVersion(), len(applied))
+	}
+
+	version, _ = SchemaVersion(db)
+	if version != LatestSchemaVersion() {
+		t.Errorf("Expected version %d got %d\n", LatestSchemaVersion(), version)
+	}
+
+	// All foreign keys on simID should point to simIDs(simID)
+	for _, table := range []string{"simAttributes", "comments", "simTextAttributes", "timeseries", "fieldBlobs"} {
+		rows, err := db.Query("SELECT \"table\", \"from\", \"to\" FROM pragma_foreign_key_list(?)", table)
+		if err != nil {
+			t.Errorf("%s\n", err)
+			return
+		}
+		count := 0
+		for rows.Next() {
+			var target, from, to string
+			rows.Scan(&target, &from, &to)
+			if target != "simIDs" || from != "simID" || to != "simID" {
+				t.Errorf("%s: Expected reference to simIDs(simID) got %s(%s) from %s\n", table, target, to, from)
+			}
+			count++
+		}
+		rows.Close()
+		if count != 1 {
+			t.Errorf("%s: Expected one foreign key got %d\n", table, count)
+		}
+	}
+
+	// The data should be preserved
+	var value float64
+	var time sql.NullFloat64
+	db.QueryRow("SELECT value, time FROM timeseries WHERE simID=7").Scan(&value, &time)
+	if value != 0.5 || time.Valid {
+		t.Errorf("Expected value 0.5 and no time got %f %v\n", value, time)
+	}
+	var comment string
+	db.QueryRow("SELECT value FROM comments WHERE simID=7").Scan(&comment)
+	if comment != "legacy run" {
+		t.Errorf("Expected comment 'legacy run' got %s\n", comment)
+	}
+	db.QueryRow("SELECT value FROM simAttributes WHERE simID=7").Scan(&value)
+	if value != 300.0 {
+		t.Errorf("Expected attribute 300 got %f\n", value)
+	}
+
+	var numIndices int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='index' AND name LIKE '%SimIDTimestep%'").Scan(&numIndices)
+	if numIndices != 3 {
+		t.Errorf("Expected 3 indices got %d\n", numIndices)
+	}
+
+	// New simulation IDs should be larger than the ones created by older versions
+	fdb := FieldDB{DB: db}
+	if err := fdb.initialize(); err != nil {
+		t.Errorf("%s\n", err)
+	}
+	if fdb.SimID() <= 7 {
+		t.Errorf("Expected a simulation ID larger than 7 got %d\n", fdb.SimID())
+	}
+	var creationTime string
+	db.QueryRow("SELECT creationTime FROM simIDs WHERE simID=7").Scan(&creationTime)
+	if creationTime != "2020-01-01" {
+		t.Errorf("Expected the legacy simulation ID to be preserved got creation time '%s'\n", creationTime)
+	}
+
+	// Migrating again should not apply anything
+	applied, err = Migrate(db)
+	if err != nil || len(applied) != 0 {
+		t.Errorf("Expected no migrations got %d (%v)\n", len(applied), err)
+	}
+}
+
+func TestMigrateNewDB(t *testing.T) {
+	dbName := "./testMigrateNew.db"
+	defer os.Remove(dbName)
+	sqlDB, _ := sql.Open("sqlite3", dbName)
+	defer sqlDB.Close()
+
+	db := FieldDB{DB: sqlDB}
+	db.initialize()
+	version, err := SchemaVersion(sqlDB)
+	if err != nil || version != LatestSchemaVersion() {
+		t.Errorf("Expected version %d got %d (%v)\n", LatestSchemaVersion(), version, err)
+	}
+
+	// Fields can be written after the migration
+	db.TimeSeries(map[string]float64{"mean": 1.0}, 0)
+	db.SetAttr(map[string]float64{"temperature": 300.0})
+	db.Comment("new run")
+}
+
+func TestConcurrentOpenFieldDB(t *testing.T) {
+	dbName := "./testConcurrentOpen.db"
+	defer os.Remove(dbName)
+
+	num := 8
+	errs := make([]error, num)
+	var wg sync.WaitGroup
+	for i := 0; i < num; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			db, err := OpenFieldDB(dbName, []int{4, 4}, FieldDBOptions{})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = db.Close()
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Open #%d: %s\n", i, err)
+		}
+	}
+
+	sqlDB, _ := sql.Open("sqlite3", dbName)
+	defer sqlDB.Close()
+	var numVersions int
+	sqlDB.QueryRow("SELECT COUNT(*) FROM schemaVersion").Scan(&numVersions)
+	if numVersions != LatestSchemaVersion() {
+		t.Errorf("Expected %d rows in schemaVersion got %d\n", LatestSchemaVersion(), numVersions)
+	}
+
+	var numSims int
+	sqlDB.QueryRow("SELECT COUNT(*) FROM simIDs").Scan(&numSims)
+	if numSims != num {
+		t.Errorf("Expected %d simulation IDs got %d\n", num, numSims)
+	}
+}
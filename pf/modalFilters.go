@@ -0,0 +1,179 @@
+package pf
+
+import "math"
+
+// The filters in this file are functions of a normalized wavenumber x, where x = 0
+// corresponds to the zeroth mode and x = 1 corresponds to the Nyquist frequency.
+// Wrap them in AxisFilter or RadialFilter to specify how x is obtained from the
+// frequency vector. If passed directly to a stepper, they are evaluated in the same
+// way as Vandeven (e.g. at the length of the frequency vector scaled by 2/pi).
+
+// ExponentialFilter implements the exponential filter
+// sigma(x) = exp(-Alpha*x^Order)
+// The default parameters returned by NewHouLiFilter is the choice proposed in
+// Hou, T.Y. and Li, R., 2007. Computing nearly singular solutions using pseudo-spectral methods.
+// Journal of Computational Physics, 226(1), pp.379-397.
+type ExponentialFilter struct {
+	Alpha float64
+	Order float64
+}
+
+// NewHouLiFilter returns the exponential filter with Alpha = 36 and Order = 36
+func NewHouLiFilter() ExponentialFilter {
+	return ExponentialFilter{Alpha: 36.0, Order: 36.0}
+}
+
+// Eval evaluates the filter at x
+func (e *ExponentialFilter) Eval(x float64) float64 {
+	return math.Exp(-e.Alpha * math.Pow(math.Abs(x), e.Order))
+}
+
+// RaisedCosineFilter implements the filter sigma(x) = (1 + cos(pi*x))/2. The
+// filter is zero for x > 1
+type RaisedCosineFilter struct{}
+
+// Eval evaluates the filter at x
+func (r *RaisedCosineFilter) Eval(x float64) float64 {
+	x = math.Abs(x)
+	if x >= 1.0 {
+		return 0.0
+	}
+	return 0.5 * (1.0 + math.Cos(math.Pi*x))
+}
+
+// LanczosFilter implements the filter sigma(x) = sin(pi*x)/(pi*x). The filter is
+// zero for x > 1
+type LanczosFilter struct{}
+
+// Eval evaluates the filter at x
+func (l *LanczosFilter) Eval(x float64) float64 {
+	x = math.Abs(x)
+	if x < 1e-10 {
+		return 1.0
+	} else if x >= 1.0 {
+		return 0.0
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// ButterworthFilter implements the filter sigma(x) = 1/sqrt(1 + (x/Cutoff)^(2*Order)).
+// The filter takes the value 1/sqrt(2) at the cutoff
+type ButterworthFilter struct {
+	Cutoff float64
+	Order  int
+}
+
+// Eval evaluates the filter at x
+func (b *ButterworthFilter) Eval(x float64) float64 {
+	return 1.0 / math.Sqrt(1.0+math.Pow(x/b.Cutoff, float64(2*b.Order)))
+}
+
+// AxisFilter applies a filter along each axis separately. The value for a mode is the
+// product of Filter evaluated at the normalized frequency along each axis
+type AxisFilter struct {
+	Filter ModalFilter
+}
+
+// Eval evaluates the underlying filter
+func (a *AxisFilter) Eval(x float64) float64 {
+	return a.Filter.Eval(x)
+}
+
+// EvalFreq evaluates the filter at the passed frequency
+func (a *AxisFilter) EvalFreq(freq []float64) float64 {
+	value := 1.0
+	for _, f := range freq {
+		value *= a.Filter.Eval(2.0 * math.Abs(f))
+	}
+	return value
+}
+
+// RadialFilter applies a filter that is radially symmetric in the fourier domain. Filter
+// is evaluated at |k| normalized by the Nyquist frequency. Note that modes along the
+// diagonal have normalized wavenumbers larger than one
+type RadialFilter struct {
+	Filter ModalFilter
+}
+
+// Eval evaluates the underlying filter
+func (r *RadialFilter) Eval(x float64) float64 {
+	return r.Filter.Eval(x)
+}
+
+// EvalFreq evaluates the filter at the passed frequency
+func (r *RadialFilter) EvalFreq(freq []float64) float64 {
+	fSq := 0.0
+	for _, f := range freq {
+		fSq += f * f
+	}
+	return r.Filter.Eval(2.0 * math.Sqrt(fSq))
+}
+
+// ProductFilter is a composition of filters. The value for a mode is the product of the
+// values of all filters
+type ProductFilter struct {
+	Filters []ModalFilter
+}
+
+// Eval returns the product of all filters evaluated at x
+func (p *ProductFilter) Eval(x float64) float64 {
+	value := 1.0
+	for _, f := range p.Filters {
+		value *= f.Eval(x)
+	}
+	return value
+}
+
+// EvalFreq returns the product of all filters evaluated at the passed frequency
+func (p *ProductFilter) EvalFreq(freq []float64) float64 {
+	value := 1.0
+	for _, f := range p.Filters {
+		value *= filterValue(f, freq)
+	}
+	return value
+}
+
+// FieldSelector is implemented by filters that should only be applied to some of the
+// fields in a model
+type FieldSelector interface {
+	AppliesTo(field string) bool
+}
+
+// SelectedFieldsFilter is a filter that is only applied to the fields listed in Fields
+//
+//	stepper.SetFilter(&pf.SelectedFieldsFilter{Filter: &filter, Fields: []string{"conc"}})
+type SelectedFieldsFilter struct {
+	Filter ModalFilter
+	Fields []string
+}
+
+// Eval evaluates the underlying filter
+func (s *SelectedFieldsFilter) Eval(x float64) float64 {
+	return s.Filter.Eval(x)
+}
+
+// EvalFreq evaluates the underlying filter at the passed frequency
+func (s *SelectedFieldsFilter) EvalFreq(freq []float64) float64 {
+	return filterValue(s.Filter, freq)
+}
+
+// AppliesTo returns true if field is one of the selected fields
+func (s *SelectedFieldsFilter) AppliesTo(field string) bool {
+	for _, f := range s.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// filterApplies returns true if the filter should be applied to the passed field
+func filterApplies(filter ModalFilter, field string) bool {
+	if filter == nil {
+		return false
+	}
+	if fs, ok := filter.(FieldSelector); ok {
+		return fs.AppliesTo(field)
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package pf
+
+import (
+	"math"
+	"testing"
+)
+
+func TestModalFilterShapes(t *testing.T) {
+	houLi := NewHouLiFilter()
+	for i, test := range []struct {
+		filter ModalFilter
+		x      float64
+		expect float64
+	}{
+		{filter: &houLi, x: 0.0, expect: 1.0},
+		{filter: &houLi, x: 1.0, expect: math.Exp(-36.0)},
+		{filter: &RaisedCosineFilter{}, x: 0.0, expect: 1.0},
+		{filter: &RaisedCosineFilter{}, x: 0.5, expect: 0.5},
+		{filter: &RaisedCosineFilter{}, x: 1.5, expect: 0.0},
+		{filter: &LanczosFilter{}, x: 0.0, expect: 1.0},
+		{filter: &LanczosFilter{}, x: 0.5, expect: 2.0 / math.Pi},
+		{filter: &LanczosFilter{}, x: 1.0, expect: 0.0},
+		{filter: &ButterworthFilter{Cutoff: 0.5, Order: 4}, x: 0.0, expect: 1.0},
+		{filter: &ButterworthFilter{Cutoff: 0.5, Order: 4}, x: 0.5, expect: 1.0 / math.Sqrt(2.0)},
+	} {
+		if v := test.filter.Eval(test.x); math.Abs(v-test.expect) > 1e-10 {
+			t.Errorf("Test #%d: Expected %f got %f\n", i, test.expect, v)
+		}
+	}
+}
+
+func TestAxisAndRadialFilters(t *testing.T) {
+	freq := []float64{0.25, 0.25}
+	for i, test := range []struct {
+		filter ModalFilter
+		expect float64
+	}{
+		{
+			filter: &AxisFilter{Filter: &RaisedCosineFilter{}},
+			expect: 0.25,
+		},
+		{
+			filter: &RadialFilter{Filter: &RaisedCosineFilter{}},
+			expect: 0.5 * (1.0 + math.Cos(math.Pi/math.Sqrt(2.0))),
+		},
+		{
+			filter: &ProductFilter{
+				Filters: []ModalFilter{
+					&AxisFilter{Filter: &RaisedCosineFilter{}},
+					&RadialFilter{Filter: &LanczosFilter{}},
+				},
+			},
+			expect: 0.25 * math.Sin(math.Pi/math.Sqrt(2.0)) / (math.Pi / math.Sqrt(2.0)),
+		},
+	} {
+		data := []complex128{1.0}
+		ApplyModalFilter(test.filter, func(i int) []float64 { return freq }, data)
+		if math.Abs(real(data[0])-test.expect) > 1e-10 {
+			t.Errorf("Test #%d: Expected %f got %f\n", i, test.expect, real(data[0]))
+		}
+	}
+}
+
+func TestSelectedFieldsFilter(t *testing.T) {
+	N := 16
+	m := NewModel()
+	conc := NewField("conc", N, nil)
+	eta := NewField("eta", N, nil)
+	for i := range conc.Data {
+		// Nyquist mode which is removed by the raised cosine filter
+		conc.Data[i] = complex(math.Cos(math.Pi*float64(i)), 0.0)
+		eta.Data[i] = conc.Data[i]
+	}
+	m.AddField(conc)
+	m.AddField(eta)
+	m.AddScalar(NewScalar("rate", 0.0))
+	m.AddEquation("dconc/dt = rate*conc")
+	m.AddEquation("deta/dt = rate*eta")
+
+	solver := NewSolver(&m, []int{N}, 0.1)
+	solver.Stepper.SetFilter(&SelectedFieldsFilter{
+		Filter: &RadialFilter{Filter: &RaisedCosineFilter{}},
+		Fields: []string{"conc"},
+	})
+	solver.Solve(1, 1)
+
+	for i := range conc.Data {
+		if math.Abs(real(conc.Data[i])) > 1e-10 {
+			t.Errorf("Node %d: Expected conc to be filtered. Got %f", i, real(conc.Data[i]))
+		}
+		if math.Abs(real(eta.Data[i])-math.Cos(math.Pi*float64(i))) > 1e-10 {
+			t.Errorf("Node %d: Expected eta to be unchanged. Got %f", i, real(eta.Data[i]))
+		}
+	}
+}
@@ -120,10 +120,12 @@ type Model struct {
 	ImplicitTerms map[string]PureTerm
 	ExplicitTerms map[string]PureTerm
 	MixedTerms    map[string]MixedTerm
+	Kernels       map[string]RadialKernel
 	Equations     []string
 	RHS           []RHS
 	AllSources    []Sources
 	RHSModifiers  []eqModifier
+	dealiaser     Dealiaser
 }
 
 // NewModel returns a new model
@@ -133,6 +135,7 @@ func NewModel() Model {
 		ImplicitTerms: make(map[string]PureTerm),
 		ExplicitTerms: make(map[string]PureTerm),
 		MixedTerms:    make(map[string]MixedTerm),
+		Kernels:       make(map[string]RadialKernel),
 		RHSModifiers:  []eqModifier{},
 	}
 }
@@ -186,7 +189,7 @@ func (m *Model) UpdateDerivedFields(eq string) {
 			dField := DerivedField{
 				Data: make([]complex128, len(m.Fields[0].Data)),
 				Name: newFields,
-				Calc: DerivedFieldCalcFromDesc(newFields, m.Fields),
+				Calc: m.derivedFieldCalc(newFields),
 			}
 			m.DerivedFields = append(m.DerivedFields, dField)
 			m.Bricks[newFields] = &dField
@@ -372,6 +375,20 @@ func (m *Model) RegisterMixedTerm(name string, t MixedTerm, dFields []DerivedFie
 	m.registerDerivedFields(dFields)
 }
 
+// RegisterKernel registers an isotropic kernel that can be used as an operator in the
+// equations. The name is written in front of the expression that the kernel is applied
+// to, in the same way as LAP
+//
+//	model.RegisterKernel("GAUSS", func(k float64) float64 { return -math.Exp(-k * k) })
+//	model.AddEquation("dconc/dt = GAUSS conc")
+func (m *Model) RegisterKernel(name string, kernel RadialKernel) {
+	panicOnPrefixInName(name)
+	if m.Kernels == nil {
+		m.Kernels = make(map[string]RadialKernel)
+	}
+	m.Kernels[name] = kernel
+}
+
 // IsImplicitTerm checks if the given term is a linear term
 func (m *Model) IsImplicitTerm(desc string) bool {
 	_, ok := m.ImplicitTerms[desc]
@@ -1,16 +1,60 @@
 package pf
 
+import (
+	"fmt"
+	"math"
+
+	"github.com/davidkleiven/gopf/pfutil"
+)
+
 // Monitor is a generic interface monitor structures
 type Monitor interface {
 	Add(bricks map[string]Brick)
 }
 
+// TimeMonitor is a monitor that records the simulation time next to its values.
+// The solver calls RecordTime right before Add
+type TimeMonitor interface {
+	Monitor
+	RecordTime(t float64)
+}
+
+// TimeRecorder holds the simulation times at which a monitor has been updated.
+// It is embedded in the monitors such that they implement TimeMonitor
+type TimeRecorder struct {
+	Time []float64 `json:",omitempty"`
+}
+
+// RecordTime appends a new time
+func (tr *TimeRecorder) RecordTime(t float64) {
+	tr.Time = append(tr.Time, t)
+}
+
+// LatestMonitor is a monitor that can report its most recent values. The keys
+// should be unique among the monitors of a solver. Monitors implementing this
+// interface are passed to the sinks of the solver
+type LatestMonitor interface {
+	Monitor
+	Latest() map[string]float64
+}
+
+// latest returns a map with the last item in data stored under key. If data is empty,
+// an empty map is returned
+func latest(key string, data []float64) map[string]float64 {
+	res := make(map[string]float64)
+	if len(data) > 0 {
+		res[key] = data[len(data)-1]
+	}
+	return res
+}
+
 // A PointMonitor is a monitor that monitors the solution at a given pixel/voxel
 type PointMonitor struct {
 	Data  []float64
 	Site  int
 	Field string
 	Name  string
+	TimeRecorder
 }
 
 // NewPointMonitor returns a new instance of PointMonitor
@@ -28,3 +72,222 @@ func (p *PointMonitor) Add(bricks map[string]Brick) {
 	value := real(bricks[p.Field].Get(p.Site))
 	p.Data = append(p.Data, value)
 }
+
+// Latest returns the most recent value under the key <Name>_<Field>_<Site>
+func (p *PointMonitor) Latest() map[string]float64 {
+	return latest(fmt.Sprintf("%s_%s_%d", p.Name, p.Field, p.Site), p.Data)
+}
+
+// StatMonitor is a monitor that records a statistic (e.g. the mean) of the real part
+// of a brick. If Sites is empty, the statistic is calculated over all nodes. Otherwise,
+// only the listed nodes are included. Use one of the constructors to create a monitor
+type StatMonitor struct {
+	Data     []float64
+	Field    string
+	Name     string
+	NumNodes int
+	Sites    []int `json:"-"`
+	stat     func(values []float64) float64
+	TimeRecorder
+}
+
+// Add adds a new value to the monitor
+func (s *StatMonitor) Add(bricks map[string]Brick) {
+	b := bricks[s.Field]
+	var values []float64
+	if len(s.Sites) > 0 {
+		values = make([]float64, len(s.Sites))
+		for i, site := range s.Sites {
+			values[i] = real(b.Get(site))
+		}
+	} else {
+		values = make([]float64, s.NumNodes)
+		for i := range values {
+			values[i] = real(b.Get(i))
+		}
+	}
+	s.Data = append(s.Data, s.stat(values))
+}
+
+// Latest returns the most recent value under the key <Name>_<Field>
+func (s *StatMonitor) Latest() map[string]float64 {
+	return latest(fmt.Sprintf("%s_%s", s.Name, s.Field), s.Data)
+}
+
+// newStatMonitor returns a new statistic monitor
+func newStatMonitor(field string, numNodes int, name string, stat func(values []float64) float64) StatMonitor {
+	return StatMonitor{
+		Data:     []float64{},
+		Field:    field,
+		Name:     name,
+		NumNodes: numNodes,
+		stat:     stat,
+	}
+}
+
+// mean returns the mean of the values
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0.0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// NewMeanMonitor returns a monitor that records the spatial mean of the field
+func NewMeanMonitor(field string, numNodes int) StatMonitor {
+	return newStatMonitor(field, numNodes, "MeanMonitor", mean)
+}
+
+// NewMinMonitor returns a monitor that records the minimum value of the field
+func NewMinMonitor(field string, numNodes int) StatMonitor {
+	return newStatMonitor(field, numNodes, "MinMonitor", func(values []float64) float64 {
+		min := math.Inf(1)
+		for _, v := range values {
+			min = math.Min(min, v)
+		}
+		return min
+	})
+}
+
+// NewMaxMonitor returns a monitor that records the maximum value of the field
+func NewMaxMonitor(field string, numNodes int) StatMonitor {
+	return newStatMonitor(field, numNodes, "MaxMonitor", func(values []float64) float64 {
+		max := math.Inf(-1)
+		for _, v := range values {
+			max = math.Max(max, v)
+		}
+		return max
+	})
+}
+
+// NewVarianceMonitor returns a monitor that records the spatial variance of the field
+func NewVarianceMonitor(field string, numNodes int) StatMonitor {
+	return newStatMonitor(field, numNodes, "VarianceMonitor", func(values []float64) float64 {
+		mu := mean(values)
+		variance := 0.0
+		for _, v := range values {
+			variance += (v - mu) * (v - mu)
+		}
+		return variance / float64(len(values))
+	})
+}
+
+// NewL2NormMonitor returns a monitor that records the L2 norm of the field (e.g. the square
+// root of the sum of the squared values)
+func NewL2NormMonitor(field string, numNodes int) StatMonitor {
+	return newStatMonitor(field, numNodes, "L2NormMonitor", func(values []float64) float64 {
+		return math.Sqrt(pfutil.Dot(values, values))
+	})
+}
+
+// NewVolumeFractionMonitor returns a monitor that records the fraction of nodes where the
+// field is larger than threshold
+func NewVolumeFractionMonitor(field string, numNodes int, threshold float64) StatMonitor {
+	return newStatMonitor(field, numNodes, "VolumeFractionMonitor", func(values []float64) float64 {
+		count := 0
+		for _, v := range values {
+			if v > threshold {
+				count++
+			}
+		}
+		return float64(count) / float64(len(values))
+	})
+}
+
+// NewRegionAverageMonitor returns a monitor that records the average of the field over the
+// nodes that are inside the passed shape. The transformation is applied to the node positions
+// in the same way as in pfutil.Draw. If nil, the identity transformation is used.
+func NewRegionAverageMonitor(field string, domainSize []int, shape pfutil.Shape, transformation *pfutil.Affine) StatMonitor {
+	grid := pfutil.NewGrid(domainSize)
+	pfutil.Draw(shape, &grid, transformation, 1.0)
+	sites := []int{}
+	for i, v := range grid.Data {
+		if v > 0.5 {
+			sites = append(sites, i)
+		}
+	}
+	if len(sites) == 0 {
+		panic("monitor: No nodes are inside the passed shape")
+	}
+	monitor := newStatMonitor(field, len(grid.Data), "RegionAverageMonitor", mean)
+	monitor.Sites = sites
+	return monitor
+}
+
+// EnergyTerm is implemented by terms that can evaluate their contribution to the total
+// energy (e.g. PairCorrlationTerm and FreeEnergy)
+type EnergyTerm interface {
+	GetEnergy(bricks map[string]Brick, ft FourierTransform, domainSize []int) float64
+}
+
+// LocalEnergyTerm is implemented by terms where the energy only depends on the
+// local value of the fields (e.g. IdealMixtureTerm)
+type LocalEnergyTerm interface {
+	GetEnergy(bricks map[string]Brick, nodes int) float64
+}
+
+// EnergyMonitor records the total energy of the terms that expose their energy
+type EnergyMonitor struct {
+	Data       []float64
+	Name       string
+	Terms      []EnergyTerm      `json:"-"`
+	LocalTerms []LocalEnergyTerm `json:"-"`
+	domainSize []int
+	ft         FourierTransform
+	TimeRecorder
+}
+
+// NewEnergyMonitor returns a monitor of the total energy. All the user defined terms in the
+// model that implements EnergyTerm or LocalEnergyTerm are included. Additional contributions
+// that are not registered as terms in the model (e.g. a FreeEnergy) can be passed via extra
+func NewEnergyMonitor(m *Model, domainSize []int, extra ...EnergyTerm) EnergyMonitor {
+	monitor := EnergyMonitor{
+		Data:       []float64{},
+		Name:       "EnergyMonitor",
+		Terms:      extra,
+		domainSize: domainSize,
+		ft:         pfutil.NewFFTW(domainSize),
+	}
+
+	terms := []interface{}{}
+	for _, t := range m.ImplicitTerms {
+		terms = append(terms, t)
+	}
+	for _, t := range m.ExplicitTerms {
+		terms = append(terms, t)
+	}
+	for _, t := range m.MixedTerms {
+		terms = append(terms, t)
+	}
+
+	for _, t := range terms {
+		if et, ok := t.(EnergyTerm); ok {
+			monitor.Terms = append(monitor.Terms, et)
+		} else if lt, ok := t.(LocalEnergyTerm); ok {
+			monitor.LocalTerms = append(monitor.LocalTerms, lt)
+		}
+	}
+	return monitor
+}
+
+// Add adds a new value to the monitor
+func (e *EnergyMonitor) Add(bricks map[string]Brick) {
+	energy := 0.0
+	for _, t := range e.Terms {
+		energy += t.GetEnergy(bricks, e.ft, e.domainSize)
+	}
+	numNodes := pfutil.ProdInt(e.domainSize)
+	for _, t := range e.LocalTerms {
+		energy += t.GetEnergy(bricks, numNodes)
+	}
+	e.Data = append(e.Data, energy)
+}
+
+// Latest returns the most recent value under the key <Name>
+func (e *EnergyMonitor) Latest() map[string]float64 {
+	return latest(e.Name, e.Data)
+}
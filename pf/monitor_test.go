@@ -1,8 +1,11 @@
 package pf
 
 import (
+	"encoding/json"
+	"math"
 	"testing"
 
+	"github.com/davidkleiven/gopf/pfutil"
 	"gonum.org/v1/gonum/floats"
 )
 
@@ -23,3 +26,98 @@ func TestMonitor(t *testing.T) {
 		t.Errorf("Expected\n%v\nGot\n%v\n", expect, monitor.Data)
 	}
 }
+
+func TestStatMonitors(t *testing.T) {
+	field := NewField("myfield", 4, []complex128{1.0, -2.0, 3.0, 2.0})
+	bricks := map[string]Brick{"myfield": field}
+
+	for i, test := range []struct {
+		monitor StatMonitor
+		expect  float64
+	}{
+		{monitor: NewMeanMonitor("myfield", 4), expect: 1.0},
+		{monitor: NewMinMonitor("myfield", 4), expect: -2.0},
+		{monitor: NewMaxMonitor("myfield", 4), expect: 3.0},
+		{monitor: NewVarianceMonitor("myfield", 4), expect: 3.5},
+		{monitor: NewL2NormMonitor("myfield", 4), expect: math.Sqrt(18.0)},
+		{monitor: NewVolumeFractionMonitor("myfield", 4, 1.5), expect: 0.5},
+	} {
+		test.monitor.Add(bricks)
+		if math.Abs(test.monitor.Data[0]-test.expect) > 1e-10 {
+			t.Errorf("Test #%d (%s): Expected %f got %f\n", i, test.monitor.Name, test.expect, test.monitor.Data[0])
+		}
+	}
+}
+
+func TestRegionAverageMonitor(t *testing.T) {
+	N := 16
+	field := NewField("myfield", N*N, nil)
+	for i := range field.Data {
+		pos := pfutil.Pos([]int{N, N}, i)
+		if pos[0] < 4 && pos[1] < 4 {
+			field.Data[i] = 2.0
+		}
+	}
+	bricks := map[string]Brick{"myfield": field}
+
+	// Box with corners at (0, 0) and (3, 3)
+	transformation := pfutil.Translation([]float64{-1.5, -1.5, 0.0})
+	monitor := NewRegionAverageMonitor("myfield", []int{N, N}, &pfutil.Box{Diagonal: []float64{3.0, 3.0}}, &transformation)
+	monitor.Add(bricks)
+	if len(monitor.Sites) != 16 {
+		t.Errorf("Expected 16 sites got %d\n", len(monitor.Sites))
+	}
+	if math.Abs(monitor.Data[0]-2.0) > 1e-10 {
+		t.Errorf("Expected 2.0 got %f\n", monitor.Data[0])
+	}
+}
+
+func TestEnergyMonitor(t *testing.T) {
+	N := 8
+	m := NewModel()
+	conc := NewField("conc", N, nil)
+	for i := range conc.Data {
+		conc.Data[i] = 1.0
+	}
+	m.AddField(conc)
+	ideal := IdealMixtureTerm{Field: "conc", Prefactor: 2.0}
+	m.RegisterMixedTerm("IDEAL_MIX", &ideal, []DerivedField{ideal.DerivedField(N, m.Bricks)})
+	fe := FreeEnergy{
+		Bulk: []BulkTerm{{Fields: []string{"conc"}, Density: func(v []float64) float64 { return v[0] }}},
+	}
+
+	monitor := NewEnergyMonitor(&m, []int{N}, &fe)
+	monitor.Add(m.Bricks)
+
+	// The ideal mixture contributes 2*0.5 per node and the free energy 1 per node
+	expect := 2.0 * float64(N)
+	if math.Abs(monitor.Data[0]-expect) > 1e-10 {
+		t.Errorf("Expected %f got %f\n", expect, monitor.Data[0])
+	}
+}
+
+func TestMonitorsRecordTime(t *testing.T) {
+	N := 8
+	m := NewModel()
+	conc := NewField("conc", N, nil)
+	m.AddField(conc)
+	m.AddEquation("dconc/dt = LAP conc")
+
+	solver := NewSolver(&m, []int{N}, 0.1)
+	mean := NewMeanMonitor("conc", N)
+	solver.AddMonitor(&mean)
+	solver.Solve(2, 5)
+
+	expect := []float64{0.5, 1.0}
+	if !floats.EqualApprox(expect, mean.Time, 1e-10) {
+		t.Errorf("Expected\n%v\nGot\n%v\n", expect, mean.Time)
+	}
+
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(solver.JSONifyMonitors(), &decoded); err != nil {
+		t.Errorf("%s", err)
+	}
+	if len(decoded[0]["Time"].([]interface{})) != 2 {
+		t.Errorf("Expected the time to be part of the JSON representation. Got %v", decoded[0])
+	}
+}
@@ -0,0 +1,159 @@
+package pf
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/davidkleiven/gopf/pfutil"
+)
+
+// NpyIO stores the fields in the NumPy .npy format, such that they can be loaded in python
+// without knowing the shape and the data type in advance
+//
+//	arr = np.load("run_conc_10.npy")
+//
+// The array element arr[x, y, z] is the value at the node with position (x, y, z).
+// If Archive is true, all fields are stored in one .npz archive per epoch where each field
+// is stored under its own name
+//
+//	data = np.load("run_10.npz")
+//	conc = data["conc"]
+type NpyIO struct {
+	Prefix     string
+	DomainSize []int
+	Archive    bool
+}
+
+// NewNpyIO returns a new NpyIO that writes one .npy file per field
+func NewNpyIO(prefix string, domainSize []int) NpyIO {
+	return NpyIO{Prefix: prefix, DomainSize: domainSize}
+}
+
+// SaveFields stores the real part of all fields. The files are named
+// <prefix>_<field>_<epoch>.npy, or <prefix>_<epoch>.npz if Archive is true.
+// It can be passed as a callback to the solver
+func (n *NpyIO) SaveFields(s *Solver, epoch int) {
+	if n.Archive {
+		fname := fmt.Sprintf("%s_%d.npz", n.Prefix, epoch)
+		if err := SaveNpz(fname, s.Model.Fields, n.DomainSize); err != nil {
+			panic(err)
+		}
+		return
+	}
+	for _, f := range s.Model.Fields {
+		fname := fmt.Sprintf("%s_%s_%d.npy", n.Prefix, f.Name, epoch)
+		if err := f.SaveNpy(fname, n.DomainSize); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// realPart returns the real part of the field data
+func (f Field) realPart() []float64 {
+	data := make([]float64, len(f.Data))
+	for i := range f.Data {
+		data[i] = real(f.Data[i])
+	}
+	return data
+}
+
+// SaveNpy stores the real part of the field in the NumPy .npy format
+func (f Field) SaveNpy(fname string, domainSize []int) error {
+	out, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return pfutil.WriteNpy(out, f.realPart(), domainSize)
+}
+
+// SaveNpz stores the real part of all fields in a NumPy .npz archive. Each field is stored
+// under its own name
+func SaveNpz(fname string, fields []Field, domainSize []int) error {
+	out, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	archive := zip.NewWriter(out)
+	for _, f := range fields {
+		writer, err := archive.CreateHeader(&zip.FileHeader{Name: f.Name + ".npy", Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		if err := pfutil.WriteNpy(writer, f.realPart(), domainSize); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// LoadNpy loads a field from a NumPy .npy file. The field gets the passed name. The
+// domain size is given by the shape of the array
+func LoadNpy(fname string, name string) (Field, []int, error) {
+	infile, err := os.Open(fname)
+	if err != nil {
+		return Field{}, nil, err
+	}
+	defer infile.Close()
+
+	data, shape, err := pfutil.ReadNpy(bufio.NewReader(infile))
+	if err != nil {
+		return Field{}, nil, err
+	}
+	grid := pfutil.Grid{Dims: shape, Data: data}
+	return NewField(name, len(data), grid.ToComplex()), shape, nil
+}
+
+// LoadNpz loads all arrays in a NumPy .npz archive as fields. The fields are named after
+// the entries in the archive and are sorted by name. All arrays must have the same shape.
+func LoadNpz(fname string) ([]Field, []int, error) {
+	archive, err := zip.OpenReader(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer archive.Close()
+
+	fields := []Field{}
+	var domainSize []int
+	for _, entry := range archive.File {
+		reader, err := entry.Open()
+		if err != nil {
+			return nil, nil, err
+		}
+		data, shape, err := pfutil.ReadNpy(bufio.NewReader(reader))
+		reader.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if domainSize == nil {
+			domainSize = shape
+		} else if !sameShape(shape, domainSize) {
+			return nil, nil, fmt.Errorf("npz: %s has shape %v, expected %v", entry.Name, shape, domainSize)
+		}
+		grid := pfutil.Grid{Dims: shape, Data: data}
+		name := strings.TrimSuffix(entry.Name, ".npy")
+		fields = append(fields, NewField(name, len(data), grid.ToComplex()))
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields, domainSize, nil
+}
+
+// sameShape returns true if the two shapes are equal
+func sameShape(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
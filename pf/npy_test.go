@@ -0,0 +1,64 @@
+package pf
+
+import (
+	"math"
+	"os"
+	"testing"
+)
+
+func TestNpyIO(t *testing.T) {
+	domainSize := []int{4, 3}
+	N := 12
+	m := NewModel()
+	conc := NewField("conc", N, nil)
+	eta := NewField("eta", N, nil)
+	for i := range conc.Data {
+		conc.Data[i] = complex(float64(i), 0.0)
+		eta.Data[i] = complex(-float64(i), 0.0)
+	}
+	m.AddField(conc)
+	m.AddField(eta)
+	m.AddEquation("dconc/dt = LAP conc")
+	m.AddEquation("deta/dt = LAP eta")
+	solver := NewSolver(&m, domainSize, 0.1)
+
+	npy := NewNpyIO("npyTest", domainSize)
+	npy.SaveFields(solver, 2)
+	defer os.Remove("npyTest_conc_2.npy")
+	defer os.Remove("npyTest_eta_2.npy")
+
+	field, shape, err := LoadNpy("npyTest_eta_2.npy", "eta")
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	if !sameShape(shape, domainSize) {
+		t.Errorf("Expected shape %v got %v\n", domainSize, shape)
+	}
+	for i := range field.Data {
+		if math.Abs(real(field.Data[i]-eta.Data[i])) > 1e-10 {
+			t.Errorf("Expected %v got %v\n", eta.Data, field.Data)
+			break
+		}
+	}
+
+	npy.Archive = true
+	npy.SaveFields(solver, 3)
+	defer os.Remove("npyTest_3.npz")
+
+	fields, shape, err := LoadNpz("npyTest_3.npz")
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	if len(fields) != 2 || fields[0].Name != "conc" || fields[1].Name != "eta" || !sameShape(shape, domainSize) {
+		t.Errorf("Unexpected fields %v with shape %v\n", fields, shape)
+		return
+	}
+	for i := range fields[0].Data {
+		if math.Abs(real(fields[0].Data[i]-conc.Data[i])) > 1e-10 {
+			t.Errorf("Expected %v got %v\n", conc.Data, fields[0].Data)
+			break
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package pf
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// gopfModule is the module path used to look up the version of gopf in the build info
+const gopfModule = "github.com/davidkleiven/gopf"
+
+// maxParamLength is the maximum length of slices that are stored as parameters. Longer
+// slices (typically data arrays) are only stored with their type and length
+const maxParamLength = 16
+
+// maxParamDepth is the maximum nesting level of structs that are inspected when
+// extracting parameters
+const maxParamDepth = 3
+
+// Component describes a term, source or time stepper that is part of a simulation.
+// - Name: Name of the component (e.g. the name the term was registered with)
+// - Kind: implicit, explicit, mixed, source or stepper
+// - Type: Go type of the component
+// - Parameters: Value of all exported parameters. Nested parameters are separated by dots
+type Component struct {
+	Name       string
+	Kind       string
+	Type       string
+	Parameters map[string]string
+}
+
+// Provenance holds information about how a simulation was produced, such that a run can
+// be understood and reproduced long after it was stored.
+type Provenance struct {
+	Equations   []string
+	Fields      []string
+	Components  []Component
+	Dt          float64
+	Stepper     string
+	DomainSize  []int
+	Seeds       map[string]int64
+	GoVersion   string
+	GopfVersion string
+	Hostname    string
+	CommandLine string
+}
+
+// NewProvenance collects the provenance of the solver and the process running it. The
+// global random number generator can not be inspected, thus seeds must be added to
+// Seeds by the caller (see also FieldDB.RecordSeed).
+func NewProvenance(s *Solver, domainSize []int) Provenance {
+	p := Provenance{
+		Dt:          s.Dt,
+		Stepper:     fmt.Sprintf("%T", s.Stepper),
+		DomainSize:  domainSize,
+		Seeds:       make(map[string]int64),
+		GoVersion:   runtime.Version(),
+		GopfVersion: gopfVersion(),
+		CommandLine: commandLine(os.Args),
+	}
+	p.Hostname, _ = os.Hostname()
+
+	m := s.Model
+	p.Equations = append(p.Equations, m.Equations...)
+	for _, f := range m.Fields {
+		p.Fields = append(p.Fields, f.Name)
+	}
+
+	p.Components = append(p.Components, termComponents("implicit", m.ImplicitTerms)...)
+	p.Components = append(p.Components, termComponents("explicit", m.ExplicitTerms)...)
+	mixed := make(map[string]interface{})
+	for name, t := range m.MixedTerms {
+		mixed[name] = t
+	}
+	p.Components = append(p.Components, components("mixed", mixed)...)
+	for eq, sources := range m.AllSources {
+		for i, source := range sources {
+			p.Components = append(p.Components, newComponent(fmt.Sprintf("eq%d_source%d", eq, i), "source", source))
+		}
+	}
+	p.Components = append(p.Components, newComponent("stepper", "stepper", s.Stepper))
+	return p
+}
+
+// termComponents returns the components of a set of pure terms sorted by name
+func termComponents(kind string, terms map[string]PureTerm) []Component {
+	items := make(map[string]interface{})
+	for name, t := range terms {
+		items[name] = t
+	}
+	return components(kind, items)
+}
+
+// components returns the components sorted by name
+func components(kind string, items map[string]interface{}) []Component {
+	names := []string{}
+	for name := range items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]Component, len(names))
+	for i, name := range names {
+		result[i] = newComponent(name, kind, items[name])
+	}
+	return result
+}
+
+// newComponent returns a component with all exported parameters of item
+func newComponent(name string, kind string, item interface{}) Component {
+	c := Component{
+		Name:       name,
+		Kind:       kind,
+		Type:       fmt.Sprintf("%T", item),
+		Parameters: make(map[string]string),
+	}
+	exportedParameters(reflect.ValueOf(item), "", 0, c.Parameters)
+	return c
+}
+
+// exportedParameters inserts the value of all exported fields of v into params. Structs
+// (also behind pointers and interfaces) are inspected recursively, functions, maps and
+// channels are ignored.
+func exportedParameters(v reflect.Value, prefix string, depth int, params map[string]string) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || depth >= maxParamDepth {
+		return
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			// Unexported field
+			continue
+		}
+		name := prefix + field.Name
+		value := v.Field(i)
+		switch value.Kind() {
+		case reflect.Func, reflect.Map, reflect.Chan, reflect.UnsafePointer:
+			continue
+		case reflect.Ptr, reflect.Interface:
+			if value.IsNil() {
+				params[name] = "nil"
+				continue
+			}
+			params[name] = fmt.Sprintf("%T", value.Interface())
+			exportedParameters(value, name+".", depth+1, params)
+		case reflect.Struct:
+			exportedParameters(value, name+".", depth+1, params)
+		case reflect.Slice, reflect.Array:
+			if value.Len() > maxParamLength || !isParameterKind(value.Type().Elem().Kind()) {
+				params[name] = fmt.Sprintf("%s (length %d)", value.Type(), value.Len())
+			} else {
+				params[name] = fmt.Sprintf("%v", value.Interface())
+			}
+		default:
+			params[name] = fmt.Sprintf("%v", value.Interface())
+		}
+	}
+}
+
+// isParameterKind returns true if slices with elements of the passed kind are stored
+// with their values. Complex numbers are used for work arrays and are therefore excluded
+func isParameterKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// gopfVersion returns the version of the gopf module the running binary was built with
+func gopfVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Path == gopfModule {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == gopfModule {
+			if dep.Replace != nil {
+				return fmt.Sprintf("%s => %s %s", dep.Version, dep.Replace.Path, dep.Replace.Version)
+			}
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+// commandLine joins the arguments. Arguments containing white space are quoted.
+func commandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if strings.ContainsAny(arg, " \t\n") {
+			quoted[i] = strconv.Quote(arg)
+		} else {
+			quoted[i] = arg
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// StoreProvenance stores the provenance of the current simulation. Previously stored
+// provenance of the current simulation is replaced. SaveFields calls this method
+// automatically the first time it is invoked.
+func (fdb *FieldDB) StoreProvenance(p Provenance) error {
+	if err := fdb.initialize(); err != nil {
+		return err
+	}
+	tx, err := fdb.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, table := range []string{"provenance", "provenanceEquations", "provenanceComponents", "provenanceParameters"} {
+		if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE simID=?", table), fdb.simID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	info := map[string]string{
+		"dt":          strconv.FormatFloat(p.Dt, 'g', -1, 64),
+		"stepper":     p.Stepper,
+		"domainSize":  formatShape(p.DomainSize),
+		"fields":      strings.Join(p.Fields, ","),
+		"goVersion":   p.GoVersion,
+		"gopfVersion": p.GopfVersion,
+		"hostname":    p.Hostname,
+		"commandLine": p.CommandLine,
+	}
+	for key, value := range info {
+		if _, err := tx.Exec("INSERT INTO provenance (simID, key, value) VALUES (?, ?, ?)", fdb.simID, key, value); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	for i, eq := range p.Equations {
+		if _, err := tx.Exec("INSERT INTO provenanceEquations (simID, eqNo, equation) VALUES (?, ?, ?)", fdb.simID, i, eq); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	for _, c := range p.Components {
+		_, err := tx.Exec("INSERT INTO provenanceComponents (simID, name, kind, type) VALUES (?, ?, ?, ?)",
+			fdb.simID, c.Name, c.Kind, c.Type)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		for param, value := range c.Parameters {
+			_, err := tx.Exec("INSERT INTO provenanceParameters (simID, component, parameter, value) VALUES (?, ?, ?, ?)",
+				fdb.simID, c.Name, param, value)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	for name, seed := range p.Seeds {
+		_, err := tx.Exec("INSERT OR REPLACE INTO randomSeeds (simID, name, seed) VALUES (?, ?, ?)", fdb.simID, name, seed)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RecordSeed stores the seed used to initialize a random number generator in the
+// current simulation. Name identifies the generator (e.g. "global" for math/rand).
+//
+//	rand.Seed(42)
+//	db.RecordSeed("global", 42)
+func (fdb *FieldDB) RecordSeed(name string, seed int64) error {
+	if err := fdb.initialize(); err != nil {
+		return err
+	}
+	_, err := fdb.DB.Exec("INSERT OR REPLACE INTO randomSeeds (simID, name, seed) VALUES (?, ?, ?)", fdb.simID, name, seed)
+	return err
+}
+
+// LoadProvenance loads the provenance of the simulation with the passed ID. Simulations
+// stored before provenance was captured give an empty Provenance.
+func (fdb *FieldDB) LoadProvenance(simID int) (Provenance, error) {
+	p := Provenance{Seeds: make(map[string]int64)}
+	exists, err := fdb.hasTable("provenance")
+	if err != nil || !exists {
+		return p, err
+	}
+
+	rows, err := fdb.DB.Query("SELECT key, value FROM provenance WHERE simID=?", simID)
+	if err != nil {
+		return p, err
+	}
+	info := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			rows.Close()
+			return p, err
+		}
+		info[key] = value
+	}
+	rows.Close()
+
+	if dt, ok := info["dt"]; ok {
+		if p.Dt, err = strconv.ParseFloat(dt, 64); err != nil {
+			return p, err
+		}
+	}
+	if shape := info["domainSize"]; shape != "" {
+		if p.DomainSize, err = parseShape(shape); err != nil {
+			return p, err
+		}
+	}
+	if fields := info["fields"]; fields != "" {
+		p.Fields = strings.Split(fields, ",")
+	}
+	p.Stepper = info["stepper"]
+	p.GoVersion = info["goVersion"]
+	p.GopfVersion = info["gopfVersion"]
+	p.Hostname = info["hostname"]
+	p.CommandLine = info["commandLine"]
+
+	rows, err = fdb.DB.Query("SELECT equation FROM provenanceEquations WHERE simID=? ORDER BY eqNo", simID)
+	if err != nil {
+		return p, err
+	}
+	for rows.Next() {
+		var eq string
+		if err := rows.Scan(&eq); err != nil {
+			rows.Close()
+			return p, err
+		}
+		p.Equations = append(p.Equations, eq)
+	}
+	rows.Close()
+
+	rows, err = fdb.DB.Query("SELECT name, kind, type FROM provenanceComponents WHERE simID=? ORDER BY rowid", simID)
+	if err != nil {
+		return p, err
+	}
+	index := make(map[string]int)
+	for rows.Next() {
+		c := Component{Parameters: make(map[string]string)}
+		if err := rows.Scan(&c.Name, &c.Kind, &c.Type); err != nil {
+			rows.Close()
+			return p, err
+		}
+		index[c.Name] = len(p.Components)
+		p.Components = append(p.Components, c)
+	}
+	rows.Close()
+
+	rows, err = fdb.DB.Query("SELECT component, parameter, value FROM provenanceParameters WHERE simID=?", simID)
+	if err != nil {
+		return p, err
+	}
+	for rows.Next() {
+		var component, param, value string
+		if err := rows.Scan(&component, &param, &value); err != nil {
+			rows.Close()
+			return p, err
+		}
+		if i, ok := index[component]; ok {
+			p.Components[i].Parameters[param] = value
+		}
+	}
+	rows.Close()
+
+	rows, err = fdb.DB.Query("SELECT name, seed FROM randomSeeds WHERE simID=?", simID)
+	if err != nil {
+		return p, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var seed int64
+		if err := rows.Scan(&name, &seed); err != nil {
+			return p, err
+		}
+		p.Seeds[name] = seed
+	}
+	return p, rows.Err()
+}
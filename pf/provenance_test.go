@@ -0,0 +1,107 @@
+package pf
+
+import (
+	"os"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestProvenance(t *testing.T) {
+	dbName := "./testProvenance.db"
+	defer os.Remove(dbName)
+
+	domainSize := []int{4, 4}
+	model := NewModel()
+	model.AddField(NewField("conc", 16, nil))
+	term := FourierMultiplier{Kernel: FractionalLaplacianKernel(1.0), Prefactor: 2.5, Field: "conc"}
+	model.RegisterImplicitTerm("NONLOCAL", &term, nil)
+	model.AddEquation("dconc/dt = NONLOCAL")
+	solver := NewSolver(&model, domainSize, 0.1)
+	filter := NewVandeven(4)
+	solver.Stepper.(*Euler).Filter = &filter
+
+	db, err := OpenFieldDB(dbName, domainSize, FieldDBOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.RecordSeed("global", 42); err != nil {
+		t.Errorf("%s\n", err)
+	}
+	solver.AddErrCallback(db.SaveFields)
+	if err := solver.Solve(2, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := db.LoadProvenance(db.SimID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Dt != 0.1 || p.Stepper != "*pf.Euler" || p.GoVersion != runtime.Version() {
+		t.Errorf("Unexpected provenance %v\n", p)
+	}
+	if !reflect.DeepEqual(p.DomainSize, domainSize) || !reflect.DeepEqual(p.Fields, []string{"conc"}) {
+		t.Errorf("Expected domain %v and fields [conc] got %v and %v\n", domainSize, p.DomainSize, p.Fields)
+	}
+	if !reflect.DeepEqual(p.Equations, []string{"dconc/dt=NONLOCAL"}) {
+		t.Errorf("Unexpected equations %v\n", p.Equations)
+	}
+	if p.Seeds["global"] != 42 {
+		t.Errorf("Expected seed 42 got %v\n", p.Seeds)
+	}
+	if p.CommandLine == "" || p.GopfVersion == "" {
+		t.Errorf("Command line and gopf version should be set\n")
+	}
+
+	want := []Component{
+		{
+			Name: "NONLOCAL",
+			Kind: "implicit",
+			Type: "*pf.FourierMultiplier",
+			Parameters: map[string]string{
+				"Prefactor": "2.5",
+				"Field":     "conc",
+			},
+		},
+		{
+			Name: "stepper",
+			Kind: "stepper",
+			Type: "*pf.Euler",
+			Parameters: map[string]string{
+				"Dt":          "0.1",
+				"CurrentStep": "1",
+				"Filter":      "*pf.Vandeven",
+				"Filter.Data": "[]float64 (length 1000)",
+			},
+		},
+	}
+	if len(p.Components) != len(want) {
+		t.Fatalf("Expected %d components got %v\n", len(want), p.Components)
+	}
+	for i, c := range want {
+		got := p.Components[i]
+		if got.Name != c.Name || got.Kind != c.Kind || got.Type != c.Type {
+			t.Errorf("Expected %v got %v\n", c, got)
+		}
+		for param, value := range c.Parameters {
+			if got.Parameters[param] != value {
+				t.Errorf("%s: Expected %s=%s got %s\n", c.Name, param, value, got.Parameters[param])
+			}
+		}
+	}
+
+	// Simulations without provenance give an empty result
+	p, err = db.LoadProvenance(db.SimID() + 1)
+	if err != nil || len(p.Components) != 0 || p.Stepper != "" {
+		t.Errorf("Expected empty provenance got %v (%v)\n", p, err)
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	got := commandLine([]string{"gopf", "db", "comment", "a comment"})
+	want := "gopf db comment \"a comment\""
+	if got != want {
+		t.Errorf("Expected %s got %s\n", want, got)
+	}
+}
@@ -0,0 +1,212 @@
+package pf
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// simIDTables returns the names of all tables that have a simID column. The simIDs
+// table is placed last, such that rows referring to a simulation are deleted before
+// the simulation itself
+func simIDTables(tx *sql.Tx) ([]string, error) {
+	rows, err := tx.Query("SELECT name FROM sqlite_master WHERE type='table' AND name != 'simIDs' ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+
+	tables := []string{}
+	for _, name := range names {
+		ok, err := hasColumn(tx, name, "simID")
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			tables = append(tables, name)
+		}
+	}
+	return append(tables, "simIDs"), nil
+}
+
+// maxInClause is the largest number of values passed in one IN (...) clause. SQLite
+// limits the number of variables in a statement (999 in older versions)
+const maxInClause = 500
+
+// inClause returns a list of placeholders and the corresponding arguments
+func inClause(values []int) (string, []interface{}) {
+	placeholders := make([]string, len(values))
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		placeholders[i] = "?"
+		args[i] = v
+	}
+	return strings.Join(placeholders, ","), args
+}
+
+// deleteIn executes query, which must contain one %s that is replaced by the placeholders
+// of an IN clause, for chunks of at most maxInClause values. The leading arguments are
+// passed before the values of each chunk. The total number of deleted rows is returned.
+func deleteIn(tx *sql.Tx, query string, values []int, leading ...interface{}) (int64, error) {
+	var total int64
+	for start := 0; start < len(values); start += maxInClause {
+		end := start + maxInClause
+		if end > len(values) {
+			end = len(values)
+		}
+		placeholders, args := inClause(values[start:end])
+		result, err := tx.Exec(fmt.Sprintf(query, placeholders), append(leading, args...)...)
+		if err != nil {
+			return total, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// snapshotTimesteps returns the timesteps in increasing order where the simulation has
+// fields stored in any of the passed tables
+func snapshotTimesteps(tx *sql.Tx, tables []string, simID int) ([]int, error) {
+	queries := make([]string, len(tables))
+	args := make([]interface{}, len(tables))
+	for i, table := range tables {
+		queries[i] = fmt.Sprintf("SELECT timestep FROM %s WHERE simID=?", table)
+		args[i] = simID
+	}
+	rows, err := tx.Query(strings.Join(queries, " UNION ")+" ORDER BY timestep", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	timesteps := []int{}
+	var step int
+	for rows.Next() {
+		if err := rows.Scan(&step); err != nil {
+			return nil, err
+		}
+		timesteps = append(timesteps, step)
+	}
+	return timesteps, rows.Err()
+}
+
+// DeleteSimulations removes all rows belonging to the passed simulations from all tables
+// in one transaction. The number of deleted rows in each table is returned. If dryRun is
+// true, the transaction is rolled back such that the database is left unchanged. The
+// space occupied by the deleted rows is not released until Vacuum is called.
+func (fdb *FieldDB) DeleteSimulations(simIDs []int, dryRun bool) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	if len(simIDs) == 0 {
+		return counts, nil
+	}
+
+	tx, err := fdb.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	tables, err := simIDTables(tx)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, table := range tables {
+		query := fmt.Sprintf("DELETE FROM %s WHERE simID IN (%%s)", table)
+		if counts[table], err = deleteIn(tx, query, simIDs); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if dryRun {
+		return counts, tx.Rollback()
+	}
+	return counts, tx.Commit()
+}
+
+// PruneSnapshots deletes field snapshots of the passed simulations in one transaction.
+// For each simulation, every n-th snapshot (counted from the first) and the last
+// keepLast snapshots are kept. Zero disables the corresponding rule, but at least one of
+// them must be positive. The number of deleted rows in the fields and fieldBlobs tables
+// is returned. If dryRun is true, the transaction is rolled back such that the database
+// is left unchanged.
+func (fdb *FieldDB) PruneSnapshots(simIDs []int, every int, keepLast int, dryRun bool) (map[string]int64, error) {
+	if every <= 0 && keepLast <= 0 {
+		return nil, fmt.Errorf("prune: every or keepLast must be positive")
+	}
+
+	// The write lock is taken before the timesteps are read, such that snapshots added by
+	// other connections can not change which snapshots are the last ones
+	tx, err := fdb.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if err := lockForWriting(tx, "fields"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	tables := []string{}
+	for _, table := range []string{"fields", "fieldBlobs"} {
+		var exists int
+		row := tx.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?", table)
+		if err := row.Scan(&exists); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if exists > 0 {
+			tables = append(tables, table)
+		}
+	}
+
+	counts := make(map[string]int64)
+	for _, simID := range simIDs {
+		timesteps, err := snapshotTimesteps(tx, tables, simID)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		remove := []int{}
+		for i, ts := range timesteps {
+			keep := (every > 0 && i%every == 0) || (keepLast > 0 && i >= len(timesteps)-keepLast)
+			if !keep {
+				remove = append(remove, ts)
+			}
+		}
+
+		for _, table := range tables {
+			query := fmt.Sprintf("DELETE FROM %s WHERE simID=? AND timestep IN (%%s)", table)
+			n, err := deleteIn(tx, query, remove, simID)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			counts[table] += n
+		}
+	}
+
+	if dryRun {
+		return counts, tx.Rollback()
+	}
+	return counts, tx.Commit()
+}
+
+// Vacuum rebuilds the database file such that space released by deleted rows is
+// returned to the file system
+func (fdb *FieldDB) Vacuum() error {
+	_, err := fdb.DB.Exec("VACUUM")
+	return err
+}
@@ -0,0 +1,172 @@
+package pf
+
+import (
+	"database/sql"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// createSimulations stores numSims simulations with fields at numEpochs timesteps
+func createSimulations(t *testing.T, dbName string, numSims int, numEpochs int) []int {
+	ids := []int{}
+	for i := 0; i < numSims; i++ {
+		model := NewModel()
+		model.AddField(NewField("conc", 16, nil))
+		model.AddEquation("dconc/dt = conc")
+		solver := NewSolver(&model, []int{4, 4}, 0.1)
+
+		db, err := OpenFieldDB(dbName, []int{4, 4}, FieldDBOptions{Layout: FieldLayout(i % 2)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Comment("test run")
+		db.SetAttr(map[string]float64{"temperature": 300.0})
+		db.TimeSeries(map[string]float64{"energy": 1.0}, 0)
+		db.Tag("test")
+		solver.AddErrCallback(db.SaveFields)
+		if err := solver.Solve(numEpochs, 1); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, db.SimID())
+		db.Close()
+	}
+	return ids
+}
+
+// countRows returns the number of rows belonging to the simulation in the table
+func countRows(db *sql.DB, table string, simID int) int {
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE simID=?", simID).Scan(&count)
+	return count
+}
+
+func TestDeleteSimulations(t *testing.T) {
+	dbName := "./testDeleteSimulations.db"
+	defer os.Remove(dbName)
+	ids := createSimulations(t, dbName, 3, 2)
+
+	sqlDB, _ := sql.Open("sqlite3", dbName)
+	defer sqlDB.Close()
+	db := FieldDB{DB: sqlDB}
+
+	tables := []string{"comments", "simAttributes", "timeseries", "tags", "provenance", "simIDs"}
+	counts, err := db.DeleteSimulations(ids[:2], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		if counts[table] != 2 && table != "provenance" {
+			t.Errorf("%s: Expected 2 rows in dry run got %d\n", table, counts[table])
+		}
+	}
+	if counts["fields"] != 2*16 || counts["fieldBlobs"] != 2 {
+		t.Errorf("Expected 32 rows in fields and 2 blobs got %v\n", counts)
+	}
+
+	// Dry run should not delete anything
+	for _, table := range tables {
+		if countRows(sqlDB, table, ids[0]) == 0 {
+			t.Errorf("%s: Rows were deleted in dry run\n", table)
+		}
+	}
+
+	if _, err := db.DeleteSimulations(ids[:2], false); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range append(tables, "fields", "fieldBlobs", "provenanceParameters") {
+		for _, id := range ids[:2] {
+			if n := countRows(sqlDB, table, id); n != 0 {
+				t.Errorf("%s: Expected no rows for simulation %d got %d\n", table, id, n)
+			}
+		}
+	}
+	if countRows(sqlDB, "simIDs", ids[2]) != 1 || countRows(sqlDB, "fieldBlobs", ids[2]) == 0 {
+		t.Errorf("The remaining simulation should be untouched\n")
+	}
+	if err := db.Vacuum(); err != nil {
+		t.Errorf("%s\n", err)
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	dbName := "./testPruneSnapshots.db"
+	defer os.Remove(dbName)
+	ids := createSimulations(t, dbName, 2, 10)
+
+	sqlDB, _ := sql.Open("sqlite3", dbName)
+	defer sqlDB.Close()
+	db := FieldDB{DB: sqlDB}
+
+	for i, test := range []struct {
+		every    int
+		keepLast int
+		want     []int
+	}{
+		{every: 3, keepLast: 0, want: []int{0, 3, 6, 9}},
+		{every: 0, keepLast: 2, want: []int{8, 9}},
+		{every: 4, keepLast: 1, want: []int{0, 4, 8, 9}},
+		{every: 1, keepLast: 0, want: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+	} {
+		for _, id := range ids {
+			counts, err := db.PruneSnapshots([]int{id}, test.every, test.keepLast, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			numRemoved := counts["fields"]/16 + counts["fieldBlobs"]
+			if int(numRemoved) != 10-len(test.want) {
+				t.Errorf("Test #%d: Expected %d snapshots to be removed got %v\n", i, 10-len(test.want), counts)
+			}
+		}
+	}
+
+	counts, err := db.PruneSnapshots(ids, 4, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts["fields"] != 6*16 || counts["fieldBlobs"] != 6 {
+		t.Errorf("Expected 96 rows in fields and 6 blobs to be deleted got %v\n", counts)
+	}
+	for _, id := range ids {
+		timesteps, err := db.Timesteps(id)
+		if err != nil || !reflect.DeepEqual(timesteps, []int{0, 4, 8, 9}) {
+			t.Errorf("Expected timesteps [0 4 8 9] got %v (%v)\n", timesteps, err)
+		}
+	}
+
+	if _, err := db.PruneSnapshots(ids, 0, 0, false); err == nil {
+		t.Errorf("Expected error when nothing should be kept\n")
+	}
+}
+
+func TestPruneManySnapshots(t *testing.T) {
+	dbName := "./testPruneManySnapshots.db"
+	defer os.Remove(dbName)
+	db, err := OpenFieldDB(dbName, []int{1}, FieldDBOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// More snapshots than SQLite accepts variables in one statement
+	num := 2500
+	tx, _ := db.DB.Begin()
+	for i := 0; i < num; i++ {
+		_, err := tx.Exec("INSERT INTO fieldBlobs (name, timestep, simID) VALUES ('conc', ?, ?)", i, db.SimID())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tx.Commit()
+
+	counts, err := db.PruneSnapshots([]int{db.SimID()}, 0, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts["fieldBlobs"] != int64(num-1) {
+		t.Errorf("Expected %d blobs to be deleted got %d\n", num-1, counts["fieldBlobs"])
+	}
+	if timesteps, err := db.Timesteps(db.SimID()); err != nil || !reflect.DeepEqual(timesteps, []int{num - 1}) {
+		t.Errorf("Expected timesteps [%d] got %v (%v)\n", num-1, timesteps, err)
+	}
+}
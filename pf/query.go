@@ -0,0 +1,428 @@
+package pf
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a filter expression that is matched against the attributes of a simulation
+// (simAttributes and simTextAttributes). Comparisons are combined with and, or, not and
+// parentheses
+//
+//	temperature>300 and model=='kks'
+//	not (dt >= 0.1 or solver != "rk4")
+//
+// Supported comparison operators are ==, =, !=, <, <=, > and >=. Numeric attributes are
+// compared with numbers and text attributes with quoted strings. Text attributes are
+// converted to numbers when compared with a number. The simulation ID can be referred to
+// as simID. A comparison involving an attribute that is not set is false.
+type Query struct {
+	root       queryNode
+	attributes []string
+}
+
+// queryNode is a node in the syntax tree of a query
+type queryNode interface {
+	eval(attr map[string]interface{}) bool
+}
+
+type andNode struct {
+	left, right queryNode
+}
+
+func (n andNode) eval(attr map[string]interface{}) bool {
+	return n.left.eval(attr) && n.right.eval(attr)
+}
+
+type orNode struct {
+	left, right queryNode
+}
+
+func (n orNode) eval(attr map[string]interface{}) bool {
+	return n.left.eval(attr) || n.right.eval(attr)
+}
+
+type notNode struct {
+	node queryNode
+}
+
+func (n notNode) eval(attr map[string]interface{}) bool {
+	return !n.node.eval(attr)
+}
+
+// cmpNode compares an attribute with a number (isNum is true) or a string
+type cmpNode struct {
+	key   string
+	op    string
+	num   float64
+	str   string
+	isNum bool
+}
+
+func (n cmpNode) eval(attr map[string]interface{}) bool {
+	value, ok := attr[n.key]
+	if !ok {
+		return false
+	}
+
+	var num float64
+	switch v := value.(type) {
+	case float64:
+		if !n.isNum {
+			return false
+		}
+		num = v
+	case int:
+		if !n.isNum {
+			return false
+		}
+		num = float64(v)
+	case string:
+		if !n.isNum {
+			return compare(strings.Compare(v, n.str), n.op)
+		}
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return false
+		}
+		num = parsed
+	default:
+		return false
+	}
+
+	switch {
+	case num < n.num:
+		return compare(-1, n.op)
+	case num > n.num:
+		return compare(1, n.op)
+	}
+	return compare(0, n.op)
+}
+
+// compare converts the result of a three-way comparison (-1, 0 or 1) to a boolean
+func compare(c int, op string) bool {
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// Token kinds used when parsing queries
+const (
+	tokIdent = iota
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+	tokEOF
+)
+
+type queryToken struct {
+	kind  int
+	value string
+	pos   int
+}
+
+// tokenizeQuery splits the expression into tokens
+func tokenizeQuery(expr string) ([]queryToken, error) {
+	tokens := []queryToken{}
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokLParen, value: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokRParen, value: ")", pos: i})
+			i++
+		case r == '\'' || r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("query: unterminated string at position %d", i)
+			}
+			tokens = append(tokens, queryToken{kind: tokString, value: string(runes[i+1 : end]), pos: i})
+			i = end + 1
+		case strings.ContainsRune("=!<>&|", r):
+			end := i + 1
+			if end < len(runes) && strings.ContainsRune("=&|", runes[end]) {
+				end++
+			}
+			op := string(runes[i:end])
+			switch op {
+			case "=", "==":
+				tokens = append(tokens, queryToken{kind: tokOp, value: "==", pos: i})
+			case "!=", "<", "<=", ">", ">=":
+				tokens = append(tokens, queryToken{kind: tokOp, value: op, pos: i})
+			case "&&":
+				tokens = append(tokens, queryToken{kind: tokAnd, value: op, pos: i})
+			case "||":
+				tokens = append(tokens, queryToken{kind: tokOr, value: op, pos: i})
+			case "!":
+				tokens = append(tokens, queryToken{kind: tokNot, value: op, pos: i})
+			default:
+				return nil, fmt.Errorf("query: unknown operator %s at position %d", op, i)
+			}
+			i = end
+		case unicode.IsDigit(r) || r == '.' || r == '-' || r == '+':
+			end := i + 1
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || strings.ContainsRune(".eE", runes[end]) ||
+				(strings.ContainsRune("+-", runes[end]) && strings.ContainsRune("eE", runes[end-1]))) {
+				end++
+			}
+			value := string(runes[i:end])
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("query: invalid number %s at position %d", value, i)
+			}
+			tokens = append(tokens, queryToken{kind: tokNumber, value: value, pos: i})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || strings.ContainsRune("_.", runes[end])) {
+				end++
+			}
+			word := string(runes[i:end])
+			kind := tokIdent
+			switch strings.ToLower(word) {
+			case "and":
+				kind = tokAnd
+			case "or":
+				kind = tokOr
+			case "not":
+				kind = tokNot
+			}
+			tokens = append(tokens, queryToken{kind: kind, value: word, pos: i})
+			i = end
+		default:
+			return nil, fmt.Errorf("query: unexpected character %c at position %d", r, i)
+		}
+	}
+	tokens = append(tokens, queryToken{kind: tokEOF, pos: len(runes)})
+	return tokens, nil
+}
+
+// queryParser is a recursive descent parser for the grammar
+//
+//	expr       := and {or and}
+//	and        := unary {and unary}
+//	unary      := not unary | ( expr ) | comparison
+//	comparison := identifier operator (number | string)
+type queryParser struct {
+	tokens     []queryToken
+	pos        int
+	attributes map[string]bool
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) expr() (queryNode, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) and() (queryNode, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) unary() (queryNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNot:
+		node, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node: node}, nil
+	case tokLParen:
+		node, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("query: expected ) at position %d", closing.pos)
+		}
+		return node, nil
+	case tokIdent:
+		op := p.next()
+		if op.kind != tokOp {
+			return nil, fmt.Errorf("query: expected comparison operator after %s at position %d", tok.value, op.pos)
+		}
+		value := p.next()
+		node := cmpNode{key: tok.value, op: op.value}
+		switch value.kind {
+		case tokNumber:
+			node.num, _ = strconv.ParseFloat(value.value, 64)
+			node.isNum = true
+		case tokString:
+			node.str = value.value
+		default:
+			return nil, fmt.Errorf("query: expected number or quoted string at position %d", value.pos)
+		}
+		p.attributes[tok.value] = true
+		return node, nil
+	}
+	return nil, fmt.Errorf("query: unexpected token '%s' at position %d", tok.value, tok.pos)
+}
+
+// ParseQuery parses a filter expression (see Query)
+func ParseQuery(expr string) (*Query, error) {
+	tokens, err := tokenizeQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	parser := queryParser{tokens: tokens, attributes: make(map[string]bool)}
+	root, err := parser.expr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := parser.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("query: unexpected token '%s' at position %d", tok.value, tok.pos)
+	}
+
+	q := Query{root: root}
+	for name := range parser.attributes {
+		q.attributes = append(q.attributes, name)
+	}
+	sort.Strings(q.attributes)
+	return &q, nil
+}
+
+// Match returns true if the attributes satisfy the query. The values must be either
+// float64 or string.
+func (q *Query) Match(attr map[string]interface{}) bool {
+	return q.root.eval(attr)
+}
+
+// Attributes returns the names of all attributes used in the query sorted alphabetically
+func (q *Query) Attributes() []string {
+	return q.attributes
+}
+
+// SimAttributes returns the attributes of all simulations in the database. The keys of
+// the returned map are the simulation IDs. Numeric attributes are stored as float64 and
+// text attributes as string. In addition, each simulation has the attribute simID.
+func (fdb *FieldDB) SimAttributes() (map[int]map[string]interface{}, error) {
+	result := make(map[int]map[string]interface{})
+	rows, err := fdb.DB.Query("SELECT simID FROM simIDs")
+	if err != nil {
+		return nil, err
+	}
+	simIDs, err := scanSimIDs(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range simIDs {
+		result[id] = map[string]interface{}{"simID": float64(id)}
+	}
+
+	for _, table := range []string{"simAttributes", "simTextAttributes"} {
+		rows, err := fdb.DB.Query(fmt.Sprintf("SELECT key, value, simID FROM %s", table))
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var key string
+			var value interface{}
+			var simID int
+			if err := rows.Scan(&key, &value, &simID); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if _, ok := result[simID]; !ok {
+				result[simID] = map[string]interface{}{"simID": float64(simID)}
+			}
+			switch v := value.(type) {
+			case int64:
+				result[simID][key] = float64(v)
+			case []byte:
+				result[simID][key] = string(v)
+			default:
+				result[simID][key] = v
+			}
+		}
+		rows.Close()
+	}
+	return result, nil
+}
+
+// Select returns the IDs of all simulations whose attributes match the filter expression
+// (see Query), sorted in increasing order. An empty expression matches all simulations.
+func (fdb *FieldDB) Select(where string) ([]int, error) {
+	attributes, err := fdb.SimAttributes()
+	if err != nil {
+		return nil, err
+	}
+
+	var query *Query
+	if strings.TrimSpace(where) != "" {
+		if query, err = ParseQuery(where); err != nil {
+			return nil, err
+		}
+	}
+
+	simIDs := []int{}
+	for id, attr := range attributes {
+		if query == nil || query.Match(attr) {
+			simIDs = append(simIDs, id)
+		}
+	}
+	sort.Ints(simIDs)
+	return simIDs, nil
+}
@@ -0,0 +1,122 @@
+package pf
+
+import (
+	"database/sql"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestQueryMatch(t *testing.T) {
+	attr := map[string]interface{}{
+		"simID":       float64(3),
+		"temperature": 350.0,
+		"dt":          0.01,
+		"model":       "kks",
+		"version":     "2",
+	}
+
+	for i, test := range []struct {
+		expr string
+		want bool
+	}{
+		{expr: "temperature>300", want: true},
+		{expr: "temperature > 300 and model=='kks'", want: true},
+		{expr: "temperature>300 AND model==\"wbm\"", want: false},
+		{expr: "temperature<300 or model='kks'", want: true},
+		{expr: "not (temperature >= 350)", want: false},
+		{expr: "!(temperature < 350) && dt <= 1e-2", want: true},
+		{expr: "dt != 0.01 || simID == 3", want: true},
+		{expr: "version >= 2", want: true},
+		{expr: "model > 1", want: false},
+		{expr: "temperature == 'hot'", want: false},
+		{expr: "pressure > 0", want: false},
+		{expr: "pressure != 0", want: false},
+		{expr: "not pressure > 0", want: true},
+		{expr: "temperature > -1.5e2 and (model == 'x' or model == 'kks')", want: true},
+	} {
+		q, err := ParseQuery(test.expr)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+		if got := q.Match(attr); got != test.want {
+			t.Errorf("Test #%d: %s: Expected %v got %v\n", i, test.expr, test.want, got)
+		}
+	}
+}
+
+func TestQueryAttributes(t *testing.T) {
+	q, err := ParseQuery("temperature>300 and (model=='kks' or temperature<100)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"model", "temperature"}
+	if !reflect.DeepEqual(q.Attributes(), want) {
+		t.Errorf("Expected %v got %v\n", want, q.Attributes())
+	}
+}
+
+func TestQueryParseErrors(t *testing.T) {
+	for i, expr := range []string{
+		"",
+		"temperature",
+		"temperature >",
+		"temperature > 'abc",
+		"temperature >> 3",
+		"(temperature > 3",
+		"temperature > 3)",
+		"temperature > 3 and",
+		"temperature > 3 model == 'a'",
+		"temperature > 1.2.3",
+		"temperature > 3 # comment",
+		"300 < temperature",
+	} {
+		if _, err := ParseQuery(expr); err == nil {
+			t.Errorf("Test #%d: Expected error for '%s'\n", i, expr)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	dbName := "./testSelect.db"
+	defer os.Remove(dbName)
+
+	ids := []int{}
+	for i, temp := range []float64{250.0, 350.0, 400.0} {
+		db, err := OpenFieldDB(dbName, []int{4, 4}, FieldDBOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.SetAttr(map[string]float64{"temperature": temp})
+		if i > 0 {
+			db.SetTextAttr(map[string]string{"model": []string{"", "kks", "wbm"}[i]})
+		}
+		ids = append(ids, db.SimID())
+		db.Close()
+	}
+
+	sqlDB, _ := sql.Open("sqlite3", dbName)
+	defer sqlDB.Close()
+	db := FieldDB{DB: sqlDB}
+
+	for i, test := range []struct {
+		where string
+		want  []int
+	}{
+		{where: "", want: ids},
+		{where: "temperature>300", want: ids[1:]},
+		{where: "temperature>300 and model=='kks'", want: ids[1:2]},
+		{where: "model != 'kks'", want: ids[2:]},
+		{where: "temperature > 1000", want: []int{}},
+	} {
+		got, err := db.Select(test.where)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Test #%d: Expected %v got %v (%v)\n", i, test.want, got, err)
+		}
+	}
+
+	if _, err := db.Select("temperature >"); err == nil {
+		t.Errorf("Expected error for an invalid query\n")
+	}
+}
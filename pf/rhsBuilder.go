@@ -22,47 +22,126 @@ type RHS struct {
 }
 
 // Build constructs the right-hand-side of an equation based on a string
-// representation
+// representation. It panics if the equation can not be parsed (see BuildRHS)
 func Build(eq string, m *Model) RHS {
+	rhs, err := BuildRHS(eq, m)
+	if err != nil {
+		panic(err)
+	}
+	return rhs
+}
+
+// BuildRHS constructs the right-hand-side of an equation based on a string
+// representation. An error is returned if the equation can not be parsed, for example
+// if it contains undefined names or invalid operators.
+//
+// Besides the laplacian (LAP, LAP^p and FLAP^p, see laplacianOperator), kernels
+// registered with Model.RegisterKernel can be used as operators. The kernel name must be
+// the first factor of the term (e.g. dconc/dt = GAUSS conc or dconc/dt = GAUSS*conc^3)
+func BuildRHS(eq string, m *Model) (RHS, error) {
 	sides := strings.Split(eq, "=")
 	if len(sides) != 2 {
-		panic("build: equality sign can only occur once")
+		return RHS{}, fmt.Errorf("rhsbuilder: equality sign can only occur once in %s", eq)
 	}
 
-	field := fieldNameFromLeibniz(sides[0])
+	field, err := leibnizFieldName(sides[0])
+	if err != nil {
+		return RHS{}, err
+	}
 	termsStr := SplitOnMany(sides[1], []string{"+", "-"})
 	var rhs RHS
 	for _, t := range termsStr {
+		kernels, rest := kernelPrefixes(t.SubString, m)
+		t.SubString = rest
 		name := removeKnownPrefixes(t.SubString)
 		prefixes := getKnownPrefixes(t.SubString)
+		for _, prefix := range prefixes {
+			if fracLapPrefix.MatchString(prefix) || lapPowerPrefix.MatchString(prefix) {
+				if _, err := laplacianFromPrefix(prefix); err != nil {
+					return RHS{}, err
+				}
+			}
+		}
 		prefixes = append(prefixes, t.PreceedingDelimiter)
 		if m.IsImplicitTerm(name) {
-			rhs.Denum = append(rhs.Denum, constructFunc(m.ImplicitTerms[name].Construct(m.Bricks), prefixes))
+			rhs.Denum = append(rhs.Denum, applyOperators(constructFunc(m.ImplicitTerms[name].Construct(m.Bricks), prefixes), kernels))
 		} else if m.IsExplicitTerm(name) {
-			rhs.Terms = append(rhs.Terms, constructFunc(m.ExplicitTerms[name].Construct(m.Bricks), prefixes))
+			rhs.Terms = append(rhs.Terms, applyOperators(constructFunc(m.ExplicitTerms[name].Construct(m.Bricks), prefixes), kernels))
 		} else if m.IsMixedTerm(name) {
-			rhs.Denum = append(rhs.Denum, constructFunc(m.MixedTerms[name].ConstructLinear(m.Bricks), prefixes))
-			rhs.Terms = append(rhs.Terms, constructFunc(m.MixedTerms[name].ConstructNonLinear(m.Bricks), prefixes))
+			rhs.Denum = append(rhs.Denum, applyOperators(constructFunc(m.MixedTerms[name].ConstructLinear(m.Bricks), prefixes), kernels))
+			rhs.Terms = append(rhs.Terms, applyOperators(constructFunc(m.MixedTerms[name].ConstructNonLinear(m.Bricks), prefixes), kernels))
 		} else if isBilinear(t.SubString, field, m.AllFieldNames()) {
 			t.SubString = strings.Replace(t.SubString, field, "", -1)
-			rhs.Denum = append(rhs.Denum, ConcreteTerm(t, m))
+			term, err := concreteTerm(t, m)
+			if err != nil {
+				return RHS{}, err
+			}
+			rhs.Denum = append(rhs.Denum, applyOperators(term, kernels))
 		} else {
-			rhs.Terms = append(rhs.Terms, ConcreteTerm(t, m))
+			term, err := concreteTerm(t, m)
+			if err != nil {
+				return RHS{}, err
+			}
+			rhs.Terms = append(rhs.Terms, applyOperators(term, kernels))
+		}
+	}
+	return rhs, nil
+}
+
+// kernelPrefixes returns the operators of the registered kernels that str starts with,
+// together with the remaining part of str. A multiplication sign following a kernel
+// name is removed
+func kernelPrefixes(str string, m *Model) ([]DiffOp, string) {
+	ops := []DiffOp{}
+	for {
+		match := ""
+		for name := range m.Kernels {
+			if strings.HasPrefix(str, name) && len(name) > len(match) {
+				match = name
+			}
+		}
+		if match == "" {
+			return ops, str
+		}
+		ops = append(ops, kernelOperator(m.Kernels[match]))
+		str = strings.TrimPrefix(str[len(match):], "*")
+	}
+}
+
+// applyOperators returns a term that applies the operators to the result of term
+func applyOperators(term Term, ops []DiffOp) Term {
+	if len(ops) == 0 {
+		return term
+	}
+	return func(freq Frequency, t float64, field []complex128) {
+		term(freq, t, field)
+		for _, op := range ops {
+			op(freq, field)
 		}
 	}
-	return rhs
 }
 
 // fieldNameFromLeibniz extracts a field name from a Leibniz formatted
-// differnetation operation (e.g dkappa/dt)
+// differnetation operation (e.g dkappa/dt). It panics if the string is not
+// Leibniz formatted (see leibnizFieldName)
 func fieldNameFromLeibniz(leibniz string) string {
+	name, err := leibnizFieldName(leibniz)
+	if err != nil {
+		panic(err)
+	}
+	return name
+}
+
+// leibnizFieldName extracts a field name from a Leibniz formatted differentiation
+// operation (e.g dkappa/dt)
+func leibnizFieldName(leibniz string) (string, error) {
 	if len(leibniz) <= 3 {
-		panic("rhsbuilder: Length of the Leibniz formatted string has to be at least 3")
+		return "", fmt.Errorf("rhsbuilder: Length of the Leibniz formatted string has to be at least 3")
 	}
 	if leibniz[0:1] != "d" || leibniz[len(leibniz)-3:] != "/dt" {
-		panic("rhsbuilder: Passed string is not a leibniz formatted string")
+		return "", fmt.Errorf("rhsbuilder: %s is not a leibniz formatted string", leibniz)
 	}
-	return leibniz[1 : len(leibniz)-3]
+	return leibniz[1 : len(leibniz)-3], nil
 }
 
 // isBilinear checks if the term given is bilinear in the passed field
@@ -108,21 +187,33 @@ func isBilinear(term string, field string, fieldNames []string) bool {
 // ValidName returns true if the parser knows how to parse it
 func ValidName(name string, model *Model) bool {
 	nameStripped := strings.ReplaceAll(name, " ", "")
-	specialNames := []string{"", "LAP"}
+	specialNames := []string{"", "LAP", "FLAP"}
 	for _, n := range specialNames {
 		if nameStripped == n {
 			return true
 		}
 	}
 
-	if nameStripped[:3] == "LAP" {
+	if strings.HasPrefix(nameStripped, "FLAP") {
+		nameStripped = nameStripped[4:]
+	} else if strings.HasPrefix(nameStripped, "LAP") {
 		nameStripped = nameStripped[3:]
 	}
 	return model.IsBrickName(nameStripped) || model.IsFieldName(nameStripped)
 }
 
-// ConcreteTerm returns a function representing the passed term
+// ConcreteTerm returns a function representing the passed term. It panics if the term
+// can not be parsed
 func ConcreteTerm(termDelim SubStringDelimiter, m *Model) Term {
+	term, err := concreteTerm(termDelim, m)
+	if err != nil {
+		panic(err)
+	}
+	return term
+}
+
+// concreteTerm returns a function representing the passed term
+func concreteTerm(termDelim SubStringDelimiter, m *Model) (Term, error) {
 	term := termDelim.SubString
 	sign := 1.0
 	if termDelim.PreceedingDelimiter == "-" {
@@ -143,8 +234,7 @@ func ConcreteTerm(termDelim SubStringDelimiter, m *Model) Term {
 			brickNames = append(brickNames, name)
 			powers = append(powers, GetPower(res[i][0]))
 		} else if !ValidName(name, m) {
-			msg := fmt.Sprintf("rhsBuilder: Name %s is not defined!", name)
-			panic(msg)
+			return nil, fmt.Errorf("rhsBuilder: Name %s is not defined!", name)
 		}
 	}
 
@@ -152,9 +242,10 @@ func ConcreteTerm(termDelim SubStringDelimiter, m *Model) Term {
 
 	if strings.Contains(term, "LAP") {
 		// Term with Laplace operator
-		lapWithPowReg := regexp.MustCompile("LAP*[^a-zA-Z]*")
-		res := lapWithPowReg.FindString(term)
-		lap := LaplacianN{Power: int(GetPower(res))}
+		lap, err := termLaplacian(term)
+		if err != nil {
+			return nil, err
+		}
 		return func(freq Frequency, t float64, field []complex128) {
 			for i := range field {
 				field[i] = complex(sign, 0.0)
@@ -168,8 +259,8 @@ func ConcreteTerm(termDelim SubStringDelimiter, m *Model) Term {
 					field[i] *= m.Bricks[fieldName].Get(i)
 				}
 			}
-			lap.Eval(freq, field)
-		}
+			lap(freq, field)
+		}, nil
 	}
 
 	// Term with out laplacian operators
@@ -186,7 +277,16 @@ func ConcreteTerm(termDelim SubStringDelimiter, m *Model) Term {
 				field[i] *= m.Bricks[fieldName].Get(i)
 			}
 		}
+	}, nil
+}
+
+// termLaplacian returns the laplacian operator of a term. The term contains either the
+// fractional laplacian (FLAP^p) or the laplacian raised to a power (LAP^p)
+func termLaplacian(term string) (DiffOp, error) {
+	if res := regexp.MustCompile("FLAP[^a-zA-Z]*").FindString(term); res != "" {
+		return laplacianFromPrefix(res)
 	}
+	return laplacianFromPrefix(regexp.MustCompile("LAP*[^a-zA-Z]*").FindString(term))
 }
 
 // flipSign multiplies all items in the passed array by -1
@@ -235,6 +335,18 @@ func constructFunc(term Term, prefixes []string) Term {
 		f = term
 		break
 	default:
+		if fracLapPrefix.MatchString(prefixes[0]) || lapPowerPrefix.MatchString(prefixes[0]) {
+			// The prefixes are validated by BuildRHS
+			lap, err := laplacianFromPrefix(prefixes[0])
+			if err != nil {
+				panic(err)
+			}
+			f = func(freq Frequency, t float64, field []complex128) {
+				term(freq, t, field)
+				lap(freq, field)
+			}
+			break
+		}
 		log.Printf("Unrecognized prefix %s (potential problem)", prefixes[0])
 		f = term
 	}
@@ -252,36 +364,40 @@ func knownPrefixes() []string {
 }
 
 func removeKnownPrefixes(str string) string {
-	prefixes := knownPrefixes()
-	return recursiveRemove(prefixes, str)
+	for prefix := nextKnownPrefix(str); prefix != ""; prefix = nextKnownPrefix(str) {
+		str = str[len(prefix):]
+	}
+	return str
 }
 
 func getKnownPrefixes(str string) []string {
 	pref := []string{}
-	prefixes := knownPrefixes()
-	for len(prefixes) > 0 {
-		prefix := prefixes[0]
-		prefixes = prefixes[1:]
-		if strings.HasPrefix(str, prefix) {
-			pref = append(pref, prefix)
-			prefixes = knownPrefixes()
-			str = str[len(prefix):]
-		}
+	for prefix := nextKnownPrefix(str); prefix != ""; prefix = nextKnownPrefix(str) {
+		pref = append(pref, prefix)
+		str = str[len(prefix):]
 	}
 	return pref
 }
 
-func recursiveRemove(prefixes []string, str string) string {
-	if len(prefixes) == 0 {
-		return str
-	}
-	prefix := prefixes[0]
-	prefixes = prefixes[1:]
-	if strings.HasPrefix(str, prefix) {
-		str = str[len(prefix):]
+// lapPowerPrefix matches the laplacian raised to a power (e.g. LAP^3 or LAP^0.75)
+var lapPowerPrefix = regexp.MustCompile("^LAP\\^-?\\d+\\.?\\d*")
+
+// fracLapPrefix matches the fractional laplacian raised to a real power (e.g. FLAP^0.75)
+var fracLapPrefix = regexp.MustCompile("^FLAP\\^-?\\d+\\.?\\d*")
 
-		// Reset prefix list in case there are more of the same
-		prefixes = knownPrefixes()
+// nextKnownPrefix returns the known prefix that str starts with. If str does not start
+// with a known prefix, an empty string is returned
+func nextKnownPrefix(str string) string {
+	if prefix := fracLapPrefix.FindString(str); prefix != "" {
+		return prefix
+	}
+	if prefix := lapPowerPrefix.FindString(str); prefix != "" {
+		return prefix
+	}
+	for _, prefix := range knownPrefixes() {
+		if strings.HasPrefix(str, prefix) {
+			return prefix
+		}
 	}
-	return recursiveRemove(prefixes, str)
+	return ""
 }
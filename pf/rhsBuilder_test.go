@@ -384,6 +384,10 @@ func TestRemoveKnownPrefixes(t *testing.T){
 			str: "*LAP^2mystring",
 			expect: "mystring",
 		},
+		{
+			str: "FLAP^0.75mystring",
+			expect: "mystring",
+		},
 	}{
 		res := removeKnownPrefixes(test.str)
 
@@ -426,6 +430,10 @@ func TestGetKnownPrefixes(t *testing.T) {
 			str: "*LAP^2mystring",
 			expect: []string{"*", "LAP^2"},
 		},
+		{
+			str: "FLAP^0.75*LAPmystring",
+			expect: []string{"FLAP^0.75", "*", "LAP"},
+		},
 	}{
 		res := getKnownPrefixes(test.str)
 
@@ -507,4 +515,28 @@ func TestConstructorWithPrefixHandling(t *testing.T) {
 			}
 		}
 	}
-}	
\ No newline at end of file
+}	
+func TestBuildRHSErrors(t *testing.T) {
+	model := NewModel()
+	model.AddField(NewField("conc", 8, nil))
+	model.RegisterKernel("GAUSS", func(k float64) float64 { return -math.Exp(-k * k) })
+
+	for i, test := range []struct {
+		eq      string
+		wantErr bool
+	}{
+		{eq: "dconc/dt=LAP^0.75conc", wantErr: false},
+		{eq: "dconc/dt=FLAP^0.75conc", wantErr: false},
+		{eq: "dconc/dt=GAUSSconc", wantErr: false},
+		{eq: "dconc/dt=GAUSS*conc^3", wantErr: false},
+		{eq: "dconc/dt=m1*conc", wantErr: true},
+		{eq: "dconc/dt=UNKNOWNconc", wantErr: true},
+		{eq: "dconc/dt", wantErr: true},
+		{eq: "conc/dt=LAPconc", wantErr: true},
+	} {
+		_, err := BuildRHS(test.eq, &model)
+		if (err != nil) != test.wantErr {
+			t.Errorf("Test #%d: %s: Expected error %v got %v\n", i, test.eq, test.wantErr, err)
+		}
+	}
+}
@@ -62,7 +62,7 @@ func (rk *RK4) Step(m *Model) {
 		}
 		copy(m.Fields[i].Data, final[i].Data)
 
-		if rk.Filter != nil {
+		if filterApplies(rk.Filter, m.Fields[i].Name) {
 			ApplyModalFilter(rk.Filter, rk.FT.Freq, m.Fields[i].Data)
 		}
 	}
@@ -323,7 +323,7 @@ func TestDiagonalShermannMorrison(t *testing.T) {
 	}
 }
 
-func ExampleModel() (*Model, *Solver) {
+func exampleModel() (*Model, *Solver) {
 	N := 16
 	f1 := NewField("conc", N*N, nil)
 	for i := range f1.Data {
@@ -341,7 +341,7 @@ func ExampleModel() (*Model, *Solver) {
 }
 
 func TestRevertOrientationVector(t *testing.T) {
-	model, solver := ExampleModel()
+	model, solver := exampleModel()
 	N := int(math.Sqrt(float64(len(model.Fields[0].Data))))
 
 	stepper := NewSDD([]int{N, N}, model)
@@ -381,7 +381,7 @@ func TestRevertOrientationVector(t *testing.T) {
 }
 
 func TestPanicOnZeroTimeStep(t *testing.T) {
-	model, solver := ExampleModel()
+	model, solver := exampleModel()
 	N := int(math.Sqrt(float64(model.NumNodes())))
 	stepper := NewSDD([]int{N, N}, model)
 	orient := make([]float64, N*N)
@@ -455,7 +455,7 @@ func TestHouseHolderDenum(t *testing.T) {
 	// x_{n+1} = x_n + dt*(I - sigma*vv^T)x_{n+1}
 	sigma := 1.0
 	dt := 0.8
-	model, _ := ExampleModel()
+	model, _ := exampleModel()
 	N := int(math.Sqrt(float64(model.NumNodes())))
 
 	sdd := NewSDD([]int{N, N}, model)
@@ -0,0 +1,102 @@
+package pf
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// TimeSeriesSink is a generic interface for destinations of timeseries data. When sinks
+// are added to a solver, the latest values of the monitors are written to all sinks after
+// each epoch. timestep is the number of steps performed and time is the physical time
+type TimeSeriesSink interface {
+	WriteTimeSeries(data map[string]float64, timestep int, time float64) error
+}
+
+// sortedKeys returns the keys of data in sorted order
+func sortedKeys(data map[string]float64) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// CsvSink appends timeseries data to a CSV file. Each row has the format
+// timestep, time, key, value
+// The file is opened and closed on each write, such that the data survives a crash
+// and the file can be inspected while the simulation is running
+type CsvSink struct {
+	Fname string
+}
+
+// NewCsvSink returns a new CsvSink writing to fname
+func NewCsvSink(fname string) CsvSink {
+	return CsvSink{Fname: fname}
+}
+
+// WriteTimeSeries appends the data to the file. A header is written if the file is empty
+func (cs *CsvSink) WriteTimeSeries(data map[string]float64, timestep int, time float64) error {
+	out, err := os.OpenFile(cs.Fname, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	stat, err := out.Stat()
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(out)
+	if stat.Size() == 0 {
+		writer.Write([]string{"timestep", "time", "key", "value"})
+	}
+	for _, k := range sortedKeys(data) {
+		writer.Write([]string{
+			fmt.Sprintf("%d", timestep),
+			fmt.Sprintf("%e", time),
+			k,
+			fmt.Sprintf("%e", data[k]),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// JSONLinesRecord is the format of each line written by JSONLinesSink
+type JSONLinesRecord struct {
+	Timestep int                `json:"timestep"`
+	Time     float64            `json:"time"`
+	Values   map[string]float64 `json:"values"`
+}
+
+// JSONLinesSink appends timeseries data to a JSON Lines file (one JSON object per line).
+// Each line has the format of JSONLinesRecord
+type JSONLinesSink struct {
+	Fname string
+}
+
+// NewJSONLinesSink returns a new JSONLinesSink writing to fname
+func NewJSONLinesSink(fname string) JSONLinesSink {
+	return JSONLinesSink{Fname: fname}
+}
+
+// WriteTimeSeries appends one line to the file
+func (js *JSONLinesSink) WriteTimeSeries(data map[string]float64, timestep int, time float64) error {
+	line, err := json.Marshal(JSONLinesRecord{Timestep: timestep, Time: time, Values: data})
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(js.Fname, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = out.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,155 @@
+package pf
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"os"
+	"testing"
+)
+
+func solveWithSink(sink TimeSeriesSink) error {
+	return restartWithSink(sink, 0)
+}
+
+func restartWithSink(sink TimeSeriesSink, startEpoch int) error {
+	N := 8
+	m := NewModel()
+	conc := NewField("conc", N, nil)
+	for i := range conc.Data {
+		conc.Data[i] = 0.5
+	}
+	m.AddField(conc)
+	m.AddEquation("dconc/dt = LAP conc")
+
+	solver := NewSolver(&m, []int{N}, 0.1)
+	mean := NewMeanMonitor("conc", N)
+	solver.AddMonitor(&mean)
+	solver.AddSink(sink)
+	solver.StartEpoch = startEpoch
+	return solver.Solve(2, 5)
+}
+
+func TestCsvSink(t *testing.T) {
+	fname := "sinkTest.csv"
+	defer os.Remove(fname)
+	sink := NewCsvSink(fname)
+	solveWithSink(&sink)
+
+	infile, err := os.Open(fname)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	defer infile.Close()
+	records, err := csv.NewReader(infile).ReadAll()
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	expect := [][]string{
+		{"timestep", "time", "key", "value"},
+		{"5", "5.000000e-01", "MeanMonitor_conc", "5.000000e-01"},
+		{"10", "1.000000e+00", "MeanMonitor_conc", "5.000000e-01"},
+	}
+	if len(records) != len(expect) {
+		t.Errorf("Expected %d records got %d\n", len(expect), len(records))
+		return
+	}
+	for i := range expect {
+		for j := range expect[i] {
+			if records[i][j] != expect[i][j] {
+				t.Errorf("Row %d: Expected %v got %v\n", i, expect[i], records[i])
+				break
+			}
+		}
+	}
+}
+
+func TestJSONLinesSink(t *testing.T) {
+	fname := "sinkTest.jsonl"
+	defer os.Remove(fname)
+	sink := NewJSONLinesSink(fname)
+	solveWithSink(&sink)
+
+	infile, err := os.Open(fname)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	defer infile.Close()
+
+	scanner := bufio.NewScanner(infile)
+	expectSteps := []int{5, 10}
+	expectTimes := []float64{0.5, 1.0}
+	count := 0
+	for scanner.Scan() {
+		var record JSONLinesRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Errorf("%s\n", err)
+			return
+		}
+		if record.Timestep != expectSteps[count] || math.Abs(record.Time-expectTimes[count]) > 1e-10 {
+			t.Errorf("Expected (%d, %f) got (%d, %f)\n", expectSteps[count], expectTimes[count], record.Timestep, record.Time)
+		}
+		if math.Abs(record.Values["MeanMonitor_conc"]-0.5) > 1e-10 {
+			t.Errorf("Expected mean 0.5 got %v\n", record.Values)
+		}
+		count++
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 lines got %d\n", count)
+	}
+}
+
+func TestSinkErrorStopsSolver(t *testing.T) {
+	for i, sink := range []TimeSeriesSink{
+		&CsvSink{Fname: "/nonexistent/dir/sink.csv"},
+		&JSONLinesSink{Fname: "/nonexistent/dir/sink.jsonl"},
+	} {
+		if err := solveWithSink(sink); err == nil {
+			t.Errorf("Test #%d: Expected the sink error to be returned from Solve\n", i)
+		}
+	}
+}
+
+func TestRestartIntoSameSink(t *testing.T) {
+	fname := "sinkRestartTest.csv"
+	defer os.Remove(fname)
+	sink := NewCsvSink(fname)
+	if err := restartWithSink(&sink, 0); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	// The restarted run continues from epoch 2 and appends to the same file
+	if err := restartWithSink(&sink, 2); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	infile, err := os.Open(fname)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	defer infile.Close()
+	records, err := csv.NewReader(infile).ReadAll()
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	expect := []string{"5", "10", "15", "20"}
+	if len(records) != len(expect)+1 {
+		t.Errorf("Expected %d records got %d\n", len(expect)+1, len(records))
+		return
+	}
+	for i, step := range expect {
+		if records[i+1][0] != step {
+			t.Errorf("Row %d: Expected timestep %s got %s\n", i+1, step, records[i+1][0])
+		}
+	}
+}
@@ -2,6 +2,7 @@ package pf
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 
 	"github.com/davidkleiven/gopf/pfutil"
@@ -11,6 +12,10 @@ import (
 // iteration
 type SolverCB func(s *Solver, epoch int)
 
+// SolverErrCB is a callback that can fail. If an error is returned, the solver stops and
+// Solve returns the error
+type SolverErrCB func(s *Solver, epoch int) error
+
 // TimeStepper is a generic interface for a the time stepper types
 type TimeStepper interface {
 	Step(m *Model)
@@ -27,13 +32,18 @@ type FourierTransform interface {
 
 // Solver is a type used to solve phase field equations
 type Solver struct {
-	Model      *Model
-	Dt         float64
-	FT         FourierTransform
-	Stepper    TimeStepper
-	Callbacks  []SolverCB
-	Monitors   []Monitor
-	StartEpoch int
+	Model        *Model
+	Dt           float64
+	FT           FourierTransform
+	Stepper      TimeStepper
+	Callbacks    []SolverCB
+	ErrCallbacks []SolverErrCB
+	Monitors     []Monitor
+	Sinks        []TimeSeriesSink
+	StartEpoch   int
+
+	// numSteps is the number of timesteps performed by the solver
+	numSteps int
 }
 
 // NewSolver initializes a new solver
@@ -66,10 +76,17 @@ func (s *Solver) AddCallback(cb SolverCB) {
 	s.Callbacks = append(s.Callbacks, cb)
 }
 
+// AddErrCallback appends a new callback that can fail. The callbacks added via
+// AddErrCallback are executed after the ones added via AddCallback
+func (s *Solver) AddErrCallback(cb SolverErrCB) {
+	s.ErrCallbacks = append(s.ErrCallbacks, cb)
+}
+
 // Propagate evolves the equation a fixed number of steps
 func (s *Solver) Propagate(nsteps int) {
 	for i := 0; i < nsteps; i++ {
 		s.Stepper.Step(s.Model)
+		s.numSteps++
 		t := s.Stepper.GetTime()
 		for j := range s.Model.ImplicitTerms {
 			s.Model.ImplicitTerms[j].OnStepFinished(t, s.Model.Bricks)
@@ -102,21 +119,45 @@ func (s *Solver) SetStepper(name string) {
 	}
 }
 
-// Solve solves the equation
-func (s *Solver) Solve(nepochs int, nsteps int) {
+// Solve solves the equation. If one of the callbacks added via AddErrCallback or one of
+// the sinks fails, the solver stops and the error is returned. When StartEpoch is set,
+// the timesteps passed to the sinks start at StartEpoch*nsteps
+func (s *Solver) Solve(nepochs int, nsteps int) error {
+	// A restarted run continues counting timesteps from the restart point, such that
+	// the timesteps passed to the sinks do not collide with the ones of the previous run
+	if start := s.StartEpoch * nsteps; s.numSteps < start {
+		s.numSteps = start
+	}
 	for i := 0; i < nepochs; i++ {
 		s.Propagate(nsteps)
 
 		for _, cb := range s.Callbacks {
 			cb(s, i+s.StartEpoch)
 		}
+		for _, cb := range s.ErrCallbacks {
+			if err := cb(s, i+s.StartEpoch); err != nil {
+				return fmt.Errorf("solver: callback failed in epoch %d: %w", i+s.StartEpoch, err)
+			}
+		}
 
-		// Update monitors
+		// Update monitors. The derived fields are synchronized such that they
+		// represent the real space values of the current state
+		if len(s.Monitors) > 0 {
+			s.Model.SyncDerivedFields()
+		}
+		t := s.Stepper.GetTime()
 		for i := range s.Monitors {
+			if tm, ok := s.Monitors[i].(TimeMonitor); ok {
+				tm.RecordTime(t)
+			}
 			s.Monitors[i].Add(s.Model.Bricks)
 		}
+		if err := s.writeToSinks(t); err != nil {
+			return fmt.Errorf("solver: sink failed in epoch %d: %w", i+s.StartEpoch, err)
+		}
 		log.Printf("Step %d of %d (%d %%)\n", i, nepochs, 100*i/nepochs)
 	}
+	return nil
 }
 
 // AddMonitor adds a new monitor to the solver
@@ -124,6 +165,33 @@ func (s *Solver) AddMonitor(m Monitor) {
 	s.Monitors = append(s.Monitors, m)
 }
 
+// AddSink adds a new sink that receives the latest monitor values after each epoch
+func (s *Solver) AddSink(sink TimeSeriesSink) {
+	s.Sinks = append(s.Sinks, sink)
+}
+
+// writeToSinks passes the latest values of all monitors implementing LatestMonitor
+// to the sinks
+func (s *Solver) writeToSinks(t float64) error {
+	if len(s.Sinks) == 0 {
+		return nil
+	}
+	data := make(map[string]float64)
+	for _, m := range s.Monitors {
+		if lm, ok := m.(LatestMonitor); ok {
+			for k, v := range lm.Latest() {
+				data[k] = v
+			}
+		}
+	}
+	for _, sink := range s.Sinks {
+		if err := sink.WriteTimeSeries(data, s.numSteps, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // JSONifyMonitors return a JSON representation of all the monitors
 func (s *Solver) JSONifyMonitors() []byte {
 	res, err := json.Marshal(s.Monitors)
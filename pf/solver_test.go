@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math"
 	"testing"
+
+	"github.com/davidkleiven/gopf/pfutil"
 )
 
 func TestSolverDiffusion(t *testing.T) {
@@ -33,6 +35,68 @@ func TestSolverDiffusion(t *testing.T) {
 	}
 }
 
+func TestSolverDiffusion1D(t *testing.T) {
+	m := NewModel()
+	N := 32
+	conc := NewField("conc", N, nil)
+	for i := range conc.Data {
+		conc.Data[i] = complex(math.Cos(2.0*math.Pi*float64(i)/float64(N)), 0.0)
+	}
+	m.AddField(conc)
+	m.AddEquation("dconc/dt = LAP conc")
+
+	dt := 0.1
+	solver := NewSolver(&m, []int{N}, dt)
+	solver.Solve(1, 10)
+
+	// The semi-implicit euler scheme damps the mode by 1/(1 + dt*k^2) each step
+	k := 2.0 * math.Pi / float64(N)
+	damping := math.Pow(1.0/(1.0+dt*k*k), 10)
+	for i := range conc.Data {
+		expect := damping * math.Cos(2.0*math.Pi*float64(i)/float64(N))
+		if math.Abs(real(conc.Data[i])-expect) > 1e-8 {
+			t.Errorf("Node %d: Expected %f got %f\n", i, expect, real(conc.Data[i]))
+		}
+	}
+}
+
+func TestSolverNonPeriodicBoundaries(t *testing.T) {
+	N := 32
+	dt := 0.1
+	for _, bc := range []pfutil.BoundaryCondition{pfutil.NeumannDCT2, pfutil.DirichletDST1} {
+		// Lowest mode satisfying the boundary condition
+		k := math.Pi / float64(N)
+		shift := 0.5
+		mode := math.Cos
+		if bc == pfutil.DirichletDST1 {
+			k = math.Pi / float64(N+1)
+			shift = 1.0
+			mode = math.Sin
+		}
+
+		m := NewModel()
+		conc := NewField("conc", N, nil)
+		for i := range conc.Data {
+			conc.Data[i] = complex(mode(k*(float64(i)+shift)), 0.0)
+		}
+		m.AddField(conc)
+		m.AddEquation("dconc/dt = LAP conc")
+
+		solver := NewSolver(&m, []int{N}, dt)
+		solver.FT = pfutil.NewR2RTransform([]int{N}, []pfutil.BoundaryCondition{bc})
+		solver.SetStepper("euler")
+		solver.Solve(1, 10)
+
+		damping := math.Pow(1.0/(1.0+dt*k*k), 10)
+		for i := range conc.Data {
+			expect := damping * mode(k*(float64(i)+shift))
+			if math.Abs(real(conc.Data[i])-expect) > 1e-8 {
+				t.Errorf("%s: Node %d: Expected %f got %f\n", bc, i, expect, real(conc.Data[i]))
+			}
+		}
+	}
+}
+
 func TestJSONifyMonitors(t *testing.T) {
 	m := NewModel()
 	N := 16
@@ -24,12 +24,14 @@ func NewSquareGradient(field string, domainSize []int) SquaredGradient {
 		Field:  field,
 		Factor: 1.0,
 	}
-	if len(domainSize) == 2 {
+	if len(domainSize) == 1 {
+		sq.FT = pfutil.NewFFTW(domainSize)
+	} else if len(domainSize) == 2 {
 		sq.FT = sfft.NewFFT2(domainSize[0], domainSize[1])
 	} else if len(domainSize) == 3 {
 		sq.FT = sfft.NewFFT3(domainSize[0], domainSize[1], domainSize[2])
 	} else {
-		panic("squaregradient: Domain size has to be of length 2 or 3")
+		panic("squaregradient: Domain size has to be of length 1, 2 or 3")
 	}
 	return sq
 }
@@ -0,0 +1,157 @@
+package pf
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Tag adds tags to the current simulation. Tags can be used to group simulations, for
+// example all runs belonging to one parameter study
+//
+//	db.Tag("study-A", "coarse-grid")
+func (fdb *FieldDB) Tag(tags ...string) error {
+	if err := fdb.initialize(); err != nil {
+		return err
+	}
+	return fdb.AddTags(fdb.simID, tags...)
+}
+
+// AddTags adds tags to the simulation with the passed ID. Tags that are already present
+// are ignored.
+func (fdb *FieldDB) AddTags(simID int, tags ...string) error {
+	if err := fdb.checkTagsTable(); err != nil {
+		return err
+	}
+	tx, err := fdb.DB.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare("INSERT OR IGNORE INTO tags (simID, tag) VALUES (?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			tx.Rollback()
+			return fmt.Errorf("tags: empty tags are not allowed")
+		}
+		if _, err := stmt.Exec(simID, tag); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RemoveTags removes tags from the simulation with the passed ID. Tags that are not
+// present are ignored.
+func (fdb *FieldDB) RemoveTags(simID int, tags ...string) error {
+	if err := fdb.checkTagsTable(); err != nil {
+		return err
+	}
+	tx, err := fdb.DB.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare("DELETE FROM tags WHERE simID=? AND tag=?")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, tag := range tags {
+		if _, err := stmt.Exec(simID, strings.TrimSpace(tag)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Tags returns the tags of the simulation with the passed ID sorted alphabetically
+func (fdb *FieldDB) Tags(simID int) ([]string, error) {
+	if err := fdb.checkTagsTable(); err != nil {
+		return nil, err
+	}
+	rows, err := fdb.DB.Query("SELECT tag FROM tags WHERE simID=? ORDER BY tag", simID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// FilterByTags returns the IDs of all simulations that have all the passed tags. The
+// IDs are sorted in increasing order, which is the order in which they were created.
+func (fdb *FieldDB) FilterByTags(tags ...string) ([]int, error) {
+	if err := fdb.checkTagsTable(); err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("tags: at least one tag must be given")
+	}
+
+	// Duplicated tags must be removed, since they would never satisfy the count below
+	unique := make(map[string]bool)
+	placeholders := []string{}
+	args := []interface{}{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if unique[tag] {
+			continue
+		}
+		unique[tag] = true
+		placeholders = append(placeholders, "?")
+		args = append(args, tag)
+	}
+	args = append(args, len(unique))
+
+	query := fmt.Sprintf("SELECT simID FROM tags WHERE tag IN (%s) GROUP BY simID "+
+		"HAVING COUNT(DISTINCT tag)=? ORDER BY simID", strings.Join(placeholders, ","))
+	rows, err := fdb.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanSimIDs(rows)
+}
+
+// scanSimIDs reads the simulation IDs from rows with one column
+func scanSimIDs(rows *sql.Rows) ([]int, error) {
+	simIDs := []int{}
+	for rows.Next() {
+		var simID int
+		if err := rows.Scan(&simID); err != nil {
+			return nil, err
+		}
+		simIDs = append(simIDs, simID)
+	}
+	return simIDs, rows.Err()
+}
+
+// checkTagsTable returns an error if the database does not have the tags table
+func (fdb *FieldDB) checkTagsTable() error {
+	exists, err := fdb.hasTable("tags")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("tags: the database has no tags table. Upgrade the database with Migrate (gopf db migrate)")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package pf
+
+import (
+	"database/sql"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSimIDsAreUnique(t *testing.T) {
+	dbName := "./testSimIDsUnique.db"
+	defer os.Remove(dbName)
+
+	previous := 0
+	for i := 0; i < 5; i++ {
+		db, err := OpenFieldDB(dbName, []int{4, 4}, FieldDBOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if db.SimID() <= previous {
+			t.Errorf("Expected simulation ID larger than %d got %d\n", previous, db.SimID())
+		}
+		previous = db.SimID()
+		db.Close()
+	}
+}
+
+func TestTags(t *testing.T) {
+	dbName := "./testTags.db"
+	defer os.Remove(dbName)
+
+	ids := []int{}
+	for i := 0; i < 3; i++ {
+		db, err := OpenFieldDB(dbName, []int{4, 4}, FieldDBOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Tag("study"); err != nil {
+			t.Errorf("%s\n", err)
+		}
+		ids = append(ids, db.SimID())
+		db.Close()
+	}
+
+	sqlDB, _ := sql.Open("sqlite3", dbName)
+	defer sqlDB.Close()
+	db := FieldDB{DB: sqlDB}
+
+	if err := db.AddTags(ids[0], "coarse", "study"); err != nil {
+		t.Errorf("%s\n", err)
+	}
+	if err := db.AddTags(ids[2], "coarse", "fine"); err != nil {
+		t.Errorf("%s\n", err)
+	}
+	if err := db.AddTags(ids[1], " "); err == nil {
+		t.Errorf("Expected error for an empty tag\n")
+	}
+
+	tags, err := db.Tags(ids[0])
+	if err != nil || !reflect.DeepEqual(tags, []string{"coarse", "study"}) {
+		t.Errorf("Expected [coarse study] got %v (%v)\n", tags, err)
+	}
+
+	for i, test := range []struct {
+		tags []string
+		want []int
+	}{
+		{tags: []string{"study"}, want: ids},
+		{tags: []string{"study", "coarse"}, want: []int{ids[0], ids[2]}},
+		{tags: []string{"fine", "fine"}, want: []int{ids[2]}},
+		{tags: []string{"unknown"}, want: []int{}},
+	} {
+		got, err := db.FilterByTags(test.tags...)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Test #%d: Expected %v got %v (%v)\n", i, test.want, got, err)
+		}
+	}
+
+	if err := db.RemoveTags(ids[2], "coarse", "notPresent"); err != nil {
+		t.Errorf("%s\n", err)
+	}
+	got, _ := db.FilterByTags("coarse")
+	if !reflect.DeepEqual(got, []int{ids[0]}) {
+		t.Errorf("Expected [%d] got %v\n", ids[0], got)
+	}
+
+	if _, err := db.FilterByTags(); err == nil {
+		t.Errorf("Expected error when no tags are given\n")
+	}
+}
+
+func TestTagsMissingTable(t *testing.T) {
+	dbName := "./testTagsMissingTable.db"
+	defer os.Remove(dbName)
+	sqlDB := createLegacyDB(t, dbName)
+	defer sqlDB.Close()
+
+	db := FieldDB{DB: sqlDB}
+	if _, err := db.Tags(7); err == nil {
+		t.Errorf("Expected error when the tags table does not exist\n")
+	}
+}
@@ -66,6 +66,8 @@ func (th *TensorialHessian) GetCoeff(i, j int) float64 {
 	d := 2
 	if len(th.K) == 9 {
 		d = 3
+	} else if len(th.K) == 1 {
+		d = 1
 	}
 	return th.K[i*d+j]
 }
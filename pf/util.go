@@ -42,6 +42,20 @@ func DerivedFieldCalcFromDesc(desc string, fields []Field) DerivedFieldCalc {
 		fieldMap[fields[i].Name] = &fields[i]
 	}
 
+	fieldNames, powers := parseFactors(desc)
+	return func(data []complex128) {
+		for i := range data {
+			data[i] = 1.0
+			for j := range fieldNames {
+				data[i] *= cmplx.Pow(fieldMap[fieldNames[j]].Data[i], complex(powers[j], 0.0))
+			}
+		}
+	}
+}
+
+// parseFactors splits a description of a product (e.g. conc^2*eta) into the
+// names of the factors and their powers
+func parseFactors(desc string) ([]string, []float64) {
 	fieldReg := regexp.MustCompile("[^\\*]*")
 	res := fieldReg.FindAllStringSubmatch(desc, -1)
 
@@ -53,15 +67,7 @@ func DerivedFieldCalcFromDesc(desc string, fields []Field) DerivedFieldCalc {
 		fieldNames[i] = nameNoPow.FindString(res[i][0])
 		powers[i] = GetPower(res[i][0])
 	}
-
-	return func(data []complex128) {
-		for i := range data {
-			data[i] = 1.0
-			for j := range fieldNames {
-				data[i] *= cmplx.Pow(fieldMap[fieldNames[j]].Data[i], complex(powers[j], 0.0))
-			}
-		}
-	}
+	return fieldNames, powers
 }
 
 // GetPower returns the power from a string
@@ -121,14 +127,31 @@ type ModalFilter interface {
 	Eval(x float64) float64
 }
 
+// FrequencyFilter is a modal filter that depends on the full frequency vector,
+// and not only on its length (e.g. filters that act along each axis separately).
+// If the filter passed to ApplyModalFilter implements this interface, EvalFreq
+// is used instead of Eval
+type FrequencyFilter interface {
+	ModalFilter
+	EvalFreq(freq []float64) float64
+}
+
 // ApplyModalFilter applies the filter f in-place to data
 func ApplyModalFilter(filter ModalFilter, freq Frequency, data []complex128) {
 	for i := range data {
-		f := freq(i)
-		fRad := math.Sqrt(pfutil.Dot(f, f))
-		value := fRad * 2.0 / math.Pi
-		data[i] *= complex(filter.Eval(value), 0.0)
+		data[i] *= complex(filterValue(filter, freq(i)), 0.0)
+	}
+}
+
+// filterValue evaluates the filter at the passed frequency. Filters implementing
+// FrequencyFilter are evaluated via EvalFreq, other filters are evaluated at the
+// length of the frequency vector scaled by 2/pi
+func filterValue(filter ModalFilter, f []float64) float64 {
+	if ff, ok := filter.(FrequencyFilter); ok {
+		return ff.EvalFreq(f)
 	}
+	fRad := math.Sqrt(pfutil.Dot(f, f))
+	return filter.Eval(fRad * 2.0 / math.Pi)
 }
 
 // SubStringDelimiter is a type that represents a substring as well as
@@ -0,0 +1,362 @@
+package pf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/davidkleiven/gopf/pfutil"
+)
+
+// VTKFormat determines how the data arrays are stored in the .vti files
+type VTKFormat int
+
+const (
+	// VTKAppendedRaw stores the data as raw binary data in the AppendedData section
+	VTKAppendedRaw VTKFormat = iota
+
+	// VTKBase64 stores the data base64 encoded inside each DataArray element
+	VTKBase64
+)
+
+// VTKVector is a vector quantity that is written to the .vti files. Calc should return
+// one slice per component with the real space values at each node.
+type VTKVector struct {
+	Name string
+	Calc func(s *Solver) [][]float64
+}
+
+// VTKDataArray represents the DataArray item in the VTK XML format
+type VTKDataArray struct {
+	XMLName       xml.Name `xml:"DataArray"`
+	Type          string   `xml:"type,attr"`
+	Name          string   `xml:"Name,attr"`
+	NumComponents int      `xml:"NumberOfComponents,attr,omitempty"`
+	Format        string   `xml:"format,attr"`
+	Offset        *int     `xml:"offset,attr"`
+	Value         string   `xml:",chardata"`
+}
+
+// VTKPointData represents the PointData item in the VTK XML format
+type VTKPointData struct {
+	XMLName    xml.Name       `xml:"PointData"`
+	Scalars    string         `xml:"Scalars,attr,omitempty"`
+	Vectors    string         `xml:"Vectors,attr,omitempty"`
+	DataArrays []VTKDataArray `xml:"DataArray"`
+}
+
+// VTKPiece represents the Piece item in the VTK XML format
+type VTKPiece struct {
+	XMLName   xml.Name `xml:"Piece"`
+	Extent    string   `xml:"Extent,attr"`
+	PointData VTKPointData
+}
+
+// VTKImageData represents the ImageData item in the VTK XML format
+type VTKImageData struct {
+	XMLName     xml.Name `xml:"ImageData"`
+	WholeExtent string   `xml:"WholeExtent,attr"`
+	Origin      string   `xml:"Origin,attr"`
+	Spacing     string   `xml:"Spacing,attr"`
+	Piece       VTKPiece
+}
+
+// PVDDataSet represents one entry in a ParaView collection file
+type PVDDataSet struct {
+	XMLName  xml.Name `xml:"DataSet"`
+	Timestep float64  `xml:"timestep,attr"`
+	Group    string   `xml:"group,attr"`
+	Part     int      `xml:"part,attr"`
+	File     string   `xml:"file,attr"`
+}
+
+// PVDCollection represents the Collection item in a ParaView collection file
+type PVDCollection struct {
+	XMLName  xml.Name     `xml:"Collection"`
+	DataSets []PVDDataSet `xml:"DataSet"`
+}
+
+// PVD represents a ParaView collection file (.pvd)
+type PVD struct {
+	XMLName    xml.Name `xml:"VTKFile"`
+	Type       string   `xml:"type,attr"`
+	Version    string   `xml:"version,attr"`
+	ByteOrder  string   `xml:"byte_order,attr"`
+	Collection PVDCollection
+}
+
+// VTKIO writes fields to self-contained VTK ImageData files (.vti) that can be opened
+// directly in ParaView. After each epoch, a ParaView collection file (prefix.pvd) that
+// references all .vti files together with the simulation time is updated. Thus, the
+// time series can be loaded in ParaView while the simulation is running. All fields and
+// derived fields in the model are written. Vector quantities (e.g. gradients and currents)
+// can be added via AddVector.
+//
+//	vtk := pf.NewVTKIO("run", domainSize)
+//	vtk.AddGradient("conc")
+//	solver.AddCallback(vtk.SaveFields)
+type VTKIO struct {
+	Prefix     string
+	DomainSize []int
+	Format     VTKFormat
+	Compress   bool
+	Vectors    []VTKVector
+	pvd        PVD
+}
+
+// NewVTKIO returns a new VTKIO writing raw appended data without compression
+func NewVTKIO(prefix string, domainSize []int) VTKIO {
+	return VTKIO{
+		Prefix:     prefix,
+		DomainSize: domainSize,
+		Format:     VTKAppendedRaw,
+		pvd:        newPVD(),
+	}
+}
+
+// newPVD returns an empty ParaView collection
+func newPVD() PVD {
+	return PVD{
+		Type:      "Collection",
+		Version:   "0.1",
+		ByteOrder: "LittleEndian",
+	}
+}
+
+// AddVector adds a vector quantity to the output
+func (v *VTKIO) AddVector(name string, calc func(s *Solver) [][]float64) {
+	v.Vectors = append(v.Vectors, VTKVector{Name: name, Calc: calc})
+}
+
+// AddGradient adds the gradient of the passed field to the output. The gradient is
+// calculated with a GradientCalculator using the fourier transform of the solver, such
+// that non-periodic boundary conditions are respected. The name of the vector quantity
+// is GRAD_<field>
+func (v *VTKIO) AddGradient(field string) {
+	v.AddVector("GRAD_"+field, func(s *Solver) [][]float64 {
+		brick, ok := s.Model.Bricks[field]
+		if !ok {
+			panic(fmt.Sprintf("vtk: unknown field %s", field))
+		}
+		data := make([]complex128, s.Model.NumNodes())
+		for i := range data {
+			data[i] = brick.Get(i)
+		}
+		work := make([]complex128, len(data))
+		grad := make([][]float64, len(s.FT.Freq(0)))
+		for d := range grad {
+			calc := GradientCalculator{FT: s.FT, Comp: d}
+			calc.Calculate(data, work)
+			grad[d] = make([]float64, len(work))
+			for i := range work {
+				grad[d][i] = real(work[i])
+			}
+		}
+		return grad
+	})
+}
+
+// AddCurrent adds the current of the charge density given by ct.Field to the output. The
+// name of the vector quantity is CURRENT_<field>
+func (v *VTKIO) AddCurrent(ct *ChargeTransport) {
+	v.AddVector("CURRENT_"+ct.Field, func(s *Solver) [][]float64 {
+		return ct.Current(s.Model.Bricks[ct.Field], s.Model.NumNodes(), true)
+	})
+}
+
+// vtkArray holds the name, number of components and values of one array in the .vti file.
+// The values are ordered with the x-coordinate varying fastest as required by VTK
+type vtkArray struct {
+	name          string
+	numComponents int
+	values        []float64
+}
+
+// SaveFields writes the fields to prefix_<epoch>.vti and updates prefix.pvd. It can be
+// passed as a callback to the solver
+func (v *VTKIO) SaveFields(s *Solver, epoch int) {
+	if len(s.Model.DerivedFields) > 0 {
+		s.Model.SyncDerivedFields()
+	}
+	arrays := []vtkArray{}
+	for _, f := range s.Model.Fields {
+		arrays = append(arrays, v.scalarArray(f.Name, f))
+	}
+	for _, f := range s.Model.DerivedFields {
+		arrays = append(arrays, v.scalarArray(f.Name, f))
+	}
+	for _, vec := range v.Vectors {
+		arrays = append(arrays, v.vectorArray(vec.Name, vec.Calc(s)))
+	}
+
+	fname := fmt.Sprintf("%s_%d.vti", v.Prefix, epoch)
+	if err := v.writeVTI(fname, arrays); err != nil {
+		panic(err)
+	}
+
+	// The collection is initialized here such that a VTKIO literal can be used
+	if v.pvd.Type == "" {
+		v.pvd = newPVD()
+	}
+	v.pvd.Collection.DataSets = append(v.pvd.Collection.DataSets, PVDDataSet{
+		Timestep: s.Stepper.GetTime(),
+		File:     filepath.Base(fname),
+	})
+	if err := v.writePVD(v.Prefix + ".pvd"); err != nil {
+		panic(err)
+	}
+}
+
+// extent returns the number of nodes in x, y and z direction
+func (v *VTKIO) extent() [3]int {
+	ext := [3]int{1, 1, 1}
+	copy(ext[:], v.DomainSize)
+	return ext
+}
+
+// vtkOrder returns the node numbers in the order expected by VTK (x varies fastest)
+func (v *VTKIO) vtkOrder() []int {
+	ext := v.extent()
+	order := make([]int, 0, pfutil.ProdInt(v.DomainSize))
+	pos := make([]int, len(v.DomainSize))
+	for z := 0; z < ext[2]; z++ {
+		for y := 0; y < ext[1]; y++ {
+			for x := 0; x < ext[0]; x++ {
+				copy(pos, []int{x, y, z})
+				order = append(order, pfutil.NodeIdx(v.DomainSize, pos))
+			}
+		}
+	}
+	return order
+}
+
+// scalarArray returns the real part of the brick in VTK order
+func (v *VTKIO) scalarArray(name string, b Brick) vtkArray {
+	order := v.vtkOrder()
+	values := make([]float64, len(order))
+	for i, node := range order {
+		values[i] = real(b.Get(node))
+	}
+	return vtkArray{name: name, numComponents: 1, values: values}
+}
+
+// vectorArray returns the vector in VTK order. Vectors always have three components
+func (v *VTKIO) vectorArray(name string, components [][]float64) vtkArray {
+	order := v.vtkOrder()
+	values := make([]float64, 3*len(order))
+	for i, node := range order {
+		for d := range components {
+			values[3*i+d] = components[d][node]
+		}
+	}
+	return vtkArray{name: name, numComponents: 3, values: values}
+}
+
+// encodeArray returns the header and the (possibly compressed) bytes of the array. The
+// header is given as UInt64 in little endian. Compressed data are stored as a single block.
+func (v *VTKIO) encodeArray(values []float64) ([]byte, []byte) {
+	raw := new(bytes.Buffer)
+	binary.Write(raw, binary.LittleEndian, values)
+	header := new(bytes.Buffer)
+	if !v.Compress {
+		binary.Write(header, binary.LittleEndian, uint64(raw.Len()))
+		return header.Bytes(), raw.Bytes()
+	}
+
+	compressed := new(bytes.Buffer)
+	zw := zlib.NewWriter(compressed)
+	zw.Write(raw.Bytes())
+	zw.Close()
+	binary.Write(header, binary.LittleEndian, []uint64{1, uint64(raw.Len()), uint64(raw.Len()), uint64(compressed.Len())})
+	return header.Bytes(), compressed.Bytes()
+}
+
+// writeVTI writes the arrays to a .vti file
+func (v *VTKIO) writeVTI(fname string, arrays []vtkArray) error {
+	ext := v.extent()
+	extent := fmt.Sprintf("0 %d 0 %d 0 %d", ext[0]-1, ext[1]-1, ext[2]-1)
+	imgData := VTKImageData{
+		WholeExtent: extent,
+		Origin:      "0 0 0",
+		Spacing:     "1 1 1",
+		Piece:       VTKPiece{Extent: extent},
+	}
+
+	appended := new(bytes.Buffer)
+	for _, arr := range arrays {
+		header, data := v.encodeArray(arr.values)
+		dataArray := VTKDataArray{
+			Type: "Float64",
+			Name: arr.name,
+		}
+		if arr.numComponents > 1 {
+			dataArray.NumComponents = arr.numComponents
+			imgData.Piece.PointData.Vectors = arr.name
+		} else if imgData.Piece.PointData.Scalars == "" {
+			imgData.Piece.PointData.Scalars = arr.name
+		}
+
+		if v.Format == VTKBase64 {
+			dataArray.Format = "binary"
+			if v.Compress {
+				// The header of compressed data is encoded separately
+				dataArray.Value = base64.StdEncoding.EncodeToString(header) + base64.StdEncoding.EncodeToString(data)
+			} else {
+				dataArray.Value = base64.StdEncoding.EncodeToString(append(header, data...))
+			}
+		} else {
+			dataArray.Format = "appended"
+			offset := appended.Len()
+			dataArray.Offset = &offset
+			appended.Write(header)
+			appended.Write(data)
+		}
+		imgData.Piece.PointData.DataArrays = append(imgData.Piece.PointData.DataArrays, dataArray)
+	}
+
+	out, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	writer := bufio.NewWriter(out)
+
+	compressor := ""
+	if v.Compress {
+		compressor = " compressor=\"vtkZLibDataCompressor\""
+	}
+	fmt.Fprintf(writer, "<?xml version=\"1.0\"?>\n")
+	fmt.Fprintf(writer, "<VTKFile type=\"ImageData\" version=\"1.0\" byte_order=\"LittleEndian\" header_type=\"UInt64\"%s>\n", compressor)
+	enc := xml.NewEncoder(writer)
+	enc.Indent("", "    ")
+	if err := enc.Encode(imgData); err != nil {
+		return err
+	}
+	if v.Format == VTKAppendedRaw {
+		fmt.Fprintf(writer, "\n<AppendedData encoding=\"raw\">\n_")
+		io.Copy(writer, appended)
+		fmt.Fprintf(writer, "\n</AppendedData>")
+	}
+	fmt.Fprintf(writer, "\n</VTKFile>\n")
+	return writer.Flush()
+}
+
+// writePVD writes the collection file
+func (v *VTKIO) writePVD(fname string) error {
+	out, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	out.WriteString(xml.Header)
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "    ")
+	return enc.Encode(v.pvd)
+}
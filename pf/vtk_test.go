@@ -0,0 +1,211 @@
+package pf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/davidkleiven/gopf/pfutil"
+)
+
+// decodeVTKArray decodes the header and data of one array
+func decodeVTKArray(t *testing.T, data []byte, compressed bool) []float64 {
+	var raw []byte
+	if compressed {
+		header := make([]uint64, 4)
+		binary.Read(bytes.NewReader(data[:32]), binary.LittleEndian, header)
+		zr, err := zlib.NewReader(bytes.NewReader(data[32 : 32+header[3]]))
+		if err != nil {
+			t.Fatalf("%s\n", err)
+		}
+		raw, _ = ioutil.ReadAll(zr)
+	} else {
+		var size uint64
+		binary.Read(bytes.NewReader(data[:8]), binary.LittleEndian, &size)
+		raw = data[8 : 8+size]
+	}
+	values := make([]float64, len(raw)/8)
+	binary.Read(bytes.NewReader(raw), binary.LittleEndian, values)
+	return values
+}
+
+func TestVTKIO(t *testing.T) {
+	domainSize := []int{4, 3}
+	N := 12
+
+	for i, test := range []struct {
+		format   VTKFormat
+		compress bool
+	}{
+		{format: VTKAppendedRaw, compress: false},
+		{format: VTKAppendedRaw, compress: true},
+		{format: VTKBase64, compress: false},
+		{format: VTKBase64, compress: true},
+	} {
+		m := NewModel()
+		conc := NewField("conc", N, nil)
+		for j := range conc.Data {
+			conc.Data[j] = complex(float64(j), 0.0)
+		}
+		m.AddField(conc)
+		m.AddEquation("dconc/dt = conc^2")
+		solver := NewSolver(&m, domainSize, 0.1)
+
+		vtk := NewVTKIO("vtkTest", domainSize)
+		vtk.Format = test.format
+		vtk.Compress = test.compress
+		vtk.AddGradient("conc")
+		vtk.SaveFields(solver, 0)
+		vtk.SaveFields(solver, 1)
+
+		content, err := ioutil.ReadFile("vtkTest_1.vti")
+		os.Remove("vtkTest_0.vti")
+		os.Remove("vtkTest_1.vti")
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+
+		// Extract the XML part and the appended data
+		xmlPart := string(content)
+		var appended []byte
+		if idx := bytes.Index(content, []byte("<AppendedData encoding=\"raw\">\n_")); idx >= 0 {
+			xmlPart = string(content[:idx])
+			appended = content[idx+len("<AppendedData encoding=\"raw\">\n_"):]
+		}
+		start := strings.Index(xmlPart, "<ImageData")
+		end := strings.Index(xmlPart, "</ImageData>") + len("</ImageData>")
+		var imgData VTKImageData
+		if err := xml.Unmarshal([]byte(xmlPart[start:end]), &imgData); err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+		if imgData.WholeExtent != "0 3 0 2 0 0" {
+			t.Errorf("Test #%d: Unexpected extent %s\n", i, imgData.WholeExtent)
+		}
+
+		arrays := imgData.Piece.PointData.DataArrays
+		if len(arrays) != 3 || arrays[0].Name != "conc" || arrays[1].Name != "conc^2" || arrays[2].Name != "GRAD_conc" || arrays[2].NumComponents != 3 {
+			t.Errorf("Test #%d: Unexpected data arrays %v\n", i, arrays)
+			continue
+		}
+
+		var data []byte
+		if test.format == VTKBase64 {
+			value := strings.TrimSpace(arrays[0].Value)
+			if test.compress {
+				// Header of 4 UInt64 encoded separately (32 bytes -> 44 characters)
+				header, _ := base64.StdEncoding.DecodeString(value[:44])
+				body, _ := base64.StdEncoding.DecodeString(value[44:])
+				data = append(header, body...)
+			} else {
+				data, _ = base64.StdEncoding.DecodeString(value)
+			}
+		} else {
+			data = appended[*arrays[0].Offset:]
+		}
+		values := decodeVTKArray(t, data, test.compress)
+
+		// VTK order: x varies fastest
+		for j, v := range values {
+			x := j % 4
+			y := j / 4
+			expect := float64(x*3 + y)
+			if math.Abs(v-expect) > 1e-10 {
+				t.Errorf("Test #%d: Expected %f got %f at %d\n", i, expect, v, j)
+				break
+			}
+		}
+	}
+
+	content, err := ioutil.ReadFile("vtkTest.pvd")
+	os.Remove("vtkTest.pvd")
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	var pvd PVD
+	if err := xml.Unmarshal(content, &pvd); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	if len(pvd.Collection.DataSets) != 2 || pvd.Collection.DataSets[1].File != "vtkTest_1.vti" {
+		t.Errorf("Unexpected collection %v\n", pvd.Collection.DataSets)
+	}
+}
+
+func TestVTKIOLiteral(t *testing.T) {
+	N := 4
+	m := NewModel()
+	m.AddField(NewField("conc", N, nil))
+	m.AddEquation("dconc/dt = LAP conc")
+	solver := NewSolver(&m, []int{N}, 0.1)
+
+	vtk := VTKIO{Prefix: "vtkLiteral", DomainSize: []int{N}}
+	vtk.SaveFields(solver, 0)
+	os.Remove("vtkLiteral_0.vti")
+
+	content, err := ioutil.ReadFile("vtkLiteral.pvd")
+	os.Remove("vtkLiteral.pvd")
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	var pvd PVD
+	if err := xml.Unmarshal(content, &pvd); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	if pvd.Type != "Collection" || pvd.Version != "0.1" || pvd.ByteOrder != "LittleEndian" {
+		t.Errorf("Unexpected header type=%s version=%s byte order=%s\n", pvd.Type, pvd.Version, pvd.ByteOrder)
+	}
+	if len(pvd.Collection.DataSets) != 1 || pvd.Collection.DataSets[0].File != "vtkLiteral_0.vti" {
+		t.Errorf("Unexpected collection %v\n", pvd.Collection.DataSets)
+	}
+}
+
+func TestVTKGradient(t *testing.T) {
+	N := 16
+	for _, bc := range []pfutil.BoundaryCondition{pfutil.Periodic, pfutil.NeumannDCT2, pfutil.DirichletDST1} {
+		k := 2.0 * math.Pi / float64(N)
+		shift := 0.0
+		mode, deriv := math.Sin, math.Cos
+		switch bc {
+		case pfutil.NeumannDCT2:
+			k = math.Pi / float64(N)
+			shift = 0.5
+			mode = math.Cos
+			deriv = func(x float64) float64 { return -math.Sin(x) }
+		case pfutil.DirichletDST1:
+			k = math.Pi / float64(N+1)
+			shift = 1.0
+		}
+
+		m := NewModel()
+		field := NewField("conc", N, nil)
+		for i := range field.Data {
+			field.Data[i] = complex(mode(k*(float64(i)+shift)), 0.0)
+		}
+		m.AddField(field)
+		solver := NewSolver(&m, []int{N}, 0.1)
+		solver.FT = pfutil.NewR2RTransform([]int{N}, []pfutil.BoundaryCondition{bc})
+
+		vtk := NewVTKIO("vtkGradient", []int{N})
+		vtk.AddGradient("conc")
+		grad := vtk.Vectors[0].Calc(solver)
+		for i := range grad[0] {
+			expect := k * deriv(k*(float64(i)+shift))
+			if math.Abs(grad[0][i]-expect) > 1e-8 {
+				t.Errorf("%s: Node %d: Expected %f got %f\n", bc, i, expect, grad[0][i])
+				break
+			}
+		}
+	}
+}
@@ -81,6 +81,11 @@ type XDMF struct {
 
 // CreateXDMF returns a new instance of XDMF
 func CreateXDMF(fieldNames []string, prefix string, num int, domainSize []int) XDMF {
+	if len(domainSize) == 1 {
+		// XDMF has no 1D structured topology. A 1D domain is therefore
+		// represented as a 2D mesh consisting of a single row
+		domainSize = []int{1, domainSize[0]}
+	}
 	dim := len(domainSize)
 	dimensions := ""
 	geoType := ""
@@ -91,7 +96,7 @@ func CreateXDMF(fieldNames []string, prefix string, num int, domainSize []int) X
 		dimensions = fmt.Sprintf("%d %d %d", domainSize[0], domainSize[1], domainSize[2])
 		geoType = "ORIGIN_DXDYDZ"
 	} else {
-		panic("Length of domain size to be either 2 or 3")
+		panic("Length of domain size to be either 1, 2 or 3")
 	}
 	xdmf := XDMF{}
 	xdmf.Domain.Topology = XDMFTopology{
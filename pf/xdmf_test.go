@@ -57,3 +57,10 @@ func TestCreateXDMF(t *testing.T) {
 	enc.Encode(xdmf)
 	s = buf.String()
 }
+
+func TestCreateXDMF1D(t *testing.T) {
+	xdmf := CreateXDMF([]string{"conc"}, "myprefix", 1, []int{64})
+	if xdmf.Domain.Topology.Type != "2DCoRectMesh" || xdmf.Domain.Topology.Dimensions != "1 64" {
+		t.Errorf("Unexpected topology for 1D domain: %v\n", xdmf.Domain.Topology)
+	}
+}
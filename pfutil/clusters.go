@@ -0,0 +1,210 @@
+package pfutil
+
+// Connectivity determines which neighbours that are considered to be connected when
+// clusters are labelled
+type Connectivity int
+
+const (
+	// FaceConnected nodes share a face (4 neighbours in 2D and 6 neighbours in 3D)
+	FaceConnected Connectivity = iota + 1
+
+	// EdgeConnected nodes share a face or an edge (8 neighbours in 2D and 18 neighbours in 3D)
+	EdgeConnected
+
+	// CornerConnected nodes share a face, an edge or a corner (8 neighbours in 2D and 26
+	// neighbours in 3D)
+	CornerConnected
+)
+
+// Cluster holds the statistics of a connected cluster of nodes
+type Cluster struct {
+	// Label is the label of the cluster. Labels start at 1
+	Label int
+
+	// Phase is the value of the phase map of the nodes in the cluster
+	Phase int
+
+	// Volume is the number of nodes in the cluster
+	Volume int
+
+	// Centroid is the center of mass of the cluster. When periodic boundary conditions
+	// are used, the cluster is unwrapped before the centroid is calculated, and the
+	// result is wrapped back into the domain
+	Centroid []float64
+
+	// Percolating is true if the cluster is connected to its own periodic image
+	// (e.g. it spans the entire domain). In that case the centroid is not well defined
+	Percolating bool
+}
+
+// Threshold returns a phase map where nodes where the data is larger than threshold
+// are 1 and all other nodes are 0
+func Threshold(data ImmutableSlice, threshold float64) []int {
+	phase := make([]int, data.Len())
+	for i := range phase {
+		if data.Get(i) > threshold {
+			phase[i] = 1
+		}
+	}
+	return phase
+}
+
+// ArgmaxPhase returns a phase map where each node is assigned to the order parameter
+// with the largest value. Nodes belonging to order parameter j gets the value j+1. If the
+// largest value is below threshold, the node is assigned to the background (value 0)
+func ArgmaxPhase(data []ImmutableSlice, threshold float64) []int {
+	if len(data) == 0 {
+		return []int{}
+	}
+	phase := make([]int, data[0].Len())
+	for i := range phase {
+		best := 0
+		for j := 1; j < len(data); j++ {
+			if data[j].Get(i) > data[best].Get(i) {
+				best = j
+			}
+		}
+		if data[best].Get(i) >= threshold {
+			phase[i] = best + 1
+		}
+	}
+	return phase
+}
+
+// neighbourOffsets returns the offsets to all neighbours for the given connectivity
+func neighbourOffsets(dim int, conn Connectivity) [][]int {
+	offsets := [][]int{}
+	end := make([]int, dim)
+	for i := range end {
+		end[i] = 3
+	}
+	prod := NewProduct(end)
+	for idx := prod.Next(); idx != nil; idx = prod.Next() {
+		offset := make([]int, dim)
+		numNonZero := 0
+		for i := range idx {
+			offset[i] = idx[i] - 1
+			if offset[i] != 0 {
+				numNonZero++
+			}
+		}
+		if numNonZero > 0 && numNonZero <= int(conn) {
+			offsets = append(offsets, offset)
+		}
+	}
+	return offsets
+}
+
+// LabelClusters labels all connected clusters in the phase map. Nodes with phase 0 are
+// considered as background, and neighbouring nodes are part of the same cluster if they
+// have the same phase. If periodic is true, clusters are connected across the boundaries
+// of the domain. The labels (0 for background and 1, 2, ... for the clusters) and the
+// statistics of each cluster are returned. Cluster i in the returned slice has label i+1
+func LabelClusters(phase []int, domainSize []int, conn Connectivity, periodic bool) ([]int, []Cluster) {
+	numNodes := ProdInt(domainSize)
+	if len(phase) != numNodes {
+		panic("clusters: The length of the phase map does not match the domain size")
+	}
+	dim := len(domainSize)
+	offsets := neighbourOffsets(dim, conn)
+	labels := make([]int, numNodes)
+
+	// unwrapped holds the position of each node after unwrapping the cluster it belongs to
+	unwrapped := make([]int, dim*numNodes)
+	clusters := []Cluster{}
+	queue := []int{}
+	neighbour := make([]int, dim)
+
+	for start := range phase {
+		if phase[start] == 0 || labels[start] != 0 {
+			continue
+		}
+		cluster := Cluster{
+			Label:    len(clusters) + 1,
+			Phase:    phase[start],
+			Centroid: make([]float64, dim),
+		}
+		labels[start] = cluster.Label
+		copy(unwrapped[dim*start:dim*start+dim], Pos(domainSize, start))
+		queue = append(queue[:0], start)
+
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			cluster.Volume++
+			pos := unwrapped[dim*node : dim*node+dim]
+			for d := range pos {
+				cluster.Centroid[d] += float64(pos[d])
+			}
+
+			for _, offset := range offsets {
+				inside := true
+				for d := range offset {
+					neighbour[d] = wrapIndex(pos[d]+offset[d], domainSize[d])
+					if !periodic && neighbour[d] != pos[d]+offset[d] {
+						inside = false
+					}
+				}
+				if !inside {
+					continue
+				}
+				j := NodeIdx(domainSize, neighbour)
+				if phase[j] != cluster.Phase {
+					continue
+				}
+
+				if labels[j] == 0 {
+					labels[j] = cluster.Label
+					for d := range offset {
+						unwrapped[dim*j+d] = pos[d] + offset[d]
+					}
+					queue = append(queue, j)
+				} else {
+					for d := range offset {
+						if unwrapped[dim*j+d] != pos[d]+offset[d] {
+							cluster.Percolating = true
+						}
+					}
+				}
+			}
+		}
+
+		for d := range cluster.Centroid {
+			cluster.Centroid[d] /= float64(cluster.Volume)
+			if periodic {
+				cluster.Centroid[d] = wrapFloat(cluster.Centroid[d], float64(domainSize[d]))
+			}
+		}
+		clusters = append(clusters, cluster)
+	}
+	return labels, clusters
+}
+
+// SizeDistribution returns the number of clusters of each volume
+func SizeDistribution(clusters []Cluster) map[int]int {
+	dist := make(map[int]int)
+	for _, c := range clusters {
+		dist[c.Volume]++
+	}
+	return dist
+}
+
+// wrapIndex wraps the index into the range [0, n)
+func wrapIndex(i int, n int) int {
+	i = i % n
+	if i < 0 {
+		i += n
+	}
+	return i
+}
+
+// wrapFloat wraps x into the range [0, length)
+func wrapFloat(x float64, length float64) float64 {
+	for x < 0.0 {
+		x += length
+	}
+	for x >= length {
+		x -= length
+	}
+	return x
+}
@@ -0,0 +1,155 @@
+package pfutil
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+)
+
+func TestNeighbourOffsets(t *testing.T) {
+	for i, test := range []struct {
+		dim    int
+		conn   Connectivity
+		expect int
+	}{
+		{dim: 1, conn: FaceConnected, expect: 2},
+		{dim: 2, conn: FaceConnected, expect: 4},
+		{dim: 2, conn: EdgeConnected, expect: 8},
+		{dim: 2, conn: CornerConnected, expect: 8},
+		{dim: 3, conn: FaceConnected, expect: 6},
+		{dim: 3, conn: EdgeConnected, expect: 18},
+		{dim: 3, conn: CornerConnected, expect: 26},
+	} {
+		if num := len(neighbourOffsets(test.dim, test.conn)); num != test.expect {
+			t.Errorf("Test #%d: Expected %d neighbours got %d\n", i, test.expect, num)
+		}
+	}
+}
+
+func TestLabelClustersPeriodic(t *testing.T) {
+	N := 10
+	domainSize := []int{N, N}
+	phase := make([]int, N*N)
+
+	// 2x2 square that wraps around the corner of the domain
+	for _, pos := range [][]int{{0, 0}, {0, N - 1}, {N - 1, 0}, {N - 1, N - 1}} {
+		phase[NodeIdx(domainSize, pos)] = 1
+	}
+
+	// 1x3 bar in the middle
+	for y := 4; y < 7; y++ {
+		phase[NodeIdx(domainSize, []int{5, y})] = 1
+	}
+
+	labels, clusters := LabelClusters(phase, domainSize, FaceConnected, true)
+	if len(clusters) != 2 {
+		t.Errorf("Expected 2 clusters got %d\n", len(clusters))
+		return
+	}
+	if clusters[0].Volume != 4 || clusters[1].Volume != 3 {
+		t.Errorf("Expected volumes 4 and 3 got %d and %d\n", clusters[0].Volume, clusters[1].Volume)
+	}
+
+	// The centroid of the corner cluster is at (-0.5, -0.5), which is wrapped to (N-0.5, N-0.5)
+	expect := []float64{float64(N) - 0.5, float64(N) - 0.5}
+	if !floats.EqualApprox(clusters[0].Centroid, expect, 1e-10) {
+		t.Errorf("Expected centroid %v got %v\n", expect, clusters[0].Centroid)
+	}
+	if !floats.EqualApprox(clusters[1].Centroid, []float64{5.0, 5.0}, 1e-10) {
+		t.Errorf("Expected centroid (5, 5) got %v\n", clusters[1].Centroid)
+	}
+	if labels[NodeIdx(domainSize, []int{N - 1, 0})] != clusters[0].Label {
+		t.Errorf("Corner node not part of the first cluster\n")
+	}
+
+	// Without periodic boundary conditions the corner cluster splits into four
+	_, clusters = LabelClusters(phase, domainSize, FaceConnected, false)
+	if len(clusters) != 5 {
+		t.Errorf("Expected 5 clusters got %d\n", len(clusters))
+	}
+}
+
+func TestLabelClustersConnectivity(t *testing.T) {
+	domainSize := []int{4, 4, 4}
+	phase := make([]int, 64)
+	phase[NodeIdx(domainSize, []int{1, 1, 1})] = 1
+	phase[NodeIdx(domainSize, []int{2, 2, 1})] = 1
+	phase[NodeIdx(domainSize, []int{1, 2, 2})] = 1
+
+	for i, test := range []struct {
+		conn   Connectivity
+		expect int
+	}{
+		{conn: FaceConnected, expect: 3},
+		{conn: EdgeConnected, expect: 1},
+		{conn: CornerConnected, expect: 1},
+	} {
+		_, clusters := LabelClusters(phase, domainSize, test.conn, false)
+		if len(clusters) != test.expect {
+			t.Errorf("Test #%d: Expected %d clusters got %d\n", i, test.expect, len(clusters))
+		}
+	}
+
+	// Only corner sharing
+	phase = make([]int, 64)
+	phase[NodeIdx(domainSize, []int{1, 1, 1})] = 1
+	phase[NodeIdx(domainSize, []int{2, 2, 2})] = 1
+	_, clusters := LabelClusters(phase, domainSize, EdgeConnected, false)
+	if len(clusters) != 2 {
+		t.Errorf("Expected 2 clusters got %d\n", len(clusters))
+	}
+	_, clusters = LabelClusters(phase, domainSize, CornerConnected, false)
+	if len(clusters) != 1 {
+		t.Errorf("Expected 1 cluster got %d\n", len(clusters))
+	}
+}
+
+func TestPercolatingCluster(t *testing.T) {
+	N := 8
+	domainSize := []int{N, N}
+	phase := make([]int, N*N)
+	for y := 0; y < N; y++ {
+		phase[NodeIdx(domainSize, []int{3, y})] = 1
+	}
+	_, clusters := LabelClusters(phase, domainSize, FaceConnected, true)
+	if len(clusters) != 1 || !clusters[0].Percolating {
+		t.Errorf("Expected one percolating cluster got %v\n", clusters)
+	}
+	_, clusters = LabelClusters(phase, domainSize, FaceConnected, false)
+	if clusters[0].Percolating {
+		t.Errorf("Cluster can not percolate without periodic boundary conditions\n")
+	}
+}
+
+func TestArgmaxPhase(t *testing.T) {
+	eta1 := RealSlice{Data: []float64{0.9, 0.1, 0.2, 0.5}}
+	eta2 := RealSlice{Data: []float64{0.1, 0.8, 0.3, 0.4}}
+	phase := ArgmaxPhase([]ImmutableSlice{&eta1, &eta2}, 0.45)
+	expect := []int{1, 2, 0, 1}
+	for i := range expect {
+		if phase[i] != expect[i] {
+			t.Errorf("Expected %v got %v\n", expect, phase)
+			return
+		}
+	}
+
+	// Different order parameters form different clusters
+	_, clusters := LabelClusters([]int{1, 1, 2, 2}, []int{4}, FaceConnected, true)
+	if len(clusters) != 2 || clusters[0].Phase != 1 || clusters[1].Phase != 2 {
+		t.Errorf("Expected two clusters of different phase got %v\n", clusters)
+	}
+	if math.Abs(clusters[0].Centroid[0]-0.5) > 1e-10 {
+		t.Errorf("Expected centroid 0.5 got %f\n", clusters[0].Centroid[0])
+	}
+}
+
+func TestThresholdAndSizeDistribution(t *testing.T) {
+	data := RealSlice{Data: []float64{0.9, 0.1, 0.8, 0.7, 0.0, 0.6}}
+	phase := Threshold(&data, 0.5)
+	_, clusters := LabelClusters(phase, []int{6}, FaceConnected, false)
+	dist := SizeDistribution(clusters)
+	if dist[1] != 2 || dist[2] != 1 || len(dist) != 2 {
+		t.Errorf("Unexpected size distribution %v\n", dist)
+	}
+}
@@ -0,0 +1,242 @@
+package pfutil
+
+import "math"
+
+// Polyline is a sequence of connected points in 2D. If Closed is true, the last point is
+// connected to the first point
+type Polyline struct {
+	Points [][]float64
+	Closed bool
+}
+
+// Length returns the length of the polyline
+func (p *Polyline) Length() float64 {
+	length := 0.0
+	for i := 1; i < len(p.Points); i++ {
+		length += dist2D(p.Points[i-1], p.Points[i])
+	}
+	if p.Closed && len(p.Points) > 1 {
+		length += dist2D(p.Points[len(p.Points)-1], p.Points[0])
+	}
+	return length
+}
+
+// Contours holds the contour lines of a 2D field. The polylines are oriented such that
+// the region where the field is larger than the level is on the left hand side
+type Contours struct {
+	Polylines []Polyline
+
+	// EnclosedArea is the area of the region where the linearly interpolated field is
+	// larger than the level
+	EnclosedArea float64
+}
+
+// Length returns the total length of all contour lines
+func (c *Contours) Length() float64 {
+	length := 0.0
+	for i := range c.Polylines {
+		length += c.Polylines[i].Length()
+	}
+	return length
+}
+
+// dist2D returns the distance between two points in 2D
+func dist2D(a, b []float64) float64 {
+	return math.Sqrt((a[0]-b[0])*(a[0]-b[0]) + (a[1]-b[1])*(a[1]-b[1]))
+}
+
+// contourSegment is a directed line segment between two edge crossings
+type contourSegment struct {
+	start, end edgeKey
+}
+
+// squareItem is an item encountered when walking counter clockwise along the boundary
+// of a square cell. It is either a corner or a crossing of the contour
+type squareItem struct {
+	point    []float64
+	key      edgeKey
+	isCorner bool
+
+	// isExit is true if the walk passes from the inside to the outside at the crossing
+	isExit bool
+}
+
+// MarchingSquares extracts the contour lines where the field is equal to level. The
+// crossings are found by linear interpolation along the edges of each cell. The ambiguous
+// saddle configurations are resolved by the average of the four corners. If periodic is
+// true, the cells between the last and the first node along each direction are included,
+// in which case the points of these cells lie between N-1 and N, where N is the number of
+// nodes along the direction. Contours crossing the boundary are therefore split into
+// several polylines. The enclosed area is returned as part of the result.
+func MarchingSquares(data ImmutableSlice, domainSize []int, level float64, periodic bool) Contours {
+	if len(domainSize) != 2 {
+		panic("contour: Marching squares requires a 2D domain")
+	}
+	contours := Contours{}
+	points := make(map[edgeKey][]float64)
+	segments := []contourSegment{}
+
+	// Corners in counter clockwise order
+	ccw := []int{0, 1, 3, 2}
+	cells := NewProduct(numCells(domainSize, periodic))
+	for pos := cells.Next(); pos != nil; pos = cells.Next() {
+		corners := cellCorners(data, domainSize, pos)
+		mean := 0.0
+		walk := []squareItem{}
+		for i, c := range ccw {
+			mean += 0.25 * corners[c].value
+			p := corners[c]
+			q := corners[ccw[(i+1)%4]]
+			pIn := p.value > level
+			qIn := q.value > level
+			if pIn {
+				walk = append(walk, squareItem{point: cornerPos(p)[:2], isCorner: true})
+			}
+			if pIn != qIn {
+				key := newEdgeKey(p.pos, q.pos)
+				var point []float64
+				if pIn {
+					point = crossing(p, q, level)
+				} else {
+					point = crossing(q, p, level)
+				}
+				point = point[:2]
+				points[key] = point
+				walk = append(walk, squareItem{point: point, key: key, isExit: pIn})
+			}
+		}
+
+		for _, polygon := range cellPolygons(walk, mean > level) {
+			contours.EnclosedArea += shoelace(polygon)
+		}
+
+		for i, item := range walk {
+			if item.isCorner || !item.isExit {
+				continue
+			}
+			entry := pairedEntry(walk, i, mean > level)
+			segments = append(segments, contourSegment{start: item.key, end: walk[entry].key})
+		}
+	}
+	contours.Polylines = joinSegments(segments, points)
+	return contours
+}
+
+// pairedEntry returns the position in walk of the entry crossing connected to the exit
+// crossing at position exit. If the center of the cell is inside, the exit is connected to
+// the next entry. Otherwise it is connected to the previous entry. These coincide if there
+// are only two crossings
+func pairedEntry(walk []squareItem, exit int, centerInside bool) int {
+	step := -1
+	if centerInside {
+		step = 1
+	}
+	for i := 1; i < len(walk); i++ {
+		j := (exit + step*i + len(walk)) % len(walk)
+		if !walk[j].isCorner && !walk[j].isExit {
+			return j
+		}
+	}
+	panic("contour: No entry crossing found")
+}
+
+// cellPolygons returns the polygons that enclose the region inside the cell where the
+// field is larger than the level. The polygons are traced by following the contour from
+// an exit crossing to its entry crossing and then the cell boundary to the next exit
+func cellPolygons(walk []squareItem, centerInside bool) [][][]float64 {
+	numCrossings := 0
+	for _, item := range walk {
+		if !item.isCorner {
+			numCrossings++
+		}
+	}
+	if numCrossings == 0 {
+		if len(walk) == 0 {
+			return [][][]float64{}
+		}
+		polygon := [][]float64{}
+		for _, item := range walk {
+			polygon = append(polygon, item.point)
+		}
+		return [][][]float64{polygon}
+	}
+
+	polygons := [][][]float64{}
+	visited := make(map[int]bool)
+	for start, item := range walk {
+		if item.isCorner || !item.isExit || visited[start] {
+			continue
+		}
+		polygon := [][]float64{}
+		exit := start
+		for !visited[exit] {
+			visited[exit] = true
+			polygon = append(polygon, walk[exit].point)
+			i := pairedEntry(walk, exit, centerInside)
+			for {
+				polygon = append(polygon, walk[i].point)
+				i = (i + 1) % len(walk)
+				if !walk[i].isCorner && walk[i].isExit {
+					break
+				}
+			}
+			exit = i
+		}
+		polygons = append(polygons, polygon)
+	}
+	return polygons
+}
+
+// shoelace returns the area of the polygon
+func shoelace(polygon [][]float64) float64 {
+	area := 0.0
+	for i := range polygon {
+		j := (i + 1) % len(polygon)
+		area += polygon[i][0]*polygon[j][1] - polygon[j][0]*polygon[i][1]
+	}
+	return 0.5 * math.Abs(area)
+}
+
+// joinSegments joins the directed segments into polylines
+func joinSegments(segments []contourSegment, points map[edgeKey][]float64) []Polyline {
+	next := make(map[edgeKey]int)
+	isEnd := make(map[edgeKey]bool)
+	for i, s := range segments {
+		next[s.start] = i
+		isEnd[s.end] = true
+	}
+
+	used := make([]bool, len(segments))
+	polylines := []Polyline{}
+	trace := func(first int) Polyline {
+		line := Polyline{Points: [][]float64{points[segments[first].start]}}
+		i := first
+		for {
+			used[i] = true
+			key := segments[i].end
+			j, ok := next[key]
+			if ok && j == first {
+				line.Closed = true
+				return line
+			}
+			line.Points = append(line.Points, points[key])
+			if !ok || used[j] {
+				return line
+			}
+			i = j
+		}
+	}
+
+	// Open polylines start at a crossing that is not the end of any segment
+	for i, s := range segments {
+		if !isEnd[s.start] {
+			polylines = append(polylines, trace(i))
+		}
+	}
+	for i := range segments {
+		if !used[i] {
+			polylines = append(polylines, trace(i))
+		}
+	}
+	return polylines
+}
@@ -0,0 +1,131 @@
+package pfutil
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+func circleField(N int, radius float64, center []float64) *RealSlice {
+	domainSize := []int{N, N}
+	data := make([]float64, N*N)
+	for i := range data {
+		pos := Pos(domainSize, i)
+		dx := float64(pos[0]) - center[0]
+		dy := float64(pos[1]) - center[1]
+		data[i] = radius - math.Sqrt(dx*dx+dy*dy)
+	}
+	return &RealSlice{Data: data}
+}
+
+func TestMarchingSquaresCircle(t *testing.T) {
+	N := 64
+	radius := 10.0
+	contours := MarchingSquares(circleField(N, radius, []float64{32.0, 32.0}), []int{N, N}, 0.0, false)
+	if len(contours.Polylines) != 1 || !contours.Polylines[0].Closed {
+		t.Errorf("Expected one closed polyline got %d\n", len(contours.Polylines))
+		return
+	}
+	length := 2.0 * math.Pi * radius
+	if math.Abs(contours.Length()-length) > 0.01*length {
+		t.Errorf("Expected length %f got %f\n", length, contours.Length())
+	}
+	area := math.Pi * radius * radius
+	if math.Abs(contours.EnclosedArea-area) > 0.01*area {
+		t.Errorf("Expected area %f got %f\n", area, contours.EnclosedArea)
+	}
+
+	// Area from the polygon must match the enclosed area. The inside is on the left
+	// hand side, so the polygon is counter clockwise
+	signed := 0.0
+	pts := contours.Polylines[0].Points
+	for i := range pts {
+		j := (i + 1) % len(pts)
+		signed += 0.5 * (pts[i][0]*pts[j][1] - pts[j][0]*pts[i][1])
+	}
+	if math.Abs(signed-contours.EnclosedArea) > 1e-8 {
+		t.Errorf("Expected signed area %f got %f\n", contours.EnclosedArea, signed)
+	}
+}
+
+func TestMarchingSquaresPeriodic(t *testing.T) {
+	N := 32
+	radius := 6.0
+
+	// Circle centered at the corner, which is split into four open polylines
+	domainSize := []int{N, N}
+	data := make([]float64, N*N)
+	for i := range data {
+		pos := Pos(domainSize, i)
+		dx := math.Min(float64(pos[0]), float64(N-pos[0]))
+		dy := math.Min(float64(pos[1]), float64(N-pos[1]))
+		data[i] = radius - math.Sqrt(dx*dx+dy*dy)
+	}
+	contours := MarchingSquares(&RealSlice{Data: data}, domainSize, 0.0, true)
+	if len(contours.Polylines) != 4 {
+		t.Errorf("Expected 4 polylines got %d\n", len(contours.Polylines))
+	}
+	for _, line := range contours.Polylines {
+		if line.Closed {
+			t.Errorf("Polylines crossing the boundary should be open\n")
+		}
+	}
+	area := math.Pi * radius * radius
+	if math.Abs(contours.EnclosedArea-area) > 0.02*area {
+		t.Errorf("Expected area %f got %f\n", area, contours.EnclosedArea)
+	}
+}
+
+func TestMarchingSquaresSaddle(t *testing.T) {
+	for i, test := range []struct {
+		data   []float64
+		area   float64
+		length float64
+	}{
+		// Center outside: two separate corners
+		{data: []float64{1.0, -1.0, -1.0, 1.0}, area: 0.25, length: math.Sqrt(2.0)},
+
+		// Center inside: the outside corners are cut off
+		{data: []float64{2.0, -1.0, -1.0, 2.0}, area: 1.0 - 2.0*0.5*(1.0/3.0)*(1.0/3.0), length: 2.0 * math.Sqrt(2.0) / 3.0},
+	} {
+		contours := MarchingSquares(&RealSlice{Data: test.data}, []int{2, 2}, 0.0, false)
+		if len(contours.Polylines) != 2 {
+			t.Errorf("Test #%d: Expected 2 polylines got %d\n", i, len(contours.Polylines))
+		}
+		if math.Abs(contours.EnclosedArea-test.area) > 1e-10 {
+			t.Errorf("Test #%d: Expected area %f got %f\n", i, test.area, contours.EnclosedArea)
+		}
+		if math.Abs(contours.Length()-test.length) > 1e-10 {
+			t.Errorf("Test #%d: Expected length %f got %f\n", i, test.length, contours.Length())
+		}
+	}
+}
+
+func TestContourExport(t *testing.T) {
+	N := 16
+	contours := MarchingSquares(circleField(N, 4.0, []float64{8.0, 8.0}), []int{N, N}, 0.0, false)
+	fname := "contourExport.csv"
+	if err := contours.SaveCsv(fname); err != nil {
+		t.Errorf("%s\n", err)
+	}
+	content, _ := ioutil.ReadFile(fname)
+	os.Remove(fname)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+
+	// Header + all points + the first point repeated
+	if len(lines) != len(contours.Polylines[0].Points)+2 {
+		t.Errorf("Expected %d lines got %d\n", len(contours.Polylines[0].Points)+2, len(lines))
+	}
+
+	fname = "contourExport.svg"
+	if err := contours.SaveSVG(fname, float64(N), float64(N)); err != nil {
+		t.Errorf("%s\n", err)
+	}
+	content, _ = ioutil.ReadFile(fname)
+	os.Remove(fname)
+	if !strings.Contains(string(content), "<polyline") {
+		t.Errorf("Expected a polyline in the SVG file\n")
+	}
+}
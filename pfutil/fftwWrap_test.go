@@ -2,6 +2,7 @@ package pfutil
 
 import (
 	"math"
+	"math/cmplx"
 	"testing"
 
 	"github.com/davidkleiven/gosfft/sfft"
@@ -60,6 +61,12 @@ func TestConjugateNode(t *testing.T) {
 	for i, test := range []struct {
 		Dim []int
 	}{
+		{
+			Dim: []int{8},
+		},
+		{
+			Dim: []int{9},
+		},
 		{
 			Dim: []int{8, 8},
 		},
@@ -88,3 +95,73 @@ func TestConjugateNode(t *testing.T) {
 		}
 	}
 }
+
+func TestFreq1D(t *testing.T) {
+	ft := NewFFTW([]int{8})
+	expect := []float64{0.0, 0.125, 0.25, 0.375, 0.5, -0.375, -0.25, -0.125}
+	for i := range expect {
+		f := ft.Freq(i)
+		if len(f) != 1 || math.Abs(f[0]-expect[i]) > 1e-10 {
+			t.Errorf("Node %d: Expected [%f] got %v\n", i, expect[i], f)
+		}
+	}
+}
+
+func TestFFTWNonCubic3D(t *testing.T) {
+	dims := []int{4, 6, 8}
+	ft := NewFFTW(dims)
+	data := make([]complex128, ProdInt(dims))
+
+	// Plane wave with one period along each axis
+	for i := range data {
+		pos := Pos(dims, i)
+		phase := 0.0
+		for j := range pos {
+			phase += 2.0 * math.Pi * float64(pos[j]) / float64(dims[j])
+		}
+		data[i] = complex(math.Cos(phase), math.Sin(phase))
+	}
+	ft.FFT(data)
+
+	expect := NodeIdx(dims, []int{1, 1, 1})
+	for i := range data {
+		amp := math.Sqrt(real(data[i])*real(data[i]) + imag(data[i])*imag(data[i]))
+		if i == expect && math.Abs(amp-float64(len(data))) > 1e-8 {
+			t.Errorf("Expected amplitude %d at node %d got %f\n", len(data), i, amp)
+		} else if i != expect && amp > 1e-8 {
+			t.Errorf("Expected zero amplitude at node %d (freq %v) got %f\n", i, ft.Freq(i), amp)
+		}
+	}
+}
+
+func TestFFTWMatchesDirectSum3D(t *testing.T) {
+	for i, dims := range [][]int{{3, 4, 5}, {6, 4, 2}, {4, 4, 4}} {
+		ft := NewFFTW(dims)
+		data := make([]complex128, ProdInt(dims))
+		for j := range data {
+			data[j] = complex(math.Sin(float64(j)), math.Cos(0.5*float64(j)))
+		}
+
+		// Direct evaluation of the DFT using the frequencies returned by Freq
+		expect := make([]complex128, len(data))
+		for k := range expect {
+			freq := ft.Freq(k)
+			for j := range data {
+				pos := Pos(dims, j)
+				phase := 0.0
+				for d := range pos {
+					phase -= 2.0 * math.Pi * freq[d] * float64(pos[d])
+				}
+				expect[k] += data[j] * complex(math.Cos(phase), math.Sin(phase))
+			}
+		}
+
+		ft.FFT(data)
+		for k := range data {
+			if cmplx.Abs(data[k]-expect[k]) > 1e-8 {
+				t.Errorf("Test #%d: Node %d: Expected %v got %v\n", i, k, expect[k], data[k])
+				break
+			}
+		}
+	}
+}
@@ -73,9 +73,11 @@ func (g *Grid) FromComplex(carray []complex128) {
 
 // SaveCsv stores the grid in a text file format. The format of the
 // produced file is
-// 1. For 2D grids
+// 1. For 1D grids
+// x, value
+// 2. For 2D grids
 // x, y, value
-// 2. For 3D grids
+// 3. For 3D grids
 // x, y, z, value
 func (g Grid) SaveCsv(fname string) {
 	f, err := os.Create(fname)
@@ -88,6 +90,8 @@ func (g Grid) SaveCsv(fname string) {
 	dim := len(g.Dims)
 	if dim == 3 {
 		header = "x,y,z,value\n"
+	} else if dim == 1 {
+		header = "x,value\n"
 	}
 	f.WriteString(header)
 	for i := range g.Data {
@@ -0,0 +1,288 @@
+package pfutil
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	imgdraw "image/draw"
+	"image/gif"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette"
+	"gonum.org/v1/plot/palette/moreland"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+)
+
+// colorMaps holds constructors of all colormaps that can be selected by name
+var colorMaps = map[string]func() palette.ColorMap{
+	"kindlmann":         moreland.Kindlmann,
+	"extendedkindlmann": moreland.ExtendedKindlmann,
+	"blackbody":         moreland.BlackBody,
+	"extendedblackbody": moreland.ExtendedBlackBody,
+	"bluered":           func() palette.ColorMap { return moreland.SmoothBlueRed() },
+	"bluetan":           func() palette.ColorMap { return moreland.SmoothBlueTan() },
+	"greenpurple":       func() palette.ColorMap { return moreland.SmoothGreenPurple() },
+	"greenred":          func() palette.ColorMap { return moreland.SmoothGreenRed() },
+	"purpleorange":      func() palette.ColorMap { return moreland.SmoothPurpleOrange() },
+}
+
+// ColorMapNames returns the names of all colormaps that can be passed to NewColorMap
+func ColorMapNames() []string {
+	names := []string{}
+	for name := range colorMaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewColorMap returns the colormap with the passed name (see ColorMapNames). The name is
+// case insensitive. An empty name gives the default colormap (kindlmann)
+func NewColorMap(name string) (palette.ColorMap, error) {
+	if name == "" {
+		name = "kindlmann"
+	}
+	cmap, ok := colorMaps[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("heatmap: unknown colormap %s. Must be one of %v", name, ColorMapNames())
+	}
+	return cmap(), nil
+}
+
+// HeatMap is a two dimensional slice of data on a grid. It implements the
+// plotter.GridXYZ interface
+type HeatMap struct {
+	Data   []float64
+	Nx, Ny int
+}
+
+// NewHeatMap returns a heat map of the data. For two dimensional data, the heat map covers
+// the entire domain. For three dimensional data, the heat map is the plane where the
+// coordinate along the axis normal (0, 1 or 2) is equal to pos. The in-plane axes
+// are the two remaining axes in increasing order.
+func NewHeatMap(data []float64, domainSize []int, normal int, pos int) (HeatMap, error) {
+	if len(data) != ProdInt(domainSize) {
+		return HeatMap{}, fmt.Errorf("heatmap: data has length %d, but the domain has %d nodes", len(data), ProdInt(domainSize))
+	}
+	switch len(domainSize) {
+	case 1:
+		return HeatMap{Data: data, Nx: domainSize[0], Ny: 1}, nil
+	case 2:
+		return HeatMap{Data: data, Nx: domainSize[0], Ny: domainSize[1]}, nil
+	case 3:
+		if normal < 0 || normal > 2 {
+			return HeatMap{}, fmt.Errorf("heatmap: the normal must be 0, 1 or 2. Got %d", normal)
+		}
+		if pos < 0 || pos >= domainSize[normal] {
+			return HeatMap{}, fmt.Errorf("heatmap: slice %d is outside the domain %v", pos, domainSize)
+		}
+		axes := []int{}
+		for i := 0; i < 3; i++ {
+			if i != normal {
+				axes = append(axes, i)
+			}
+		}
+		hm := HeatMap{
+			Data: make([]float64, domainSize[axes[0]]*domainSize[axes[1]]),
+			Nx:   domainSize[axes[0]],
+			Ny:   domainSize[axes[1]],
+		}
+		nodePos := make([]int, 3)
+		nodePos[normal] = pos
+		for x := 0; x < hm.Nx; x++ {
+			for y := 0; y < hm.Ny; y++ {
+				nodePos[axes[0]] = x
+				nodePos[axes[1]] = y
+				hm.Data[x*hm.Ny+y] = data[NodeIdx(domainSize, nodePos)]
+			}
+		}
+		return hm, nil
+	}
+	return HeatMap{}, fmt.Errorf("heatmap: unsupported domain %v", domainSize)
+}
+
+// Dims returns the number of columns and rows
+func (h HeatMap) Dims() (c, r int) {
+	return h.Nx, h.Ny
+}
+
+// X returns the x coordinate of column c
+func (h HeatMap) X(c int) float64 {
+	return float64(c)
+}
+
+// Y returns the y coordinate of row r
+func (h HeatMap) Y(r int) float64 {
+	return float64(r)
+}
+
+// Z returns the value at column c and row r
+func (h HeatMap) Z(c, r int) float64 {
+	return h.Data[c*h.Ny+r]
+}
+
+// SetColorRange sets the range of the colormap. If max > min, the range is fixed to
+// [min, max]. Otherwise, it is set to the range of the data extended by 5% on each side
+func SetColorRange(cmap palette.ColorMap, data plotter.GridXYZ, min, max float64) {
+	if max <= min {
+		min, max = gridRange(data)
+		rng := max - min
+		if rng == 0.0 {
+			rng = 1.0
+		}
+		min -= 0.05 * rng
+		max += 0.05 * rng
+	}
+	cmap.SetMin(min)
+	cmap.SetMax(max)
+}
+
+// gridRange returns the minimum and maximum value of the data. NaN values are ignored
+func gridRange(data plotter.GridXYZ) (float64, float64) {
+	min := math.Inf(1)
+	max := math.Inf(-1)
+	c, r := data.Dims()
+	for i := 0; i < c; i++ {
+		for j := 0; j < r; j++ {
+			v := data.Z(i, j)
+			if math.IsNaN(v) {
+				continue
+			}
+			min = math.Min(min, v)
+			max = math.Max(max, v)
+		}
+	}
+	if min > max {
+		return 0.0, 0.0
+	}
+	return min, max
+}
+
+// HeatMapImage returns an image where each pixel is colored according to the value of
+// the corresponding node. Values outside the range of the colormap are given the color
+// of the closest end point.
+func HeatMapImage(data plotter.GridXYZ, cmap palette.ColorMap) (*image.RGBA64, error) {
+	n, m := data.Dims()
+	img := image.NewRGBA64(image.Rectangle{
+		Min: image.Point{X: 0, Y: 0},
+		Max: image.Point{X: n, Y: m},
+	})
+
+	for i := 0; i < n; i++ {
+		for j := 0; j < m; j++ {
+			v := math.Max(cmap.Min(), math.Min(cmap.Max(), data.Z(i, j)))
+			color, err := cmap.At(v)
+			if err != nil {
+				return img, err
+			}
+			img.Set(i, j, color)
+		}
+	}
+	return img, nil
+}
+
+// RenderHeatMap draws the data as an image with a colorbar on top. The title is placed
+// above the colorbar. The range of the colormap must be set in advance (see
+// SetColorRange)
+func RenderHeatMap(data plotter.GridXYZ, cmap palette.ColorMap, title string) (*vgimg.Canvas, error) {
+	plt := plot.New()
+
+	heatImg, err := HeatMapImage(data, cmap)
+	if err != nil {
+		return nil, err
+	}
+	n, m := data.Dims()
+	pImg := plotter.NewImage(heatImg, 0, 0, float64(n), float64(m))
+	plt.Add(pImg)
+
+	barplt := plot.New()
+
+	plt.X.Label.Text = "x position (\u0394 x)"
+	plt.Y.Label.Text = "y position (\u0394 x)"
+
+	bar := plotter.ColorBar{
+		ColorMap: cmap,
+	}
+	barplt.Add(&bar)
+
+	img := vgimg.New(4*vg.Inch, 4*vg.Inch)
+	dc := draw.New(img)
+	top := draw.Crop(dc, 0.325*vg.Inch, -0.325*vg.Inch, 3.35*vg.Inch, 0.0)
+	bottom := draw.Crop(dc, 0.325*vg.Inch, -0.325*vg.Inch, 0.0, -0.65*vg.Inch)
+	barplt.HideY()
+	barplt.Title.Text = title
+
+	barplt.Draw(top)
+	plt.Draw(bottom)
+	return img, nil
+}
+
+// SavePNG stores the canvas as a PNG image
+func SavePNG(fname string, img *vgimg.Canvas) error {
+	out, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = vgimg.PngCanvas{Canvas: img}.WriteTo(out)
+	return err
+}
+
+// GifPalette returns a palette with 256 colors suitable for animations of heat maps. The
+// first 192 colors are sampled from the colormap and the remaining colors are gray levels
+// used for axes, labels and the background.
+func GifPalette(cmap palette.ColorMap) color.Palette {
+	numSamples := 192
+	pal := color.Palette{}
+	for i := 0; i < numSamples; i++ {
+		v := cmap.Min() + (cmap.Max()-cmap.Min())*float64(i)/float64(numSamples-1)
+		c, err := cmap.At(v)
+		if err != nil {
+			c = color.Black
+		}
+		pal = append(pal, c)
+	}
+	numGray := 256 - numSamples
+	for i := 0; i < numGray; i++ {
+		level := uint8(255 * i / (numGray - 1))
+		pal = append(pal, color.Gray{Y: level})
+	}
+	return pal
+}
+
+// PalettedImage converts an image to a paletted image that can be used as a frame in
+// an animated GIF
+func PalettedImage(img image.Image, pal color.Palette) *image.Paletted {
+	frame := image.NewPaletted(img.Bounds(), pal)
+	imgdraw.Draw(frame, img.Bounds(), img, img.Bounds().Min, imgdraw.Src)
+	return frame
+}
+
+// SaveGIF stores the frames as an animated GIF that loops forever. The delay between
+// frames is given in 100ths of a second.
+func SaveGIF(fname string, frames []*image.Paletted, delay int) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("gif: no frames to write")
+	}
+	anim := gif.GIF{
+		Image: frames,
+		Delay: make([]int, len(frames)),
+	}
+	for i := range anim.Delay {
+		anim.Delay[i] = delay
+	}
+
+	out, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return gif.EncodeAll(out, &anim)
+}
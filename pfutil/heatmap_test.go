@@ -0,0 +1,152 @@
+package pfutil
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestNewHeatMapSlice(t *testing.T) {
+	domainSize := []int{3, 4, 5}
+	data := make([]float64, ProdInt(domainSize))
+	for i := range data {
+		pos := Pos(domainSize, i)
+		data[i] = float64(100*pos[0] + 10*pos[1] + pos[2])
+	}
+
+	for i, test := range []struct {
+		normal int
+		pos    int
+		nx, ny int
+		value  func(x, y int) float64
+	}{
+		{
+			normal: 2,
+			pos:    3,
+			nx:     3,
+			ny:     4,
+			value:  func(x, y int) float64 { return float64(100*x + 10*y + 3) },
+		},
+		{
+			normal: 1,
+			pos:    2,
+			nx:     3,
+			ny:     5,
+			value:  func(x, y int) float64 { return float64(100*x + 20 + y) },
+		},
+		{
+			normal: 0,
+			pos:    1,
+			nx:     4,
+			ny:     5,
+			value:  func(x, y int) float64 { return float64(100 + 10*x + y) },
+		},
+	} {
+		hm, err := NewHeatMap(data, domainSize, test.normal, test.pos)
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+		c, r := hm.Dims()
+		if c != test.nx || r != test.ny {
+			t.Errorf("Test #%d: Expected dims (%d, %d) got (%d, %d)\n", i, test.nx, test.ny, c, r)
+			continue
+		}
+		for x := 0; x < c; x++ {
+			for y := 0; y < r; y++ {
+				if hm.Z(x, y) != test.value(x, y) {
+					t.Errorf("Test #%d: Expected %f got %f\n", i, test.value(x, y), hm.Z(x, y))
+				}
+			}
+		}
+	}
+
+	if _, err := NewHeatMap(data, domainSize, 2, 5); err == nil {
+		t.Errorf("Expected error for slice outside the domain\n")
+	}
+}
+
+func TestSetColorRange(t *testing.T) {
+	hm := HeatMap{Data: []float64{-1.0, 0.0, 1.0, 3.0}, Nx: 2, Ny: 2}
+	cmap, err := NewColorMap("BlueRed")
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	SetColorRange(cmap, hm, 0.0, 0.0)
+	if math.Abs(cmap.Min()+1.2) > 1e-10 || math.Abs(cmap.Max()-3.2) > 1e-10 {
+		t.Errorf("Expected auto range (-1.2, 3.2) got (%f, %f)\n", cmap.Min(), cmap.Max())
+	}
+
+	SetColorRange(cmap, hm, 0.0, 1.0)
+	if cmap.Min() != 0.0 || cmap.Max() != 1.0 {
+		t.Errorf("Expected fixed range (0, 1) got (%f, %f)\n", cmap.Min(), cmap.Max())
+	}
+
+	// Values outside the fixed range should get the color of the end points
+	img, err := HeatMapImage(hm, cmap)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	low, _ := cmap.At(0.0)
+	high, _ := cmap.At(1.0)
+	if !sameColor(img.At(0, 0), low) || !sameColor(img.At(1, 1), high) {
+		t.Errorf("Values outside the range were not clamped\n")
+	}
+
+	if _, err := NewColorMap("unknownColorMap"); err == nil {
+		t.Errorf("Expected error for unknown colormap\n")
+	}
+}
+
+func sameColor(c1 color.Color, c2 color.Color) bool {
+	r1, g1, b1, a1 := c1.RGBA()
+	r2, g2, b2, a2 := c2.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
+
+func TestSaveGIF(t *testing.T) {
+	hm := HeatMap{Data: []float64{0.0, 1.0, 2.0, 3.0, 4.0, 5.0}, Nx: 3, Ny: 2}
+	cmap, _ := NewColorMap("")
+	frames := []*image.Paletted{}
+	for i := 0; i < 3; i++ {
+		SetColorRange(cmap, hm, 0.0, 0.0)
+		canvas, err := RenderHeatMap(hm, cmap, "field")
+		if err != nil {
+			t.Errorf("%s\n", err)
+			return
+		}
+		frames = append(frames, PalettedImage(canvas.Image(), GifPalette(cmap)))
+	}
+
+	fname := "heatmap_test.gif"
+	if err := SaveGIF(fname, frames, 10); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	defer os.Remove(fname)
+
+	infile, err := os.Open(fname)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	defer infile.Close()
+	anim, err := gif.DecodeAll(infile)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	if len(anim.Image) != 3 || anim.Delay[0] != 10 {
+		t.Errorf("Expected 3 frames with delay 10. Got %d frames with delay %v\n", len(anim.Image), anim.Delay)
+	}
+
+	if err := SaveGIF(fname, nil, 10); err == nil {
+		t.Errorf("Expected error when there are no frames\n")
+	}
+}
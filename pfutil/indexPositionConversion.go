@@ -1,5 +1,10 @@
 package pfutil
 
+// nodeIdx1 returns the node index for 1D grid
+func nodeIdx1(domainSize []int, idx []int) int {
+	return idx[0]
+}
+
 // nodeIdx2 returns the node index for 2D grid
 func nodeIdx2(domainSize []int, idx []int) int {
 	return idx[0]*domainSize[1] + idx[1]
@@ -12,12 +17,14 @@ func nodeIdx3(domainSize []int, idx []int) int {
 
 // NodeIdx returns the index of the node corresponding to a given typle of index
 func NodeIdx(domainSize []int, idx []int) int {
-	if len(domainSize) == 2 && len(idx) == 2 {
+	if len(domainSize) == 1 && len(idx) == 1 {
+		return nodeIdx1(domainSize, idx)
+	} else if len(domainSize) == 2 && len(idx) == 2 {
 		return nodeIdx2(domainSize, idx)
 	} else if len(domainSize) == 3 && len(idx) == 3 {
 		return nodeIdx3(domainSize, idx)
 	}
-	panic("util: Domain size and idx has to be of length 2 or 3")
+	panic("util: Domain size and idx has to be of length 1, 2 or 3")
 }
 
 func pos3(domainSize []int, nodeNum int) []int {
@@ -33,12 +40,18 @@ func pos2(domainSize []int, nodeNum int) []int {
 	return []int{row, col}
 }
 
+func pos1(domainSize []int, nodeNum int) []int {
+	return []int{nodeNum}
+}
+
 // Pos converts the node number to position
 func Pos(domainSize []int, nodeNum int) []int {
-	if len(domainSize) == 2 {
+	if len(domainSize) == 1 {
+		return pos1(domainSize, nodeNum)
+	} else if len(domainSize) == 2 {
 		return pos2(domainSize, nodeNum)
 	} else if len(domainSize) == 3 {
 		return pos3(domainSize, nodeNum)
 	}
-	panic("util: Domain size has to be either 2 or 3")
+	panic("util: Domain size has to be either 1, 2 or 3")
 }
@@ -7,6 +7,10 @@ func TestNode2PosRoundTrip(t *testing.T) {
 		nodeNum    int
 		domainSize []int
 	}{
+		{
+			nodeNum:    4,
+			domainSize: []int{9},
+		},
 		{
 			nodeNum:    16,
 			domainSize: []int{5, 7},
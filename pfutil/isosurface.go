@@ -0,0 +1,265 @@
+package pfutil
+
+import "math"
+
+// TriangleMesh represents a triangulated surface. Each entry in Triangles holds the indices
+// of the three vertices of a triangle. The vertices are ordered such that the normal vector
+// given by the right hand rule points out of the region where the field is larger than the
+// level
+type TriangleMesh struct {
+	Vertices  [][]float64
+	Triangles [][3]int
+
+	// EnclosedVolume is the volume of the region where the linearly interpolated field is
+	// larger than the level
+	EnclosedVolume float64
+}
+
+// Area returns the total area of the surface
+func (tm *TriangleMesh) Area() float64 {
+	area := 0.0
+	for i := range tm.Triangles {
+		area += 0.5 * norm(tm.normal(i))
+	}
+	return area
+}
+
+// normal returns the (non-normalized) normal vector of triangle i. The length of the
+// vector is twice the area of the triangle
+func (tm *TriangleMesh) normal(i int) []float64 {
+	a := tm.Vertices[tm.Triangles[i][0]]
+	b := tm.Vertices[tm.Triangles[i][1]]
+	c := tm.Vertices[tm.Triangles[i][2]]
+	return cross(sub(b, a), sub(c, a))
+}
+
+// edgeKey identifies the edge between two grid nodes. The nodes are given by their
+// unwrapped position, such that edges at opposite sides of a periodic domain are distinct
+type edgeKey struct {
+	a, b [3]int
+}
+
+// newEdgeKey returns the key of the edge between the nodes at positions p and q
+func newEdgeKey(p, q [3]int) edgeKey {
+	for i := range p {
+		if p[i] < q[i] {
+			return edgeKey{a: p, b: q}
+		} else if p[i] > q[i] {
+			return edgeKey{a: q, b: p}
+		}
+	}
+	return edgeKey{a: p, b: q}
+}
+
+// gridCorner holds the position and the value of a corner of a cell
+type gridCorner struct {
+	pos   [3]int
+	value float64
+}
+
+// crossing returns the point on the edge between two corners where the linearly
+// interpolated field is equal to level
+func crossing(p, q gridCorner, level float64) []float64 {
+	t := (level - p.value) / (q.value - p.value)
+	point := make([]float64, 3)
+	for i := range point {
+		point[i] = float64(p.pos[i]) + t*float64(q.pos[i]-p.pos[i])
+	}
+	return point
+}
+
+// cellCorners returns the unwrapped integer positions of the corners of the cell with
+// its lower corner at pos (in 2D the last component is zero), together with the field value
+// at each corner. The corners are ordered by the binary representation of the corner number
+func cellCorners(data ImmutableSlice, domainSize []int, pos []int) []gridCorner {
+	dim := len(domainSize)
+	corners := make([]gridCorner, 1<<uint(dim))
+	wrapped := make([]int, dim)
+	for c := range corners {
+		for d := 0; d < dim; d++ {
+			corners[c].pos[d] = pos[d] + (c>>uint(d))&1
+			wrapped[d] = wrapIndex(corners[c].pos[d], domainSize[d])
+		}
+		corners[c].value = data.Get(NodeIdx(domainSize, wrapped))
+	}
+	return corners
+}
+
+// numCells returns the number of cells along each direction. With periodic boundary
+// conditions, the cells between the last and the first node are included
+func numCells(domainSize []int, periodic bool) []int {
+	n := make([]int, len(domainSize))
+	for i := range n {
+		n[i] = domainSize[i]
+		if !periodic {
+			n[i]--
+		}
+	}
+	return n
+}
+
+// kuhnTetrahedra holds the corners of the six tetrahedra of the Kuhn triangulation of a
+// cube. All tetrahedra share the diagonal from corner 0 to corner 7. The triangulation
+// splits all faces of the cube along the diagonal from the lowest to the highest corner,
+// such that neighbouring cubes are split consistently
+var kuhnTetrahedra = [6][4]int{
+	{0, 1, 3, 7},
+	{0, 1, 5, 7},
+	{0, 2, 3, 7},
+	{0, 2, 6, 7},
+	{0, 4, 5, 7},
+	{0, 4, 6, 7},
+}
+
+// MarchingCubes extracts the isosurface where the field is equal to level. The field is
+// interpolated linearly on the tetrahedra obtained by splitting each cube of eight nodes
+// into six tetrahedra. The tetrahedral decomposition avoids the ambiguous configurations of the
+// classical marching cubes tables and gives a watertight surface. If periodic is true,
+// the cells between the last and the first node along each direction are included, in
+// which case the vertices of these cells lie between N-1 and N, where N is the number
+// of nodes along the direction. The returned mesh also holds the enclosed volume.
+func MarchingCubes(data ImmutableSlice, domainSize []int, level float64, periodic bool) TriangleMesh {
+	if len(domainSize) != 3 {
+		panic("isosurface: Marching cubes requires a 3D domain")
+	}
+	builder := meshBuilder{vertexIdx: make(map[edgeKey]int), level: level}
+	cells := NewProduct(numCells(domainSize, periodic))
+	for pos := cells.Next(); pos != nil; pos = cells.Next() {
+		corners := cellCorners(data, domainSize, pos)
+		for _, tet := range kuhnTetrahedra {
+			builder.addTetrahedron([4]gridCorner{corners[tet[0]], corners[tet[1]], corners[tet[2]], corners[tet[3]]})
+		}
+	}
+	return builder.mesh
+}
+
+// meshBuilder constructs a triangle mesh tetrahedron by tetrahedron
+type meshBuilder struct {
+	mesh      TriangleMesh
+	vertexIdx map[edgeKey]int
+	level     float64
+}
+
+// vertex returns the index of the vertex on the edge between two corners. The vertex is
+// created if it does not already exist
+func (mb *meshBuilder) vertex(p, q gridCorner) int {
+	key := newEdgeKey(p.pos, q.pos)
+	if idx, ok := mb.vertexIdx[key]; ok {
+		return idx
+	}
+	idx := len(mb.mesh.Vertices)
+	mb.mesh.Vertices = append(mb.mesh.Vertices, crossing(p, q, mb.level))
+	mb.vertexIdx[key] = idx
+	return idx
+}
+
+// addTriangle adds a triangle oriented such that the normal points along outward
+func (mb *meshBuilder) addTriangle(tri [3]int, outward []float64) {
+	mb.mesh.Triangles = append(mb.mesh.Triangles, tri)
+	last := len(mb.mesh.Triangles) - 1
+	if Dot(mb.mesh.normal(last), outward) < 0.0 {
+		mb.mesh.Triangles[last] = [3]int{tri[0], tri[2], tri[1]}
+	}
+}
+
+// addTetrahedron adds the part of the surface that lies inside the tetrahedron and the
+// volume of the part of the tetrahedron where the field is larger than the level
+func (mb *meshBuilder) addTetrahedron(corners [4]gridCorner) {
+	inside := []gridCorner{}
+	outside := []gridCorner{}
+	for _, c := range corners {
+		if c.value > mb.level {
+			inside = append(inside, c)
+		} else {
+			outside = append(outside, c)
+		}
+	}
+
+	if len(inside) == 0 {
+		return
+	}
+	if len(outside) == 0 {
+		mb.mesh.EnclosedVolume += tetVolume(cornerPos(corners[0]), cornerPos(corners[1]), cornerPos(corners[2]), cornerPos(corners[3]))
+		return
+	}
+
+	// The normal points from the inside corners towards the outside corners
+	outward := sub(meanPos(outside), meanPos(inside))
+	v := func(i int) []float64 { return mb.mesh.Vertices[i] }
+
+	switch len(inside) {
+	case 1:
+		a := inside[0]
+		tri := [3]int{mb.vertex(a, outside[0]), mb.vertex(a, outside[1]), mb.vertex(a, outside[2])}
+		mb.addTriangle(tri, outward)
+		mb.mesh.EnclosedVolume += tetVolume(cornerPos(a), v(tri[0]), v(tri[1]), v(tri[2]))
+	case 3:
+		d := outside[0]
+		tri := [3]int{mb.vertex(inside[0], d), mb.vertex(inside[1], d), mb.vertex(inside[2], d)}
+		mb.addTriangle(tri, outward)
+		total := tetVolume(cornerPos(corners[0]), cornerPos(corners[1]), cornerPos(corners[2]), cornerPos(corners[3]))
+		mb.mesh.EnclosedVolume += total - tetVolume(cornerPos(d), v(tri[0]), v(tri[1]), v(tri[2]))
+	case 2:
+		a, b := inside[0], inside[1]
+		c, d := outside[0], outside[1]
+		pac := mb.vertex(a, c)
+		pad := mb.vertex(a, d)
+		pbc := mb.vertex(b, c)
+		pbd := mb.vertex(b, d)
+
+		// The quadrilateral is split along the diagonal from pac to pbd. The enclosed part
+		// is a prism with the triangles (a, pac, pad) and (b, pbc, pbd) as end faces, which
+		// is split into three tetrahedra using the same diagonal
+		mb.addTriangle([3]int{pac, pbc, pbd}, outward)
+		mb.addTriangle([3]int{pac, pbd, pad}, outward)
+		pa := cornerPos(a)
+		pb := cornerPos(b)
+		mb.mesh.EnclosedVolume += tetVolume(pa, v(pac), v(pad), v(pbd)) +
+			tetVolume(pa, v(pac), v(pbc), v(pbd)) +
+			tetVolume(pa, pb, v(pbc), v(pbd))
+	}
+}
+
+// cornerPos returns the position of the corner as a float slice
+func cornerPos(c gridCorner) []float64 {
+	return []float64{float64(c.pos[0]), float64(c.pos[1]), float64(c.pos[2])}
+}
+
+// meanPos returns the average position of the corners
+func meanPos(corners []gridCorner) []float64 {
+	mean := make([]float64, 3)
+	for _, c := range corners {
+		for i := range mean {
+			mean[i] += float64(c.pos[i]) / float64(len(corners))
+		}
+	}
+	return mean
+}
+
+// tetVolume returns the volume of the tetrahedron with the passed vertices
+func tetVolume(a, b, c, d []float64) float64 {
+	return math.Abs(Dot(sub(b, a), cross(sub(c, a), sub(d, a)))) / 6.0
+}
+
+// sub returns a - b
+func sub(a, b []float64) []float64 {
+	res := make([]float64, len(a))
+	for i := range a {
+		res[i] = a[i] - b[i]
+	}
+	return res
+}
+
+// cross returns the cross product of two 3D vectors
+func cross(a, b []float64) []float64 {
+	return []float64{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+}
+
+// norm returns the euclidean length of the vector
+func norm(a []float64) float64 {
+	return math.Sqrt(Dot(a, a))
+}
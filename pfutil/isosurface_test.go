@@ -0,0 +1,124 @@
+package pfutil
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+// sphereField returns radius - distance from the center of the domain
+func sphereField(N int, radius float64) *RealSlice {
+	domainSize := []int{N, N, N}
+	data := make([]float64, N*N*N)
+	center := 0.5 * float64(N)
+	for i := range data {
+		pos := Pos(domainSize, i)
+		r := 0.0
+		for _, x := range pos {
+			r += (float64(x) - center) * (float64(x) - center)
+		}
+		data[i] = radius - math.Sqrt(r)
+	}
+	return &RealSlice{Data: data}
+}
+
+func TestMarchingCubesPlane(t *testing.T) {
+	N := 8
+	domainSize := []int{N, N, N}
+	data := make([]float64, N*N*N)
+	for i := range data {
+		data[i] = 3.3 - float64(Pos(domainSize, i)[0])
+	}
+	slice := RealSlice{Data: data}
+
+	mesh := MarchingCubes(&slice, domainSize, 0.0, false)
+	side := float64(N - 1)
+	if math.Abs(mesh.Area()-side*side) > 1e-8 {
+		t.Errorf("Expected area %f got %f\n", side*side, mesh.Area())
+	}
+	if math.Abs(mesh.EnclosedVolume-3.3*side*side) > 1e-8 {
+		t.Errorf("Expected volume %f got %f\n", 3.3*side*side, mesh.EnclosedVolume)
+	}
+
+	// The normals should point in the positive x-direction (towards decreasing field)
+	for i := range mesh.Triangles {
+		if n := mesh.normal(i); n[0] <= 0.0 {
+			t.Errorf("Triangle %d: Expected normal along positive x. Got %v\n", i, n)
+			break
+		}
+	}
+}
+
+func TestMarchingCubesSphere(t *testing.T) {
+	N := 32
+	radius := 8.0
+	mesh := MarchingCubes(sphereField(N, radius), []int{N, N, N}, 0.0, true)
+
+	area := 4.0 * math.Pi * radius * radius
+	if math.Abs(mesh.Area()-area) > 0.02*area {
+		t.Errorf("Expected area %f got %f\n", area, mesh.Area())
+	}
+	volume := 4.0 * math.Pi * radius * radius * radius / 3.0
+	if math.Abs(mesh.EnclosedVolume-volume) > 0.02*volume {
+		t.Errorf("Expected volume %f got %f\n", volume, mesh.EnclosedVolume)
+	}
+
+	// The surface should be watertight. Each edge is shared by exactly two triangles
+	edges := make(map[[2]int]int)
+	for _, tri := range mesh.Triangles {
+		for j := range tri {
+			a, b := tri[j], tri[(j+1)%3]
+			if a > b {
+				a, b = b, a
+			}
+			edges[[2]int{a, b}]++
+		}
+	}
+	for e, count := range edges {
+		if count != 2 {
+			t.Errorf("Edge %v is shared by %d triangles\n", e, count)
+			break
+		}
+	}
+
+	// Divergence theorem: the volume enclosed by the closed surface equals the volume
+	divVolume := 0.0
+	for i, tri := range mesh.Triangles {
+		n := mesh.normal(i)
+		c := mesh.Vertices[tri[0]]
+		divVolume += Dot(c, n) / 6.0
+	}
+	if math.Abs(divVolume-mesh.EnclosedVolume) > 1e-6*volume {
+		t.Errorf("Volume from divergence theorem %f differs from enclosed volume %f\n", divVolume, mesh.EnclosedVolume)
+	}
+}
+
+func TestMeshExport(t *testing.T) {
+	N := 8
+	mesh := MarchingCubes(sphereField(N, 2.5), []int{N, N, N}, 0.0, false)
+	for _, test := range []struct {
+		fname  string
+		save   func(fname string) error
+		header string
+	}{
+		{fname: "meshExport.stl", save: mesh.SaveSTL, header: "solid"},
+		{fname: "meshExport.ply", save: mesh.SavePLY, header: "ply"},
+		{fname: "meshExport.obj", save: mesh.SaveOBJ, header: "v "},
+	} {
+		if err := test.save(test.fname); err != nil {
+			t.Errorf("%s\n", err)
+			continue
+		}
+		content, err := ioutil.ReadFile(test.fname)
+		os.Remove(test.fname)
+		if err != nil {
+			t.Errorf("%s\n", err)
+			continue
+		}
+		if !strings.HasPrefix(string(content), test.header) {
+			t.Errorf("%s: Expected file to start with %s\n", test.fname, test.header)
+		}
+	}
+}
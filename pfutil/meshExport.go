@@ -0,0 +1,135 @@
+package pfutil
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+)
+
+// SaveSTL writes the mesh to an ASCII STL file
+func (tm *TriangleMesh) SaveSTL(fname string) error {
+	out, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	writer := bufio.NewWriter(out)
+	fmt.Fprintf(writer, "solid isosurface\n")
+	for i, tri := range tm.Triangles {
+		n := tm.normal(i)
+		if length := norm(n); length > 0.0 {
+			for j := range n {
+				n[j] /= length
+			}
+		}
+		fmt.Fprintf(writer, "facet normal %e %e %e\n", n[0], n[1], n[2])
+		fmt.Fprintf(writer, "outer loop\n")
+		for _, v := range tri {
+			p := tm.Vertices[v]
+			fmt.Fprintf(writer, "vertex %e %e %e\n", p[0], p[1], p[2])
+		}
+		fmt.Fprintf(writer, "endloop\nendfacet\n")
+	}
+	fmt.Fprintf(writer, "endsolid isosurface\n")
+	return writer.Flush()
+}
+
+// SavePLY writes the mesh to an ASCII PLY file
+func (tm *TriangleMesh) SavePLY(fname string) error {
+	out, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	writer := bufio.NewWriter(out)
+	fmt.Fprintf(writer, "ply\nformat ascii 1.0\n")
+	fmt.Fprintf(writer, "element vertex %d\nproperty float x\nproperty float y\nproperty float z\n", len(tm.Vertices))
+	fmt.Fprintf(writer, "element face %d\nproperty list uchar int vertex_indices\nend_header\n", len(tm.Triangles))
+	for _, p := range tm.Vertices {
+		fmt.Fprintf(writer, "%e %e %e\n", p[0], p[1], p[2])
+	}
+	for _, tri := range tm.Triangles {
+		fmt.Fprintf(writer, "3 %d %d %d\n", tri[0], tri[1], tri[2])
+	}
+	return writer.Flush()
+}
+
+// SaveOBJ writes the mesh to a Wavefront OBJ file
+func (tm *TriangleMesh) SaveOBJ(fname string) error {
+	out, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	writer := bufio.NewWriter(out)
+	for _, p := range tm.Vertices {
+		fmt.Fprintf(writer, "v %e %e %e\n", p[0], p[1], p[2])
+	}
+
+	// Indices in OBJ files start at 1
+	for _, tri := range tm.Triangles {
+		fmt.Fprintf(writer, "f %d %d %d\n", tri[0]+1, tri[1]+1, tri[2]+1)
+	}
+	return writer.Flush()
+}
+
+// SaveCsv writes the contour lines to a CSV file. The format is
+// polyline, x, y
+// where polyline is the index of the polyline the point belongs to. For closed polylines
+// the first point is repeated at the end
+func (c *Contours) SaveCsv(fname string) error {
+	out, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	writer := csv.NewWriter(out)
+	writer.Write([]string{"polyline", "x", "y"})
+	for i, line := range c.Polylines {
+		for _, p := range closedPoints(line) {
+			writer.Write([]string{fmt.Sprintf("%d", i), fmt.Sprintf("%e", p[0]), fmt.Sprintf("%e", p[1])})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// SaveSVG writes the contour lines to an SVG file. The width and height gives the extent
+// of the domain. The y-axis points upwards, such that the image has the same orientation
+// as plots of the field
+func (c *Contours) SaveSVG(fname string, width, height float64) error {
+	out, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	writer := bufio.NewWriter(out)
+	fmt.Fprintf(writer, "<svg xmlns=\"http://www.w3.org/2000/svg\" viewBox=\"0 0 %f %f\">\n", width, height)
+	for _, line := range c.Polylines {
+		fmt.Fprintf(writer, "<polyline fill=\"none\" stroke=\"black\" stroke-width=\"0.2\" points=\"")
+		for j, p := range closedPoints(line) {
+			if j > 0 {
+				fmt.Fprintf(writer, " ")
+			}
+			fmt.Fprintf(writer, "%f,%f", p[0], height-p[1])
+		}
+		fmt.Fprintf(writer, "\"/>\n")
+	}
+	fmt.Fprintf(writer, "</svg>\n")
+	return writer.Flush()
+}
+
+// closedPoints returns the points of the polyline. For closed polylines the first point
+// is appended at the end
+func closedPoints(line Polyline) [][]float64 {
+	if line.Closed && len(line.Points) > 0 {
+		return append(line.Points[:len(line.Points):len(line.Points)], line.Points[0])
+	}
+	return line.Points
+}
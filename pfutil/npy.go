@@ -0,0 +1,223 @@
+package pfutil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// npyMagic is the magic string at the start of all .npy files
+const npyMagic = "\x93NUMPY"
+
+// npyIndex returns the position in a NumPy array of the element at pos. If fortranOrder
+// is true the first index varies fastest, otherwise the last index varies fastest
+func npyIndex(shape []int, pos []int, fortranOrder bool) int {
+	idx := 0
+	if fortranOrder {
+		for d := len(shape) - 1; d >= 0; d-- {
+			idx = idx*shape[d] + pos[d]
+		}
+		return idx
+	}
+	for d := range shape {
+		idx = idx*shape[d] + pos[d]
+	}
+	return idx
+}
+
+// npyShape returns the python representation of the shape
+func npyShape(shape []int) string {
+	items := make([]string, len(shape))
+	for i, s := range shape {
+		items[i] = strconv.Itoa(s)
+	}
+	if len(items) == 1 {
+		return "(" + items[0] + ",)"
+	}
+	return "(" + strings.Join(items, ", ") + ")"
+}
+
+// WriteNpy writes data in the NumPy .npy format (version 1.0). The array has the shape
+// given by domainSize and dtype float64. The data is ordered such that the element
+// arr[x, y, z] in NumPy is the value at the node with position (x, y, z)
+func WriteNpy(w io.Writer, data []float64, domainSize []int) error {
+	if len(data) != ProdInt(domainSize) {
+		return fmt.Errorf("npy: data has length %d, but the domain has %d nodes", len(data), ProdInt(domainSize))
+	}
+	header := fmt.Sprintf("{'descr': '<f8', 'fortran_order': False, 'shape': %s, }", npyShape(domainSize))
+
+	// The total header length (including magic string, version and length) should be
+	// divisible by 64 and the header is terminated by a newline
+	headerLen := len(header) + 1
+	if rem := (10 + headerLen) % 64; rem != 0 {
+		headerLen += 64 - rem
+	}
+	header += strings.Repeat(" ", headerLen-len(header)-1) + "\n"
+
+	ordered := make([]float64, len(data))
+	for i := range data {
+		ordered[npyIndex(domainSize, Pos(domainSize, i), false)] = data[i]
+	}
+
+	buf := bufio.NewWriter(w)
+	buf.WriteString(npyMagic)
+	buf.Write([]byte{1, 0})
+	binary.Write(buf, binary.LittleEndian, uint16(headerLen))
+	buf.WriteString(header)
+	binary.Write(buf, binary.LittleEndian, ordered)
+	return buf.Flush()
+}
+
+var npyDescrRe = regexp.MustCompile(`'descr'\s*:\s*'([^']*)'`)
+var npyFortranRe = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+var npyShapeRe = regexp.MustCompile(`'shape'\s*:\s*\(([^)]*)\)`)
+
+// ReadNpy reads an array stored in the NumPy .npy format. Arrays with one, two or three
+// dimensions and floating point or integer dtypes are supported. The data is converted
+// to float64 and returned in the same node order as used by Pos and NodeIdx, together
+// with the shape of the array.
+func ReadNpy(r io.Reader) ([]float64, []int, error) {
+	preamble := make([]byte, 8)
+	if _, err := io.ReadFull(r, preamble); err != nil {
+		return nil, nil, err
+	}
+	if string(preamble[:6]) != npyMagic {
+		return nil, nil, fmt.Errorf("npy: not a .npy file")
+	}
+
+	var headerLen int
+	if preamble[6] == 1 {
+		var length uint16
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return nil, nil, err
+		}
+		headerLen = int(length)
+	} else {
+		var length uint32
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return nil, nil, err
+		}
+		headerLen = int(length)
+	}
+	headerBytes := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, headerBytes); err != nil {
+		return nil, nil, err
+	}
+	header := string(headerBytes)
+
+	descr := npyDescrRe.FindStringSubmatch(header)
+	fortran := npyFortranRe.FindStringSubmatch(header)
+	shapeMatch := npyShapeRe.FindStringSubmatch(header)
+	if descr == nil || fortran == nil || shapeMatch == nil {
+		return nil, nil, fmt.Errorf("npy: could not parse header %s", header)
+	}
+
+	shape := []int{}
+	for _, s := range strings.Split(shapeMatch[1], ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		shape = append(shape, n)
+	}
+	if len(shape) < 1 || len(shape) > 3 {
+		return nil, nil, fmt.Errorf("npy: only arrays with 1, 2 or 3 dimensions are supported. Got shape %v", shape)
+	}
+
+	raw, err := readNpyData(r, descr[1], ProdInt(shape))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data := make([]float64, len(raw))
+	for i := range data {
+		data[i] = raw[npyIndex(shape, Pos(shape, i), fortran[1] == "True")]
+	}
+	return data, shape, nil
+}
+
+// readNpyData reads num elements of the type given by descr and converts them to float64
+func readNpyData(r io.Reader, descr string, num int) ([]float64, error) {
+	if len(descr) < 3 {
+		return nil, fmt.Errorf("npy: unsupported dtype %s", descr)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if descr[0] == '>' {
+		order = binary.BigEndian
+	}
+
+	size, err := strconv.Atoi(descr[2:])
+	if err != nil {
+		return nil, fmt.Errorf("npy: unsupported dtype %s", descr)
+	}
+	raw := make([]byte, size*num)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, err
+	}
+	data := make([]float64, num)
+	reader := bytes.NewReader(raw)
+
+	switch descr[1:] {
+	case "f8":
+		binary.Read(reader, order, data)
+	case "f4":
+		values := make([]float32, num)
+		binary.Read(reader, order, values)
+		for i, v := range values {
+			data[i] = float64(v)
+		}
+	case "i8":
+		values := make([]int64, num)
+		binary.Read(reader, order, values)
+		for i, v := range values {
+			data[i] = float64(v)
+		}
+	case "i4":
+		values := make([]int32, num)
+		binary.Read(reader, order, values)
+		for i, v := range values {
+			data[i] = float64(v)
+		}
+	case "u1":
+		for i, v := range raw {
+			data[i] = float64(v)
+		}
+	default:
+		return nil, fmt.Errorf("npy: unsupported dtype %s", descr)
+	}
+	return data, nil
+}
+
+// SaveNpy stores the grid in the NumPy .npy format
+func (g Grid) SaveNpy(fname string) error {
+	out, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return WriteNpy(out, g.Data, g.Dims)
+}
+
+// LoadGridNpy loads a grid from a NumPy .npy file
+func LoadGridNpy(fname string) (Grid, error) {
+	infile, err := os.Open(fname)
+	if err != nil {
+		return Grid{}, err
+	}
+	defer infile.Close()
+	data, shape, err := ReadNpy(bufio.NewReader(infile))
+	if err != nil {
+		return Grid{}, err
+	}
+	return Grid{Dims: shape, Data: data}, nil
+}
@@ -0,0 +1,139 @@
+package pfutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+// npyBytes creates a .npy file in the same way as numpy.save
+func npyBytes(header string, data interface{}, order binary.ByteOrder) []byte {
+	headerLen := len(header) + 1
+	if rem := (10 + headerLen) % 64; rem != 0 {
+		headerLen += 64 - rem
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString("\x93NUMPY")
+	buf.Write([]byte{1, 0})
+	binary.Write(buf, binary.LittleEndian, uint16(headerLen))
+	buf.WriteString(header + strings.Repeat(" ", headerLen-len(header)-1) + "\n")
+	binary.Write(buf, order, data)
+	return buf.Bytes()
+}
+
+func TestReadNpy(t *testing.T) {
+	for i, test := range []struct {
+		content []byte
+		shape   []int
+		value   func(pos []int) float64
+	}{
+		{
+			// np.arange(6.0).reshape(2, 3)
+			content: npyBytes("{'descr': '<f8', 'fortran_order': False, 'shape': (2, 3), }", []float64{0, 1, 2, 3, 4, 5}, binary.LittleEndian),
+			shape:   []int{2, 3},
+			value:   func(pos []int) float64 { return float64(3*pos[0] + pos[1]) },
+		},
+		{
+			// np.asfortranarray(np.arange(6.0).reshape(2, 3)).astype('>i4')
+			content: npyBytes("{'descr': '>i4', 'fortran_order': True, 'shape': (2, 3), }", []int32{0, 3, 1, 4, 2, 5}, binary.BigEndian),
+			shape:   []int{2, 3},
+			value:   func(pos []int) float64 { return float64(3*pos[0] + pos[1]) },
+		},
+		{
+			// np.arange(24.0, dtype=np.float32).reshape(2, 3, 4)
+			content: npyBytes("{'descr': '<f4', 'fortran_order': False, 'shape': (2, 3, 4), }", float32Range(24), binary.LittleEndian),
+			shape:   []int{2, 3, 4},
+			value:   func(pos []int) float64 { return float64(12*pos[0] + 4*pos[1] + pos[2]) },
+		},
+		{
+			content: npyBytes("{'descr': '<i8', 'fortran_order': False, 'shape': (5,), }", []int64{0, 1, 2, 3, 4}, binary.LittleEndian),
+			shape:   []int{5},
+			value:   func(pos []int) float64 { return float64(pos[0]) },
+		},
+	} {
+		data, shape, err := ReadNpy(bytes.NewReader(test.content))
+		if err != nil {
+			t.Errorf("Test #%d: %s\n", i, err)
+			continue
+		}
+		if len(shape) != len(test.shape) {
+			t.Errorf("Test #%d: Expected shape %v got %v\n", i, test.shape, shape)
+			continue
+		}
+		for j := range data {
+			expect := test.value(Pos(shape, j))
+			if math.Abs(data[j]-expect) > 1e-10 {
+				t.Errorf("Test #%d: Node %d: Expected %f got %f\n", i, j, expect, data[j])
+				break
+			}
+		}
+	}
+}
+
+func float32Range(n int) []float32 {
+	data := make([]float32, n)
+	for i := range data {
+		data[i] = float32(i)
+	}
+	return data
+}
+
+func TestWriteNpy(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := WriteNpy(buf, []float64{1.0, 2.0, 3.0}, []int{3}); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	content := buf.Bytes()
+	expect := npyBytes("{'descr': '<f8', 'fortran_order': False, 'shape': (3,), }", []float64{1.0, 2.0, 3.0}, binary.LittleEndian)
+	if !bytes.Equal(content, expect) {
+		t.Errorf("Expected\n%q\ngot\n%q\n", expect, content)
+	}
+	if (len(content)-24)%64 != 0 {
+		t.Errorf("Header is not aligned to 64 bytes\n")
+	}
+}
+
+func TestGridNpyRoundTrip(t *testing.T) {
+	grid := NewGrid([]int{3, 4, 5})
+	for i := range grid.Data {
+		grid.Data[i] = float64(i) * 0.5
+	}
+	fname := "gridRoundTrip.npy"
+	defer os.Remove(fname)
+	if err := grid.SaveNpy(fname); err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+
+	loaded, err := LoadGridNpy(fname)
+	if err != nil {
+		t.Errorf("%s\n", err)
+		return
+	}
+	for i := range grid.Dims {
+		if loaded.Dims[i] != grid.Dims[i] {
+			t.Errorf("Expected dims %v got %v\n", grid.Dims, loaded.Dims)
+		}
+	}
+	for i := range grid.Data {
+		if math.Abs(grid.Data[i]-loaded.Data[i]) > 1e-10 {
+			t.Errorf("Node %d: Expected %f got %f\n", i, grid.Data[i], loaded.Data[i])
+			return
+		}
+	}
+
+	// In NumPy arr[x, y, z] should be the value at position (x, y, z). For a C-ordered
+	// array the position of that element is (x*4 + y)*5 + z
+	content, _ := ioutil.ReadFile(fname)
+	raw := make([]float64, 60)
+	binary.Read(bytes.NewReader(content[len(content)-480:]), binary.LittleEndian, raw)
+	pos := []int{2, 1, 3}
+	if math.Abs(raw[(2*4+1)*5+3]-grid.Get(pos)) > 1e-10 {
+		t.Errorf("Expected %f got %f\n", grid.Get(pos), raw[(2*4+1)*5+3])
+	}
+}
@@ -0,0 +1,298 @@
+package pfutil
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// BoundaryCondition represents the boundary condition along one axis of the
+// simulation domain. The non-periodic boundary conditions are realized via
+// cosine and sine transforms. The naming of the transform kinds follows FFTW
+// (REDFT00, REDFT10 and RODFT00)
+type BoundaryCondition int
+
+const (
+	// Periodic boundary conditions (plain FFT)
+	Periodic BoundaryCondition = iota
+
+	// NeumannDCT1 gives zero flux boundary conditions where the boundary is located
+	// at the first and last grid point (DCT-I, REDFT00)
+	NeumannDCT1
+
+	// NeumannDCT2 gives zero flux boundary conditions where the boundary is located
+	// half a grid spacing outside the first and last grid point (DCT-II, REDFT10)
+	NeumannDCT2
+
+	// DirichletDST1 gives zero value boundary conditions where the field vanishes
+	// one grid spacing outside the first and last grid point (DST-I, RODFT00)
+	DirichletDST1
+)
+
+// String returns a string representation of the boundary condition
+func (bc BoundaryCondition) String() string {
+	switch bc {
+	case Periodic:
+		return "periodic"
+	case NeumannDCT1:
+		return "neumann (DCT-I)"
+	case NeumannDCT2:
+		return "neumann (DCT-II)"
+	case DirichletDST1:
+		return "dirichlet (DST-I)"
+	}
+	return fmt.Sprintf("unknown (%d)", int(bc))
+}
+
+// extendedLength returns the length of the symmetrically extended periodic array
+func (bc BoundaryCondition) extendedLength(n int) int {
+	switch bc {
+	case NeumannDCT1:
+		if n < 2 {
+			panic("r2rtransform: DCT-I requires at least two grid points")
+		}
+		return 2 * (n - 1)
+	case NeumannDCT2:
+		return 2 * n
+	case DirichletDST1:
+		return 2 * (n + 1)
+	}
+	return n
+}
+
+// source returns the index in the original array corresponding to index j in the
+// extended array, together with the sign of the value. A sign of zero means that
+// the value in the extended array is zero
+func (bc BoundaryCondition) source(j int, n int) (int, float64) {
+	switch bc {
+	case NeumannDCT1:
+		if j < n {
+			return j, 1.0
+		}
+		return 2*(n-1) - j, 1.0
+	case NeumannDCT2:
+		if j < n {
+			return j, 1.0
+		}
+		return 2*n - 1 - j, 1.0
+	case DirichletDST1:
+		if j == 0 || j == n+1 {
+			return 0, 0.0
+		} else if j <= n {
+			return j - 1, 1.0
+		}
+		return 2*(n+1) - j - 1, -1.0
+	}
+	return j, 1.0
+}
+
+// forward returns the index in the extended spectrum and the phase factor that
+// must be multiplied with the value at that index in order to obtain coefficient k
+func (bc BoundaryCondition) forward(k int, n int) (int, complex128) {
+	switch bc {
+	case NeumannDCT2:
+		return k, cmplx.Exp(complex(0.0, -math.Pi*float64(k)/float64(2*n)))
+	case DirichletDST1:
+		return k + 1, complex(0.0, 1.0)
+	}
+	return k, complex(1.0, 0.0)
+}
+
+// backward returns the coefficient index and the factor that reconstructs index m
+// in the extended spectrum. A factor of zero means that the extended spectrum is zero
+func (bc BoundaryCondition) backward(m int, n int) (int, complex128) {
+	switch bc {
+	case NeumannDCT1:
+		if m < n {
+			return m, complex(1.0, 0.0)
+		}
+		return 2*(n-1) - m, complex(1.0, 0.0)
+	case NeumannDCT2:
+		if m < n {
+			return m, cmplx.Exp(complex(0.0, math.Pi*float64(m)/float64(2*n)))
+		} else if m == n {
+			return 0, complex(0.0, 0.0)
+		}
+		k := 2*n - m
+		return k, cmplx.Exp(complex(0.0, -math.Pi*float64(k)/float64(2*n)))
+	case DirichletDST1:
+		if m == 0 || m == n+1 {
+			return 0, complex(0.0, 0.0)
+		} else if m <= n {
+			return m - 1, complex(0.0, -1.0)
+		}
+		return 2*(n+1) - m - 1, complex(0.0, 1.0)
+	}
+	return m, complex(1.0, 0.0)
+}
+
+// wavenumber returns the frequency corresponding to coefficient k
+func (bc BoundaryCondition) wavenumber(k int, n int) float64 {
+	switch bc {
+	case NeumannDCT1, NeumannDCT2:
+		return float64(k) / float64(bc.extendedLength(n))
+	case DirichletDST1:
+		return float64(k+1) / float64(bc.extendedLength(n))
+	}
+	f := float64(k) / float64(n)
+	if f > 0.5 {
+		f -= 1.0
+	}
+	return f
+}
+
+// R2RTransform implements the FourierTransform interface for domains where each
+// axis can have its own boundary condition. Along axes with non-periodic boundary
+// conditions the data is expanded in cosine (Neumann) or sine (Dirichlet) modes,
+// and the frequencies returned by Freq are the ones of the corresponding modes.
+// Hence, even operators such as the laplacian have the correct symbol. Since the
+// FFTW binding only exposes complex transforms, the real-to-real transforms are
+// evaluated via a complex FFT of the symmetrically extended data. The coefficients
+// are identical to the ones obtained with the FFTW r2r kinds listed in the
+// documentation of BoundaryCondition. The extended data is about twice as long along
+// each non-periodic axis, so with m non-periodic axes the transform needs about 2^m
+// times the memory and time of a periodic FFT of the same domain (4 times in 2D with
+// non-periodic boundaries along both axes).
+//
+// Odd operators (such as the gradient) maps cosine modes onto sine modes and
+// vice versa. They can therefore not be applied directly in the transformed domain.
+// Use Derivative (or GradientCalculator, which calls it) to evaluate derivatives
+// in real space.
+//
+// To use the transform in a solver, replace the FT attribute and reset the stepper:
+//
+//	solver.FT = pfutil.NewR2RTransform(domainSize, []pfutil.BoundaryCondition{pfutil.NeumannDCT2, pfutil.Periodic})
+//	solver.SetStepper("euler")
+type R2RTransform struct {
+	Dimensions []int
+	Boundaries []BoundaryCondition
+	extended   *FFTWWrapper
+	extData    []complex128
+}
+
+// NewR2RTransform returns a new transform. n is the domain size and bcs holds the
+// boundary condition along each axis
+func NewR2RTransform(n []int, bcs []BoundaryCondition) *R2RTransform {
+	if len(n) != len(bcs) {
+		panic("r2rtransform: One boundary condition must be given for each axis")
+	}
+	extDims := make([]int, len(n))
+	for i := range n {
+		extDims[i] = bcs[i].extendedLength(n[i])
+	}
+	ext := NewFFTW(extDims)
+	return &R2RTransform{
+		Dimensions: n,
+		Boundaries: bcs,
+		extended:   ext,
+		extData:    make([]complex128, ProdInt(extDims)),
+	}
+}
+
+// extend fills the extended array with the symmetric extension of data
+func (r *R2RTransform) extend(data []complex128) {
+	for i := range r.extData {
+		pos := Pos(r.extended.Dimensions, i)
+		sign := 1.0
+		for j := range pos {
+			var s float64
+			pos[j], s = r.Boundaries[j].source(pos[j], r.Dimensions[j])
+			sign *= s
+		}
+		if sign == 0.0 {
+			r.extData[i] = 0.0
+		} else {
+			r.extData[i] = complex(sign, 0.0) * data[NodeIdx(r.Dimensions, pos)]
+		}
+	}
+}
+
+// restrict extracts the original domain from the extended array and places the
+// result in data
+func (r *R2RTransform) restrict(data []complex128, factor float64) {
+	extPos := make([]int, len(r.Dimensions))
+	for i := range data {
+		pos := Pos(r.Dimensions, i)
+		for j := range pos {
+			extPos[j] = pos[j]
+			if r.Boundaries[j] == DirichletDST1 {
+				extPos[j]++
+			}
+		}
+		data[i] = r.extData[NodeIdx(r.extended.Dimensions, extPos)] * complex(factor, 0.0)
+	}
+}
+
+// FFT performs the forward transform
+func (r *R2RTransform) FFT(data []complex128) []complex128 {
+	r.extend(data)
+	r.extended.FFT(r.extData)
+	extPos := make([]int, len(r.Dimensions))
+	for i := range data {
+		pos := Pos(r.Dimensions, i)
+		phase := complex(1.0, 0.0)
+		for j := range pos {
+			var p complex128
+			extPos[j], p = r.Boundaries[j].forward(pos[j], r.Dimensions[j])
+			phase *= p
+		}
+		data[i] = phase * r.extData[NodeIdx(r.extended.Dimensions, extPos)]
+	}
+	return data
+}
+
+// IFFT performs the inverse transform. As for FFTWWrapper, the result is not
+// normalized. Hence, IFFT(FFT(data)) returns the original data multiplied by
+// the number of nodes
+func (r *R2RTransform) IFFT(data []complex128) []complex128 {
+	for i := range r.extData {
+		pos := Pos(r.extended.Dimensions, i)
+		factor := complex(1.0, 0.0)
+		for j := range pos {
+			var f complex128
+			pos[j], f = r.Boundaries[j].backward(pos[j], r.Dimensions[j])
+			factor *= f
+		}
+		if factor == 0.0 {
+			r.extData[i] = 0.0
+		} else {
+			r.extData[i] = factor * data[NodeIdx(r.Dimensions, pos)]
+		}
+	}
+	r.extended.IFFT(r.extData)
+	r.restrict(data, float64(len(data))/float64(len(r.extData)))
+	return data
+}
+
+// Freq returns the frequency corresponding to site i
+func (r *R2RTransform) Freq(i int) []float64 {
+	pos := Pos(r.Dimensions, i)
+	res := make([]float64, len(r.Dimensions))
+	for j := range res {
+		res[j] = r.Boundaries[j].wavenumber(pos[j], r.Dimensions[j])
+	}
+	return res
+}
+
+// Derivative calculates the derivative along the passed axis of real space data.
+// The result is placed in data. The derivative is evaluated on the symmetric
+// extension of the data, such that it is consistent with the boundary conditions
+func (r *R2RTransform) Derivative(data []complex128, axis int) []complex128 {
+	r.extend(data)
+	r.extended.FFT(r.extData)
+	for i := range r.extData {
+		f := r.extended.Freq(i)[axis]
+		if math.Abs(f-0.5) < 1e-10 {
+			f = 0.0
+		}
+		r.extData[i] *= complex(0.0, 2.0*math.Pi*f)
+	}
+	r.extended.IFFT(r.extData)
+	r.restrict(data, 1.0/float64(len(r.extData)))
+	return data
+}
+
+// BoundaryConditions returns the boundary condition along each axis
+func (r *R2RTransform) BoundaryConditions() []BoundaryCondition {
+	return r.Boundaries
+}
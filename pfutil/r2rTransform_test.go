@@ -0,0 +1,117 @@
+package pfutil
+
+import (
+	"math"
+	"testing"
+)
+
+func TestR2RRoundTrip(t *testing.T) {
+	for i, test := range []struct {
+		Dims []int
+		BCs  []BoundaryCondition
+	}{
+		{
+			Dims: []int{8},
+			BCs:  []BoundaryCondition{NeumannDCT1},
+		},
+		{
+			Dims: []int{7},
+			BCs:  []BoundaryCondition{NeumannDCT2},
+		},
+		{
+			Dims: []int{8},
+			BCs:  []BoundaryCondition{DirichletDST1},
+		},
+		{
+			Dims: []int{6, 8},
+			BCs:  []BoundaryCondition{NeumannDCT2, Periodic},
+		},
+		{
+			Dims: []int{4, 5, 6},
+			BCs:  []BoundaryCondition{DirichletDST1, NeumannDCT1, NeumannDCT2},
+		},
+	} {
+		ft := NewR2RTransform(test.Dims, test.BCs)
+		data := make([]complex128, ProdInt(test.Dims))
+		for j := range data {
+			data[j] = complex(math.Sin(float64(j*j)), 0.0)
+		}
+		orig := make([]complex128, len(data))
+		copy(orig, data)
+		ft.FFT(data)
+		ft.IFFT(data)
+		DivRealScalar(data, float64(len(data)))
+
+		if !CmplxEqualApprox(data, orig, 1e-10) {
+			t.Errorf("Test #%d: Expected\n%v\nGot\n%v\n", i, orig, data)
+		}
+	}
+}
+
+func TestR2RCoefficients(t *testing.T) {
+	n := 6
+	x := make([]complex128, n)
+	for i := range x {
+		x[i] = complex(float64(i*i)-2.0, 0.0)
+	}
+
+	for _, bc := range []BoundaryCondition{NeumannDCT1, NeumannDCT2, DirichletDST1} {
+		// Direct evaluation of the definitions used by FFTW
+		expect := make([]float64, n)
+		for k := 0; k < n; k++ {
+			switch bc {
+			case NeumannDCT1:
+				expect[k] = real(x[0]) + math.Pow(-1.0, float64(k))*real(x[n-1])
+				for j := 1; j < n-1; j++ {
+					expect[k] += 2.0 * real(x[j]) * math.Cos(math.Pi*float64(j*k)/float64(n-1))
+				}
+			case NeumannDCT2:
+				for j := 0; j < n; j++ {
+					expect[k] += 2.0 * real(x[j]) * math.Cos(math.Pi*(float64(j)+0.5)*float64(k)/float64(n))
+				}
+			case DirichletDST1:
+				for j := 0; j < n; j++ {
+					expect[k] += 2.0 * real(x[j]) * math.Sin(math.Pi*float64((j+1)*(k+1))/float64(n+1))
+				}
+			}
+		}
+
+		ft := NewR2RTransform([]int{n}, []BoundaryCondition{bc})
+		data := make([]complex128, n)
+		copy(data, x)
+		ft.FFT(data)
+		for k := range data {
+			if math.Abs(real(data[k])-expect[k]) > 1e-8 || math.Abs(imag(data[k])) > 1e-8 {
+				t.Errorf("%s: Coefficient %d. Expected %f got %v\n", bc, k, expect[k], data[k])
+			}
+		}
+	}
+}
+
+func TestR2RDerivative(t *testing.T) {
+	n := 16
+	for _, bc := range []BoundaryCondition{NeumannDCT2, DirichletDST1} {
+		ft := NewR2RTransform([]int{n}, []BoundaryCondition{bc})
+		data := make([]complex128, n)
+		expect := make([]float64, n)
+		for i := range data {
+			if bc == NeumannDCT2 {
+				k := math.Pi / float64(n)
+				x := float64(i) + 0.5
+				data[i] = complex(math.Cos(k*x), 0.0)
+				expect[i] = -k * math.Sin(k*x)
+			} else {
+				k := math.Pi / float64(n+1)
+				x := float64(i) + 1.0
+				data[i] = complex(math.Sin(k*x), 0.0)
+				expect[i] = k * math.Cos(k*x)
+			}
+		}
+		ft.Derivative(data, 0)
+		for i := range data {
+			if math.Abs(real(data[i])-expect[i]) > 1e-8 {
+				t.Errorf("%s: Node %d. Expected %f got %f\n", bc, i, expect[i], real(data[i]))
+			}
+		}
+	}
+}